@@ -4,31 +4,55 @@ import (
 	"angular-n-go-template/backend/controllers"
 	"angular-n-go-template/backend/middleware"
 	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/security"
+	"angular-n-go-template/backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// apiPrefix is the path under which the route groups are served
+const apiPrefix = "/api/v1"
+
 // RouteConfig holds route configuration with permissions
 type RouteConfig struct {
-	Path        string   `json:"path"`
-	Method      string   `json:"method"`
+	Path        string          `json:"path"`
+	Method      string          `json:"method"`
 	Handler     gin.HandlerFunc `json:"-"`
-	Permissions []string `json:"permissions"`
-	Description string   `json:"description"`
-	Public      bool     `json:"public"`
+	Permissions []string        `json:"permissions"`
+	// Policies grant access when any of them is satisfied, in addition to Permissions.
+	// Unlike permissions they can depend on the request, e.g. on whose record is edited.
+	Policies    []rbac.Policy `json:"policies,omitempty"`
+	Description string        `json:"description"`
+	Public      bool          `json:"public"`
 }
 
 // RouteGroupConfig holds configuration for a group of routes
 type RouteGroupConfig struct {
-	Prefix      string         `json:"prefix"`
-	Permissions []string       `json:"permissions"`
-	Routes      []RouteConfig  `json:"routes"`
-	Description string         `json:"description"`
+	Prefix      string        `json:"prefix"`
+	Permissions []string      `json:"permissions"`
+	Routes      []RouteConfig `json:"routes"`
+	Description string        `json:"description"`
+}
+
+// updateUserPolicies let users update their own record, and those allowed to update any user
+// update the records of users with the same or fewer permissions than them
+var updateUserPolicies = []rbac.Policy{
+	{Permission: "users.write.own", Conditions: []rbac.Condition{rbac.SubjectIsParam("id")}},
+	{Permission: "users.write.any", Conditions: []rbac.Condition{rbac.TargetRoleNotHigher("id")}},
 }
 
 // GetRouteConfigurations returns all route configurations
 func GetRouteConfigurations(
 	authController *controllers.AuthController,
+	mfaController *controllers.MFAController,
+	invitationController *controllers.InvitationController,
+	apiKeyController *controllers.APIKeyController,
+	sessionController *controllers.SessionController,
+	oidcController *controllers.OIDCController,
+	impersonationController *controllers.ImpersonationController,
+	webAuthnController *controllers.WebAuthnController,
+	rbacController *controllers.RBACController,
+	userRoleController *controllers.UserRoleController,
 	userController *controllers.UserController,
 	adminController *controllers.AdminController,
 	rbacConfig *rbac.RBACConfig,
@@ -52,6 +76,118 @@ func GetRouteConfigurations(
 					Public:      true,
 					Description: "User login",
 				},
+				{
+					Path:        "/login/mfa",
+					Method:      "POST",
+					Handler:     authController.LoginMFA,
+					Public:      true,
+					Description: "Complete a login with a two-factor authentication code",
+				},
+				{
+					Path:        "/magic-link",
+					Method:      "POST",
+					Handler:     authController.RequestMagicLink,
+					Public:      true,
+					Description: "Request a login link by email",
+				},
+				{
+					Path:        "/magic-link/consume",
+					Method:      "POST",
+					Handler:     authController.ConsumeMagicLink,
+					Public:      true,
+					Description: "Log in with an emailed login link",
+				},
+				{
+					Path:        "/webauthn/login/begin",
+					Method:      "POST",
+					Handler:     webAuthnController.BeginLogin,
+					Public:      true,
+					Description: "Start a passkey login",
+				},
+				{
+					Path:        "/webauthn/login/finish",
+					Method:      "POST",
+					Handler:     webAuthnController.FinishLogin,
+					Public:      true,
+					Description: "Log in with a passkey",
+				},
+				{
+					Path:        "/webauthn/mfa/begin",
+					Method:      "POST",
+					Handler:     webAuthnController.BeginMFA,
+					Public:      true,
+					Description: "Start a security key second factor",
+				},
+				{
+					Path:        "/webauthn/mfa/finish",
+					Method:      "POST",
+					Handler:     webAuthnController.FinishMFA,
+					Public:      true,
+					Description: "Complete a login with a security key",
+				},
+				{
+					Path:        "/oidc/providers",
+					Method:      "GET",
+					Handler:     oidcController.GetProviders,
+					Public:      true,
+					Description: "List single sign-on providers",
+				},
+				{
+					Path:        "/oidc/:provider/authorize",
+					Method:      "POST",
+					Handler:     oidcController.Authorize,
+					Public:      true,
+					Description: "Start a login with a single sign-on provider",
+				},
+				{
+					Path:        "/oidc/:provider/callback",
+					Method:      "POST",
+					Handler:     oidcController.Callback,
+					Public:      true,
+					Description: "Complete a login with a single sign-on provider",
+				},
+				{
+					Path:        "/refresh",
+					Method:      "POST",
+					Handler:     authController.Refresh,
+					Public:      true,
+					Description: "Rotate a refresh token and issue a new access token",
+				},
+				{
+					Path:        "/invitations/accept",
+					Method:      "POST",
+					Handler:     invitationController.AcceptInvitation,
+					Public:      true,
+					Description: "Create an account from an invitation",
+				},
+				{
+					Path:        "/password/forgot",
+					Method:      "POST",
+					Handler:     authController.ForgotPassword,
+					Public:      true,
+					Description: "Request a password reset email",
+				},
+				{
+					Path:        "/password/reset",
+					Method:      "POST",
+					Handler:     authController.ResetPassword,
+					Public:      true,
+					Description: "Reset a password with a reset token",
+				},
+				{
+					Path:        "/verify-email",
+					Method:      "POST",
+					Handler:     authController.VerifyEmail,
+					Public:      true,
+					Description: "Confirm an email address with a verification token",
+				},
+				{
+					Path:        "/verify-email/resend",
+					Method:      "POST",
+					Handler:     authController.ResendVerification,
+					Permissions: []string{"profile.read"},
+					Description: "Send a new email verification link",
+				},
 				{
 					Path:        "/profile",
 					Method:      "GET",
@@ -59,6 +195,20 @@ func GetRouteConfigurations(
 					Permissions: []string{"profile.read"},
 					Description: "Get user profile",
 				},
+				{
+					Path:        "/me",
+					Method:      "PATCH",
+					Handler:     userController.UpdateMe,
+					Permissions: []string{"profile.write"},
+					Description: "Update own profile",
+				},
+				{
+					Path:        "/password",
+					Method:      "PUT",
+					Handler:     userController.ChangePassword,
+					Permissions: []string{"profile.write"},
+					Description: "Change own password and sign out other sessions",
+				},
 				{
 					Path:        "/logout",
 					Method:      "POST",
@@ -66,12 +216,150 @@ func GetRouteConfigurations(
 					Permissions: []string{"profile.read"},
 					Description: "User logout",
 				},
+				{
+					Path:        "/impersonation",
+					Method:      "DELETE",
+					Handler:     impersonationController.EndImpersonation,
+					Description: "End the impersonation of the token used for the request",
+				},
+				{
+					Path:        "/logout-all",
+					Method:      "POST",
+					Handler:     authController.LogoutEverywhere,
+					Permissions: []string{"profile.read"},
+					Description: "Log out of every device",
+				},
+				{
+					Path:        "/tokens",
+					Method:      "GET",
+					Handler:     apiKeyController.GetAPIKeys,
+					Permissions: []string{"profile.read"},
+					Description: "List personal API keys",
+				},
+				{
+					Path:        "/tokens",
+					Method:      "POST",
+					Handler:     apiKeyController.CreateAPIKey,
+					Permissions: []string{"profile.write"},
+					Description: "Create a personal API key",
+				},
+				{
+					Path:        "/tokens/:id",
+					Method:      "DELETE",
+					Handler:     apiKeyController.RevokeAPIKey,
+					Permissions: []string{"profile.write"},
+					Description: "Revoke a personal API key",
+				},
+				{
+					Path:        "/sessions",
+					Method:      "GET",
+					Handler:     sessionController.GetMySessions,
+					Permissions: []string{"profile.read"},
+					Description: "List own login sessions",
+				},
+				{
+					Path:        "/sessions",
+					Method:      "DELETE",
+					Handler:     sessionController.RevokeMyOtherSessions,
+					Permissions: []string{"profile.write"},
+					Description: "Sign out of every other session",
+				},
+				{
+					Path:        "/sessions/:id",
+					Method:      "DELETE",
+					Handler:     sessionController.RevokeMySession,
+					Permissions: []string{"profile.write"},
+					Description: "Sign out of one session",
+				},
+				{
+					Path:        "/identities",
+					Method:      "GET",
+					Handler:     oidcController.GetIdentities,
+					Permissions: []string{"profile.read"},
+					Description: "List linked single sign-on accounts",
+				},
+				{
+					Path:        "/identities/:id",
+					Method:      "DELETE",
+					Handler:     oidcController.UnlinkIdentity,
+					Permissions: []string{"profile.write"},
+					Description: "Unlink a single sign-on account",
+				},
+				{
+					Path:        "/oidc/:provider/link",
+					Method:      "POST",
+					Handler:     oidcController.AuthorizeLink,
+					Permissions: []string{"profile.write"},
+					Description: "Start linking a single sign-on account",
+				},
+				{
+					Path:        "/oidc/:provider/link/callback",
+					Method:      "POST",
+					Handler:     oidcController.LinkCallback,
+					Permissions: []string{"profile.write"},
+					Description: "Complete linking a single sign-on account",
+				},
+				// Two-factor management only requires authentication so users of roles
+				// that require MFA can enroll before being granted any permission
+				{
+					Path:        "/mfa",
+					Method:      "GET",
+					Handler:     mfaController.GetStatus,
+					Description: "Get two-factor authentication status",
+				},
+				{
+					Path:        "/mfa/enroll",
+					Method:      "POST",
+					Handler:     mfaController.Enroll,
+					Description: "Start a TOTP enrollment",
+				},
+				{
+					Path:        "/mfa/confirm",
+					Method:      "POST",
+					Handler:     mfaController.Confirm,
+					Description: "Confirm a TOTP enrollment and get recovery codes",
+				},
+				{
+					Path:        "/mfa/disable",
+					Method:      "POST",
+					Handler:     mfaController.Disable,
+					Description: "Disable two-factor authentication",
+				},
+				{
+					Path:        "/mfa/recovery-codes",
+					Method:      "POST",
+					Handler:     mfaController.RegenerateRecoveryCodes,
+					Description: "Regenerate recovery codes",
+				},
+				{
+					Path:        "/webauthn/register/begin",
+					Method:      "POST",
+					Handler:     webAuthnController.BeginRegistration,
+					Description: "Start registering a security key or passkey",
+				},
+				{
+					Path:        "/webauthn/register/finish",
+					Method:      "POST",
+					Handler:     webAuthnController.FinishRegistration,
+					Description: "Register a security key or passkey",
+				},
+				{
+					Path:        "/webauthn/credentials",
+					Method:      "GET",
+					Handler:     webAuthnController.GetCredentials,
+					Description: "List security keys and passkeys",
+				},
+				{
+					Path:        "/webauthn/credentials/:id",
+					Method:      "DELETE",
+					Handler:     webAuthnController.DeleteCredential,
+					Description: "Remove a security key or passkey",
+				},
 			},
 		},
 		{
 			Prefix:      "/users",
 			Description: "User management routes",
-			Permissions: []string{"users.read"}, // Default permission for the group
 			Routes: []RouteConfig{
 				{
 					Path:        "",
@@ -91,8 +379,8 @@ func GetRouteConfigurations(
 					Path:        "/:id",
 					Method:      "PUT",
 					Handler:     userController.UpdateUser,
-					Permissions: []string{"users.write"},
-					Description: "Update user",
+					Policies:    updateUserPolicies,
+					Description: "Update user, own record or one with the same or fewer permissions",
 				},
 				{
 					Path:        "/:id",
@@ -107,6 +395,90 @@ func GetRouteConfigurations(
 			Prefix:      "/admin",
 			Description: "Admin routes",
 			Routes: []RouteConfig{
+				{
+					Path:        "/users",
+					Method:      "POST",
+					Handler:     userController.CreateUser,
+					Permissions: []string{"admin.users.manage"},
+					Description: "Create a user with a given role",
+				},
+				{
+					Path:        "/invitations",
+					Method:      "POST",
+					Handler:     invitationController.CreateInvitation,
+					Permissions: []string{"admin.users.manage"},
+					Description: "Invite someone to create an account with a pre-assigned role",
+				},
+				{
+					Path:        "/invitations",
+					Method:      "GET",
+					Handler:     invitationController.GetInvitations,
+					Permissions: []string{"admin.users.manage"},
+					Description: "List invitations",
+				},
+				{
+					Path:        "/invitations/:id",
+					Method:      "DELETE",
+					Handler:     invitationController.RevokeInvitation,
+					Permissions: []string{"admin.users.manage"},
+					Description: "Revoke a pending invitation",
+				},
+				{
+					Path:        "/users/:id/unlock",
+					Method:      "POST",
+					Handler:     adminController.UnlockUser,
+					Permissions: []string{"admin.users.manage"},
+					Description: "Lift the login lockout of a user",
+				},
+				{
+					Path:        "/users/:id/roles",
+					Method:      "GET",
+					Handler:     userRoleController.GetUserRoles,
+					Permissions: []string{"admin.users.manage"},
+					Description: "List the roles of a user",
+				},
+				{
+					Path:        "/users/:id/roles",
+					Method:      "POST",
+					Handler:     userRoleController.GrantUserRole,
+					Permissions: []string{"admin.users.manage"},
+					Description: "Grant a role to a user, optionally until an expiry date",
+				},
+				{
+					Path:        "/users/:id/roles/:role",
+					Method:      "DELETE",
+					Handler:     userRoleController.RevokeUserRole,
+					Permissions: []string{"admin.users.manage"},
+					Description: "Revoke a role granted to a user",
+				},
+				{
+					Path:        "/users/:id/impersonate",
+					Method:      "POST",
+					Handler:     impersonationController.Impersonate,
+					Permissions: []string{"admin.users.impersonate"},
+					Description: "Act as a user with a time-boxed token",
+				},
+				{
+					Path:        "/users/:id/sessions",
+					Method:      "GET",
+					Handler:     sessionController.GetUserSessions,
+					Permissions: []string{"admin.users.manage"},
+					Description: "List the login sessions of a user",
+				},
+				{
+					Path:        "/users/:id/sessions",
+					Method:      "DELETE",
+					Handler:     sessionController.RevokeUserSessions,
+					Permissions: []string{"admin.users.manage"},
+					Description: "Sign a user out of every session",
+				},
+				{
+					Path:        "/users/:id/sessions/:sessionId",
+					Method:      "DELETE",
+					Handler:     sessionController.RevokeUserSession,
+					Permissions: []string{"admin.users.manage"},
+					Description: "Sign a user out of one session",
+				},
 				{
 					Path:        "/logs",
 					Method:      "GET",
@@ -121,6 +493,13 @@ func GetRouteConfigurations(
 					Permissions: []string{"admin.logs.read"},
 					Description: "Get request logs for specific user",
 				},
+				{
+					Path:        "/login-attempts",
+					Method:      "GET",
+					Handler:     adminController.GetLoginAttempts,
+					Permissions: []string{"admin.logs.read"},
+					Description: "Get recent login attempts",
+				},
 				{
 					Path:        "/stats",
 					Method:      "GET",
@@ -128,19 +507,138 @@ func GetRouteConfigurations(
 					Permissions: []string{"admin.stats.read"},
 					Description: "Get system statistics",
 				},
+				{
+					Path:        "/roles",
+					Method:      "GET",
+					Handler:     rbacController.GetRoles,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "List roles with their permissions",
+				},
+				{
+					Path:        "/roles",
+					Method:      "POST",
+					Handler:     rbacController.CreateRole,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "Create a role",
+				},
+				{
+					Path:        "/roles/:name",
+					Method:      "PUT",
+					Handler:     rbacController.UpdateRole,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "Update a role",
+				},
+				{
+					Path:        "/roles/:name",
+					Method:      "DELETE",
+					Handler:     rbacController.DeleteRole,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "Delete a role",
+				},
+				{
+					Path:        "/permissions",
+					Method:      "GET",
+					Handler:     rbacController.GetPermissions,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "List permissions",
+				},
+				{
+					Path:        "/permissions",
+					Method:      "POST",
+					Handler:     rbacController.CreatePermission,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "Create a permission",
+				},
+				{
+					Path:        "/permissions/:name",
+					Method:      "PUT",
+					Handler:     rbacController.UpdatePermission,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "Update a permission",
+				},
+				{
+					Path:        "/permissions/:name",
+					Method:      "DELETE",
+					Handler:     rbacController.DeletePermission,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "Delete a permission",
+				},
+				{
+					Path:        "/access/explain",
+					Method:      "POST",
+					Handler:     rbacController.ExplainAccess,
+					Permissions: []string{"admin.rbac.manage"},
+					Description: "Explain the access decision for a user or role on a route",
+				},
 			},
 		},
 	}
 }
 
-// SetupRoutes configures all routes with their permissions
+// RoutePermissions lists the permissions required by route groups and routes, without duplicates
+func RoutePermissions(routeConfigs []RouteGroupConfig) []string {
+	seen := make(map[string]bool)
+	var permissions []string
+	add := func(required []string) {
+		for _, permission := range required {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+
+	for _, groupConfig := range routeConfigs {
+		add(groupConfig.Permissions)
+		for _, route := range groupConfig.Routes {
+			add(route.Permissions)
+			for _, policy := range route.Policies {
+				add([]string{policy.Permission})
+			}
+		}
+	}
+	return permissions
+}
+
+// RouteRules lists the access rules of every route, with the full path the route is served under
+func RouteRules(routeConfigs []RouteGroupConfig) []rbac.Route {
+	var routes []rbac.Route
+	for _, groupConfig := range routeConfigs {
+		for _, route := range groupConfig.Routes {
+			routes = append(routes, rbac.Route{
+				Method:           route.Method,
+				Path:             apiPrefix + groupConfig.Prefix + route.Path,
+				Public:           route.Public,
+				GroupPermissions: groupConfig.Permissions,
+				Permissions:      route.Permissions,
+				Policies:         route.Policies,
+			})
+		}
+	}
+	return routes
+}
+
+// SetupRoutes configures all routes with their permissions and returns the route configurations
 func SetupRoutes(
 	router *gin.Engine,
 	authController *controllers.AuthController,
+	mfaController *controllers.MFAController,
+	invitationController *controllers.InvitationController,
+	apiKeyController *controllers.APIKeyController,
+	sessionController *controllers.SessionController,
+	oidcController *controllers.OIDCController,
+	impersonationController *controllers.ImpersonationController,
+	webAuthnController *controllers.WebAuthnController,
+	rbacController *controllers.RBACController,
+	userRoleController *controllers.UserRoleController,
 	userController *controllers.UserController,
 	adminController *controllers.AdminController,
 	rbacConfig *rbac.RBACConfig,
-) {
+	tokenService *services.TokenService,
+	apiKeyService *services.APIKeyService,
+	userRoleService *services.UserRoleService,
+	principalService *services.PrincipalService,
+) []RouteGroupConfig {
 	// Health check endpoint (always public)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -149,11 +647,17 @@ func SetupRoutes(
 		})
 	})
 
+	// JSON Web Key Set for services verifying our tokens (always public)
+	router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, security.PublicJWKS())
+	})
+
 	// Get route configurations
-	routeConfigs := GetRouteConfigurations(authController, userController, adminController, rbacConfig)
+	routeConfigs := GetRouteConfigurations(authController, mfaController, invitationController, apiKeyController, sessionController, oidcController, impersonationController, webAuthnController, rbacController, userRoleController, userController, adminController, rbacConfig)
 
 	// API group
-	api := router.Group("/api/v1")
+	api := router.Group(apiPrefix)
 
 	// Setup each route group
 	for _, groupConfig := range routeConfigs {
@@ -161,7 +665,7 @@ func SetupRoutes(
 
 		// Apply default permissions to the group if specified
 		if len(groupConfig.Permissions) > 0 {
-			group.Use(middleware.AuthMiddleware())
+			group.Use(middleware.AuthMiddleware(tokenService, apiKeyService, principalService))
 			group.Use(middleware.MultiplePermissionsMiddleware(groupConfig.Permissions, rbacConfig))
 		}
 
@@ -174,7 +678,7 @@ func SetupRoutes(
 
 			// Add auth middleware if not public
 			if !route.Public {
-				handlers = append(handlers, middleware.AuthMiddleware())
+				handlers = append(handlers, middleware.AuthMiddleware(tokenService, apiKeyService, principalService))
 			}
 
 			// Add permission middleware if permissions are specified
@@ -186,6 +690,11 @@ func SetupRoutes(
 				}
 			}
 
+			// Add policy middleware if policies are specified
+			if len(route.Policies) > 0 {
+				handlers = append(handlers, middleware.PolicyMiddleware(route.Policies, rbacConfig, userRoleService.ActiveRoles))
+			}
+
 			// Add the actual handler
 			handlers = append(handlers, route.Handler)
 
@@ -204,4 +713,6 @@ func SetupRoutes(
 			}
 		}
 	}
+
+	return routeConfigs
 }
@@ -8,17 +8,22 @@ import (
 	"angular-n-go-template/backend/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // AdminController handles admin-related HTTP requests
 type AdminController struct {
-	requestLogService *services.RequestLogService
+	requestLogService      *services.RequestLogService
+	loginProtectionService *services.LoginProtectionService
+	userService            *services.UserService
 }
 
 // NewAdminController creates a new admin controller
-func NewAdminController(requestLogService *services.RequestLogService) *AdminController {
+func NewAdminController(requestLogService *services.RequestLogService, loginProtectionService *services.LoginProtectionService, userService *services.UserService) *AdminController {
 	return &AdminController{
-		requestLogService: requestLogService,
+		requestLogService:      requestLogService,
+		loginProtectionService: loginProtectionService,
+		userService:            userService,
 	}
 }
 
@@ -99,3 +104,60 @@ func (c *AdminController) GetSystemStats(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, stats))
 }
+
+// GetLoginAttempts retrieves recent login attempts, optionally for one email (admin only)
+func (c *AdminController) GetLoginAttempts(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Get limit from query parameter, default to 100
+	limitStr := ctx.DefaultQuery("limit", "100")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000 // Cap at 1000 for performance
+	}
+
+	email := ctx.Query("email")
+
+	// Get login attempts
+	attempts, err := c.loginProtectionService.GetLoginAttempts(email, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{
+		"attempts": attempts,
+		"count":    len(attempts),
+		"limit":    limit,
+		"email":    email,
+	}))
+}
+
+// UnlockUser lifts the login lockout of a user (admin only)
+func (c *AdminController) UnlockUser(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse user ID
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid user ID"))
+		return
+	}
+
+	user, err := c.userService.GetUser(userID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "User"))
+		return
+	}
+
+	// Unlock account
+	if err := c.loginProtectionService.Unlock(ctx.Request.Context(), user.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "User unlocked"}))
+}
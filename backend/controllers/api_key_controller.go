@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/security"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIKeyController handles personal access token HTTP requests for the current user
+type APIKeyController struct {
+	apiKeyService *services.APIKeyService
+}
+
+// NewAPIKeyController creates a new API key controller
+func NewAPIKeyController(apiKeyService *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// interactiveUserID returns the current user ID, refusing requests authenticated with an API key
+// so that a leaked key cannot be used to mint or revoke other keys, and requests of an
+// administrator impersonating the user, who must not change their credentials
+func interactiveUserID(ctx *gin.Context) (uuid.UUID, bool) {
+	claims, ok := currentClaims(ctx)
+	if !ok || claims.HasAuthMethod(security.AuthMethodAPIKey) || claims.IsImpersonation() {
+		return uuid.Nil, false
+	}
+
+	return claims.UserID, true
+}
+
+// GetAPIKeys lists the API keys of the current user
+func (c *APIKeyController) GetAPIKeys(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	keys, err := c.apiKeyService.GetAPIKeys(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, keys))
+}
+
+// CreateAPIKey creates an API key for the current user; the token is only returned once
+func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Create API key
+	claims := ctx.MustGet("tokenClaims").(*security.Claims)
+	response, err := c.apiKeyService.CreateAPIKey(userID, claims.HasAuthMethod(security.AuthMethodMFA), &req)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.SuccessResponse(requestID, response))
+}
+
+// RevokeAPIKey deletes an API key of the current user
+func (c *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse API key ID
+	keyID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid API key ID"))
+		return
+	}
+
+	// Revoke API key
+	err = c.apiKeyService.RevokeAPIKey(userID, keyID)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "API key"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "API key revoked"}))
+}
@@ -1,9 +1,12 @@
 package controllers
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 
 	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/security"
 	"angular-n-go-template/backend/services"
 
 	"github.com/gin-gonic/gin"
@@ -12,15 +15,21 @@ import (
 
 // AuthController handles authentication-related HTTP requests
 type AuthController struct {
-	authService        *services.AuthService
-	requestLogService  *services.RequestLogService
+	authService              *services.AuthService
+	requestLogService        *services.RequestLogService
+	passwordResetService     *services.PasswordResetService
+	emailVerificationService *services.EmailVerificationService
+	magicLinkService         *services.MagicLinkService
 }
 
 // NewAuthController creates a new auth controller
-func NewAuthController(authService *services.AuthService, requestLogService *services.RequestLogService) *AuthController {
+func NewAuthController(authService *services.AuthService, requestLogService *services.RequestLogService, passwordResetService *services.PasswordResetService, emailVerificationService *services.EmailVerificationService, magicLinkService *services.MagicLinkService) *AuthController {
 	return &AuthController{
-		authService:       authService,
-		requestLogService: requestLogService,
+		authService:              authService,
+		requestLogService:        requestLogService,
+		passwordResetService:     passwordResetService,
+		emailVerificationService: emailVerificationService,
+		magicLinkService:         magicLinkService,
 	}
 }
 
@@ -29,7 +38,7 @@ func (c *AuthController) Register(ctx *gin.Context) {
 	requestID := ctx.GetString("requestId")
 
 	// Parse request body
-	var req models.CreateUserRequest
+	var req models.RegisterRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
 		return
@@ -49,6 +58,16 @@ func (c *AuthController) Register(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, models.SuccessResponse(requestID, user))
 }
 
+// clientInfo describes the client of an authentication request, with the device name it chose if any
+func clientInfo(ctx *gin.Context, deviceName string) models.ClientInfo {
+	return models.ClientInfo{
+		RequestID:  ctx.GetString("requestId"),
+		IPAddress:  ctx.ClientIP(),
+		UserAgent:  ctx.Request.UserAgent(),
+		DeviceName: deviceName,
+	}
+}
+
 // Login authenticates a user
 func (c *AuthController) Login(ctx *gin.Context) {
 	requestID := ctx.GetString("requestId")
@@ -61,7 +80,37 @@ func (c *AuthController) Login(ctx *gin.Context) {
 	}
 
 	// Login user
-	response, err := c.authService.Login(&req)
+	response, err := c.authService.Login(&req, clientInfo(ctx, req.DeviceName))
+	if err != nil {
+		if throttled, ok := err.(*services.TooManyAttemptsError); ok {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			ctx.JSON(http.StatusTooManyRequests, models.TooManyRequestsErrorResponse(requestID, err.Error()))
+			return
+		}
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusUnauthorized, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
+
+// LoginMFA completes a login for users with two-factor authentication
+func (c *AuthController) LoginMFA(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.MFALoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Verify second factor
+	response, err := c.authService.CompleteMFALogin(&req, clientInfo(ctx, req.DeviceName))
 	if err != nil {
 		if _, ok := err.(*services.ValidationError); ok {
 			ctx.JSON(http.StatusUnauthorized, models.ValidationErrorResponse(requestID, err.Error()))
@@ -74,10 +123,213 @@ func (c *AuthController) Login(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
 }
 
-// Logout logs out a user
+// Refresh exchanges a refresh token for a new token pair
+func (c *AuthController) Refresh(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Rotate refresh token
+	response, err := c.authService.Refresh(&req, clientInfo(ctx, ""))
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusUnauthorized, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
+
+// Logout revokes the current access token and its session
 func (c *AuthController) Logout(ctx *gin.Context) {
 	requestID := ctx.GetString("requestId")
 
+	// Get token claims from context
+	claimsValue, exists := ctx.Get("tokenClaims")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	claims, ok := claimsValue.(*security.Claims)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	// Logout user
+	err := c.authService.Logout(claims)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Logged out successfully"}))
+}
+
+// LogoutEverywhere revokes every outstanding token of the current user.
+// Impersonators and API keys cannot sign the user out of their devices.
+func (c *AuthController) LogoutEverywhere(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Logout user from all devices
+	err := c.authService.LogoutEverywhere(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Logged out from all devices"}))
+}
+
+// ForgotPassword starts the password reset flow.
+// The response is the same whether or not the email belongs to an account.
+func (c *AuthController) ForgotPassword(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	c.passwordResetService.RequestReset(req.Email)
+
+	ctx.JSON(http.StatusAccepted, models.SuccessResponse(requestID, gin.H{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	}))
+}
+
+// ResetPassword sets a new password using a reset token
+func (c *AuthController) ResetPassword(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Reset password
+	err := c.passwordResetService.ResetPassword(req.Token, req.Password)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Password has been reset"}))
+}
+
+// RequestMagicLink emails a single-use login link.
+// The response is the same whether or not the email belongs to an account.
+func (c *AuthController) RequestMagicLink(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	if !services.MagicLinkEnabled() {
+		ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Magic link login"))
+		return
+	}
+
+	// Parse request body
+	var req models.MagicLinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	response, err := c.magicLinkService.RequestLink(req.Email, ctx.ClientIP())
+	if err != nil {
+		if limited, ok := err.(*services.RateLimitError); ok {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+			ctx.JSON(http.StatusTooManyRequests, models.TooManyRequestsErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, models.SuccessResponse(requestID, response))
+}
+
+// ConsumeMagicLink logs a user in with an emailed login link
+func (c *AuthController) ConsumeMagicLink(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	if !services.MagicLinkEnabled() {
+		ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Magic link login"))
+		return
+	}
+
+	// Parse request body
+	var req models.ConsumeMagicLinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Login user
+	response, err := c.magicLinkService.CompleteLogin(&req, clientInfo(ctx, req.DeviceName))
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusUnauthorized, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
+
+// VerifyEmail confirms the user's email address with a verification token
+func (c *AuthController) VerifyEmail(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Verify email
+	user, err := c.emailVerificationService.VerifyEmail(req.Token)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, user))
+}
+
+// ResendVerification emails a new verification link to the current user
+func (c *AuthController) ResendVerification(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
 	// Get user ID from context
 	userIDValue, exists := ctx.Get("userID")
 	if !exists {
@@ -91,14 +343,18 @@ func (c *AuthController) Logout(ctx *gin.Context) {
 		return
 	}
 
-	// Logout user
-	err := c.authService.Logout(userID)
+	// Resend verification email
+	err := c.emailVerificationService.ResendVerification(userID)
 	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Logged out successfully"}))
+	ctx.JSON(http.StatusAccepted, models.SuccessResponse(requestID, gin.H{"message": "Verification email sent"}))
 }
 
 // GetProfile retrieves the current user's profile
@@ -112,14 +368,14 @@ func (c *AuthController) GetProfile(ctx *gin.Context) {
 		return
 	}
 
-	_, ok := userIDValue.(uuid.UUID)
+	userID, ok := userIDValue.(uuid.UUID)
 	if !ok {
 		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
 		return
 	}
 
 	// Get user profile
-	user, err := c.authService.ValidateToken(ctx.GetHeader("Authorization")[7:]) // Remove "Bearer " prefix
+	user, err := c.authService.GetProfile(userID)
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
 		return
@@ -127,7 +383,3 @@ func (c *AuthController) GetProfile(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, user))
 }
-
-
-
-
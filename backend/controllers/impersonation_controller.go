@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"net/http"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/security"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ImpersonationController handles administrators acting as another user
+type ImpersonationController struct {
+	impersonationService *services.ImpersonationService
+}
+
+// NewImpersonationController creates a new impersonation controller
+func NewImpersonationController(impersonationService *services.ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{
+		impersonationService: impersonationService,
+	}
+}
+
+// currentClaims reads the claims of the authenticated token from the request context
+func currentClaims(ctx *gin.Context) (*security.Claims, bool) {
+	claimsValue, exists := ctx.Get("tokenClaims")
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := claimsValue.(*security.Claims)
+	return claims, ok
+}
+
+// Impersonate issues a time-boxed token to act as a user
+func (c *ImpersonationController) Impersonate(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	claims, ok := currentClaims(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid user ID"))
+		return
+	}
+
+	response, err := c.impersonationService.Start(claims, userID)
+	if err != nil {
+		switch err := err.(type) {
+		case *services.ForbiddenError:
+			ctx.JSON(http.StatusForbidden, models.ErrorResponse(requestID, "FORBIDDEN", "Access forbidden", err.Message))
+		case *services.ValidationError:
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Message))
+		default:
+			ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
+
+// EndImpersonation revokes the impersonation token used for the request
+func (c *ImpersonationController) EndImpersonation(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	claims, ok := currentClaims(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	if err := c.impersonationService.End(claims); err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Impersonation ended"}))
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InvitationController handles invitation-related HTTP requests
+type InvitationController struct {
+	invitationService *services.InvitationService
+}
+
+// NewInvitationController creates a new invitation controller
+func NewInvitationController(invitationService *services.InvitationService) *InvitationController {
+	return &InvitationController{
+		invitationService: invitationService,
+	}
+}
+
+// CreateInvitation invites someone to create an account with a pre-assigned role
+func (c *InvitationController) CreateInvitation(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	claims, ok := currentClaims(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.CreateInvitationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Create invitation
+	invitation, err := c.invitationService.CreateInvitation(&req, claims)
+	if err != nil {
+		if err, ok := err.(*services.ForbiddenError); ok {
+			ctx.JSON(http.StatusForbidden, models.ErrorResponse(requestID, "FORBIDDEN", "Access forbidden", err.Message))
+			return
+		}
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.SuccessResponse(requestID, invitation))
+}
+
+// GetInvitations lists invitations with pagination.
+// Only pending invitations are returned unless status=all is given.
+func (c *InvitationController) GetInvitations(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse pagination parameters
+	limitStr := ctx.DefaultQuery("limit", "10")
+	offsetStr := ctx.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid limit parameter"))
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid offset parameter"))
+		return
+	}
+
+	status := ctx.DefaultQuery("status", models.InvitationPending)
+	if status != models.InvitationPending && status != "all" {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid status parameter"))
+		return
+	}
+
+	// Get invitations
+	invitations, err := c.invitationService.GetInvitations(status == models.InvitationPending, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, invitations))
+}
+
+// RevokeInvitation cancels a pending invitation
+func (c *InvitationController) RevokeInvitation(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse invitation ID
+	invitationID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid invitation ID"))
+		return
+	}
+
+	// Revoke invitation
+	err = c.invitationService.RevokeInvitation(invitationID)
+	if err != nil {
+		if err.Error() == "invitation not found" {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Invitation"))
+			return
+		}
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Invitation revoked"}))
+}
+
+// AcceptInvitation creates an account from an invitation token
+func (c *InvitationController) AcceptInvitation(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.AcceptInvitationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Accept invitation
+	user, err := c.invitationService.AcceptInvitation(&req)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.SuccessResponse(requestID, user))
+}
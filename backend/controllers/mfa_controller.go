@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"net/http"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MFAController handles two-factor authentication HTTP requests for the current user
+type MFAController struct {
+	mfaService *services.MFAService
+}
+
+// NewMFAController creates a new MFA controller
+func NewMFAController(mfaService *services.MFAService) *MFAController {
+	return &MFAController{
+		mfaService: mfaService,
+	}
+}
+
+// currentUserID reads the authenticated user ID from the request context
+func currentUserID(ctx *gin.Context) (uuid.UUID, bool) {
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		return uuid.Nil, false
+	}
+
+	userID, ok := userIDValue.(uuid.UUID)
+	return userID, ok
+}
+
+// GetStatus returns whether two-factor authentication is enabled for the current user
+func (c *MFAController) GetStatus(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	status, err := c.mfaService.GetStatus(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, status))
+}
+
+// Enroll starts a TOTP enrollment and returns the secret and provisioning URI
+func (c *MFAController) Enroll(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	enrollment, err := c.mfaService.Enroll(userID)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, enrollment))
+}
+
+// Confirm enables two-factor authentication with a code from the authenticator app
+func (c *MFAController) Confirm(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	recoveryCodes, err := c.mfaService.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, recoveryCodes))
+}
+
+// Disable turns off two-factor authentication for the current user
+func (c *MFAController) Disable(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	err := c.mfaService.Disable(userID, req.Code)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Two-factor authentication disabled"}))
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user
+func (c *MFAController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	recoveryCodes, err := c.mfaService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, recoveryCodes))
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"net/http"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OIDCController handles single sign-on and linked identity HTTP requests
+type OIDCController struct {
+	oidcService *services.OIDCService
+}
+
+// NewOIDCController creates a new OIDC controller
+func NewOIDCController(oidcService *services.OIDCService) *OIDCController {
+	return &OIDCController{
+		oidcService: oidcService,
+	}
+}
+
+// GetProviders lists the single sign-on providers users can log in with
+func (c *OIDCController) GetProviders(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, c.oidcService.GetProviders()))
+}
+
+// Authorize starts a login with a provider and returns the URL to redirect the browser to
+func (c *OIDCController) Authorize(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	response, err := c.oidcService.StartLogin(ctx.Param("provider"), nil)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Login provider"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
+
+// Callback completes a login with the code and state the provider redirected back with
+func (c *OIDCController) Callback(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.OIDCCallbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Login user
+	response, err := c.oidcService.CompleteLogin(ctx.Param("provider"), &req, clientInfo(ctx, req.DeviceName))
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusUnauthorized, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
+
+// AuthorizeLink starts linking a provider account to the current user
+func (c *OIDCController) AuthorizeLink(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	response, err := c.oidcService.StartLogin(ctx.Param("provider"), &userID)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Login provider"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
+
+// LinkCallback completes linking a provider account to the current user
+func (c *OIDCController) LinkCallback(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.OIDCCallbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	identities, err := c.oidcService.CompleteLink(ctx.Param("provider"), &req, userID)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, identities))
+}
+
+// GetIdentities lists the provider accounts linked to the current user
+func (c *OIDCController) GetIdentities(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	identities, err := c.oidcService.GetIdentities(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, identities))
+}
+
+// UnlinkIdentity removes a provider account from the current user
+func (c *OIDCController) UnlinkIdentity(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse identity ID
+	identityID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid identity ID"))
+		return
+	}
+
+	err = c.oidcService.UnlinkIdentity(userID, identityID)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Identity"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Identity unlinked"}))
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RBACController handles role and permission management HTTP requests
+type RBACController struct {
+	rbacService   *services.RBACService
+	accessService *services.AccessService
+}
+
+// NewRBACController creates a new RBAC controller
+func NewRBACController(rbacService *services.RBACService, accessService *services.AccessService) *RBACController {
+	return &RBACController{
+		rbacService:   rbacService,
+		accessService: accessService,
+	}
+}
+
+// respondRBACError answers a request that the RBAC service refused or failed
+func respondRBACError(ctx *gin.Context, requestID, resource string, err error) {
+	switch err := err.(type) {
+	case *services.ForbiddenError:
+		ctx.JSON(http.StatusForbidden, models.ErrorResponse(requestID, "FORBIDDEN", "Access forbidden", err.Message))
+	case *services.ValidationError:
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Message))
+	default:
+		if err.Error() == strings.ToLower(resource)+" not found" {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, resource))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+	}
+}
+
+// GetRoles lists every role with its permissions
+func (c *RBACController) GetRoles(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, c.rbacService.GetRoles()))
+}
+
+// CreateRole creates a role
+func (c *RBACController) CreateRole(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.CreateRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	role, err := c.rbacService.CreateRole(&req)
+	if err != nil {
+		respondRBACError(ctx, requestID, "Role", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.SuccessResponse(requestID, role))
+}
+
+// UpdateRole replaces the description, MFA requirement, parent roles and permissions of a role
+func (c *RBACController) UpdateRole(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.UpdateRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	role, err := c.rbacService.UpdateRole(ctx.Param("name"), &req)
+	if err != nil {
+		respondRBACError(ctx, requestID, "Role", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, role))
+}
+
+// DeleteRole deletes a role that no user has
+func (c *RBACController) DeleteRole(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	if err := c.rbacService.DeleteRole(ctx.Param("name")); err != nil {
+		respondRBACError(ctx, requestID, "Role", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Role deleted"}))
+}
+
+// GetPermissions lists every permission
+func (c *RBACController) GetPermissions(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, c.rbacService.GetPermissions()))
+}
+
+// CreatePermission creates a permission
+func (c *RBACController) CreatePermission(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.CreatePermissionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	permission, err := c.rbacService.CreatePermission(&req)
+	if err != nil {
+		respondRBACError(ctx, requestID, "Permission", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.SuccessResponse(requestID, permission))
+}
+
+// UpdatePermission updates the description, resource and action of a permission
+func (c *RBACController) UpdatePermission(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.UpdatePermissionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	permission, err := c.rbacService.UpdatePermission(ctx.Param("name"), &req)
+	if err != nil {
+		respondRBACError(ctx, requestID, "Permission", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, permission))
+}
+
+// DeletePermission deletes a permission and removes it from the roles granting it
+func (c *RBACController) DeletePermission(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	if err := c.rbacService.DeletePermission(ctx.Param("name")); err != nil {
+		respondRBACError(ctx, requestID, "Permission", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Permission deleted"}))
+}
+
+// ExplainAccess explains whether a user or a role may use a route: the route matched, the
+// permissions and policies it requires, the roles considered and the check that failed
+func (c *RBACController) ExplainAccess(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.ExplainAccessRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	decision, err := c.accessService.Explain(&req)
+	if err != nil {
+		resource := "Route"
+		if err.Error() == "user not found" {
+			resource = "User"
+		}
+		respondRBACError(ctx, requestID, resource, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, decision))
+}
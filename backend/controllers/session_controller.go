@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"net/http"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/security"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SessionController handles login session HTTP requests for the current user and for administrators
+type SessionController struct {
+	sessionService *services.SessionService
+}
+
+// NewSessionController creates a new session controller
+func NewSessionController(sessionService *services.SessionService) *SessionController {
+	return &SessionController{
+		sessionService: sessionService,
+	}
+}
+
+// currentSessionID returns the session of the access token making the request
+func currentSessionID(ctx *gin.Context) string {
+	claimsValue, exists := ctx.Get("tokenClaims")
+	if !exists {
+		return ""
+	}
+
+	claims, ok := claimsValue.(*security.Claims)
+	if !ok {
+		return ""
+	}
+	return claims.SessionID
+}
+
+// GetMySessions lists the sessions of the current user
+func (c *SessionController) GetMySessions(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	sessions, err := c.sessionService.GetSessions(userID, currentSessionID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, sessions))
+}
+
+// RevokeMySession signs the current user out of one of their sessions
+func (c *SessionController) RevokeMySession(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	err := c.sessionService.RevokeSession(userID, ctx.Param("id"))
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Session"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Session revoked"}))
+}
+
+// RevokeMyOtherSessions signs the current user out of every session but the one making the request
+func (c *SessionController) RevokeMyOtherSessions(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	revoked, err := c.sessionService.RevokeOtherSessions(userID, currentSessionID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Other sessions revoked", "revoked": revoked}))
+}
+
+// GetUserSessions lists the sessions of any user (admin only)
+func (c *SessionController) GetUserSessions(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse user ID
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid user ID"))
+		return
+	}
+
+	sessions, err := c.sessionService.GetSessions(userID, currentSessionID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, sessions))
+}
+
+// RevokeUserSession signs any user out of one session (admin only)
+func (c *SessionController) RevokeUserSession(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse user ID
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid user ID"))
+		return
+	}
+
+	err = c.sessionService.RevokeSession(userID, ctx.Param("sessionId"))
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Session"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Session revoked"}))
+}
+
+// RevokeUserSessions signs any user out of every session (admin only)
+func (c *SessionController) RevokeUserSessions(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse user ID
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid user ID"))
+		return
+	}
+
+	if err := c.sessionService.RevokeAllSessions(userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "All sessions revoked"}))
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/security"
 	"angular-n-go-template/backend/services"
 
 	"github.com/gin-gonic/gin"
@@ -13,8 +14,8 @@ import (
 
 // UserController handles user-related HTTP requests
 type UserController struct {
-	userService        *services.UserService
-	requestLogService  *services.RequestLogService
+	userService       *services.UserService
+	requestLogService *services.RequestLogService
 }
 
 // NewUserController creates a new user controller
@@ -81,6 +82,41 @@ func (c *UserController) GetUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, user))
 }
 
+// CreateUser creates a user with a given role on behalf of an administrator
+func (c *UserController) CreateUser(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	claims, ok := currentClaims(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.CreateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Create user
+	user, err := c.userService.CreateUser(claims, &req)
+	if err != nil {
+		if err, ok := err.(*services.ForbiddenError); ok {
+			ctx.JSON(http.StatusForbidden, models.ErrorResponse(requestID, "FORBIDDEN", "Access forbidden", err.Message))
+			return
+		}
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.SuccessResponse(requestID, user))
+}
+
 // UpdateUser updates a user
 func (c *UserController) UpdateUser(ctx *gin.Context) {
 	requestID := ctx.GetString("requestID")
@@ -100,8 +136,30 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
-	// Update user
-	user, err := c.userService.UpdateUser(userID, &req)
+	// Users editing their own record only change their profile: moving the email address elsewhere
+	// would let whoever holds a stolen token take the account over with a password reset
+	var user *models.UserResponse
+	if currentUserID, _ := ctx.Get("userID"); currentUserID == userID {
+		if req.Email != nil {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "You cannot change the email address of your own account"))
+			return
+		}
+		if req.IsActive != nil {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "You cannot activate or deactivate your own account"))
+			return
+		}
+		if req.Role != nil {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "You cannot change the roles of your own account"))
+			return
+		}
+		user, err = c.userService.UpdateProfile(userID, &models.UpdateProfileRequest{
+			Username:  req.Username,
+			FirstName: req.FirstName,
+			LastName:  req.LastName,
+		})
+	} else {
+		user, err = c.userService.UpdateUser(userID, &req)
+	}
 	if err != nil {
 		if err.Error() == "user not found" {
 			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "User"))
@@ -144,8 +202,66 @@ func (c *UserController) DeleteUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "User deleted successfully"}))
 }
 
+// UpdateMe updates the current user's own profile
+func (c *UserController) UpdateMe(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.UpdateProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
 
+	// Update profile
+	user, err := c.userService.UpdateProfile(userID, &req)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
 
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, user))
+}
 
+// ChangePassword changes the current user's password, signing out every other session
+func (c *UserController) ChangePassword(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
 
+	// API keys cannot change the password of their owner
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+	claims := ctx.MustGet("tokenClaims").(*security.Claims)
 
+	// Parse request body
+	var req models.ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Change password and issue tokens for this device
+	response, err := c.userService.ChangePassword(userID, &req, claims.AMR, clientInfo(ctx, ""))
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"net/http"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UserRoleController handles the roles administrators grant to users
+type UserRoleController struct {
+	userRoleService *services.UserRoleService
+}
+
+// NewUserRoleController creates a new user role controller
+func NewUserRoleController(userRoleService *services.UserRoleService) *UserRoleController {
+	return &UserRoleController{
+		userRoleService: userRoleService,
+	}
+}
+
+// respondUserRoleError answers a request that the user role service refused or failed
+func respondUserRoleError(ctx *gin.Context, requestID string, err error) {
+	switch err := err.(type) {
+	case *services.ForbiddenError:
+		ctx.JSON(http.StatusForbidden, models.ErrorResponse(requestID, "FORBIDDEN", "Access forbidden", err.Message))
+	case *services.ValidationError:
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Message))
+	default:
+		switch err.Error() {
+		case "user not found":
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "User"))
+		case "role grant not found":
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Role grant"))
+		default:
+			ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		}
+	}
+}
+
+// GetUserRoles lists the role of a user's account and the roles granted on top of it (admin only)
+func (c *UserRoleController) GetUserRoles(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse user ID
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid user ID"))
+		return
+	}
+
+	roles, err := c.userRoleService.GetRoles(userID)
+	if err != nil {
+		respondUserRoleError(ctx, requestID, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, roles))
+}
+
+// GrantUserRole grants a role to a user, optionally until an expiry date (admin only)
+func (c *UserRoleController) GrantUserRole(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	claims, ok := currentClaims(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid user ID"))
+		return
+	}
+
+	var req models.GrantRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	grant, err := c.userRoleService.GrantRole(claims, userID, &req)
+	if err != nil {
+		respondUserRoleError(ctx, requestID, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, grant))
+}
+
+// RevokeUserRole removes a role granted to a user (admin only)
+func (c *UserRoleController) RevokeUserRole(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	claims, ok := currentClaims(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid user ID"))
+		return
+	}
+
+	if err := c.userRoleService.RevokeRole(claims, userID, ctx.Param("role")); err != nil {
+		respondUserRoleError(ctx, requestID, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Role revoked"}))
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"net/http"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebAuthnController handles security key and passkey HTTP requests
+type WebAuthnController struct {
+	webAuthnService *services.WebAuthnService
+}
+
+// NewWebAuthnController creates a new WebAuthn controller
+func NewWebAuthnController(webAuthnService *services.WebAuthnService) *WebAuthnController {
+	return &WebAuthnController{
+		webAuthnService: webAuthnService,
+	}
+}
+
+// BeginRegistration returns the options for registering a security key or passkey for the current user
+func (c *WebAuthnController) BeginRegistration(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	options, err := c.webAuthnService.BeginRegistration(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, options))
+}
+
+// FinishRegistration stores the security key or passkey created by the authenticator
+func (c *WebAuthnController) FinishRegistration(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse request body
+	var req models.WebAuthnRegistrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	credential, err := c.webAuthnService.FinishRegistration(userID, &req)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.SuccessResponse(requestID, credential))
+}
+
+// GetCredentials lists the security keys and passkeys of the current user
+func (c *WebAuthnController) GetCredentials(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(requestID))
+		return
+	}
+
+	credentials, err := c.webAuthnService.GetCredentials(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, credentials))
+}
+
+// DeleteCredential removes a security key or passkey of the current user
+func (c *WebAuthnController) DeleteCredential(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	userID, ok := interactiveUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(requestID))
+		return
+	}
+
+	// Parse credential ID
+	credentialID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, "Invalid credential ID"))
+		return
+	}
+
+	err = c.webAuthnService.DeleteCredential(userID, credentialID)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusNotFound, models.NotFoundErrorResponse(requestID, "Security key"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, gin.H{"message": "Security key removed"}))
+}
+
+// BeginLogin returns the options for logging in with a passkey
+func (c *WebAuthnController) BeginLogin(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	options, err := c.webAuthnService.BeginLogin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, options))
+}
+
+// FinishLogin logs a user in with a passkey
+func (c *WebAuthnController) FinishLogin(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.WebAuthnLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Login user
+	response, err := c.webAuthnService.CompleteLogin(&req, clientInfo(ctx, req.DeviceName))
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusUnauthorized, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
+
+// BeginMFA returns the options for completing a login with a security key as second factor
+func (c *WebAuthnController) BeginMFA(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.WebAuthnMFAStartRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	options, err := c.webAuthnService.BeginMFA(&req)
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusUnauthorized, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, options))
+}
+
+// FinishMFA completes a login for users with two-factor authentication using a security key
+func (c *WebAuthnController) FinishMFA(ctx *gin.Context) {
+	requestID := ctx.GetString("requestId")
+
+	// Parse request body
+	var req models.WebAuthnMFALoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.ValidationErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	// Verify second factor
+	response, err := c.webAuthnService.CompleteMFALogin(&req, clientInfo(ctx, req.DeviceName))
+	if err != nil {
+		if _, ok := err.(*services.ValidationError); ok {
+			ctx.JSON(http.StatusUnauthorized, models.ValidationErrorResponse(requestID, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(requestID, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.SuccessResponse(requestID, response))
+}
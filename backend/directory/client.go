@@ -0,0 +1,262 @@
+// Package directory authenticates users against an LDAP server or Active Directory.
+package directory
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ErrInvalidCredentials is returned when the login is unknown, ambiguous or the password is wrong
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Config describes how to find and bind users in a directory
+type Config struct {
+	// URL of the server, e.g. ldaps://ldap.example.com:636 or ldap://dc.corp.example.com
+	URL string
+	// StartTLS upgrades a plain ldap:// connection before any credentials are sent
+	StartTLS bool
+	// InsecureSkipVerify disables TLS certificate verification (test servers only)
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+
+	// BindDN and BindPassword are the service account used to search users; empty binds anonymously
+	BindDN       string
+	BindPassword string
+
+	// BaseDN is where users are searched
+	BaseDN string
+	// UserFilter finds the entry of a login; {login} is replaced by the escaped login
+	UserFilter string
+
+	// GroupBaseDN enables searching groups by member; without it only the memberOf attribute is used
+	GroupBaseDN string
+	// GroupFilter finds the groups of a user; {dn} is replaced by the escaped user DN
+	GroupFilter string
+
+	// Attributes holding the profile of a user
+	EmailAttribute     string
+	UsernameAttribute  string
+	FirstNameAttribute string
+	LastNameAttribute  string
+	GroupAttribute     string
+}
+
+// Entry is an authenticated directory user
+type Entry struct {
+	DN        string
+	Email     string
+	Username  string
+	FirstName string
+	LastName  string
+	// Groups are the DNs of the groups the user belongs to
+	Groups []string
+}
+
+// InGroup reports whether the user belongs to a group, given either by DN or by the
+// value of the first component of its DN (e.g. "admins" for cn=admins,ou=groups,dc=example,dc=com)
+func (e *Entry) InGroup(group string) bool {
+	groupDN, err := ldap.ParseDN(group)
+	isDN := err == nil && len(groupDN.RDNs) > 0 && strings.Contains(group, "=")
+
+	for _, member := range e.Groups {
+		memberDN, err := ldap.ParseDN(member)
+		if err != nil || len(memberDN.RDNs) == 0 {
+			continue
+		}
+		if isDN && memberDN.EqualFold(groupDN) {
+			return true
+		}
+		if !isDN && strings.EqualFold(memberDN.RDNs[0].Attributes[0].Value, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Client authenticates users by searching their entry with the service account, then binding as them.
+// A new connection is opened for each login.
+type Client struct {
+	config Config
+}
+
+// NewClient creates a directory client; unset filters, attributes and timeout get defaults
+// suitable for OpenLDAP and Active Directory
+func NewClient(config Config) *Client {
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.UserFilter == "" {
+		config.UserFilter = "(mail={login})"
+	}
+	if config.GroupFilter == "" {
+		config.GroupFilter = "(|(member={dn})(uniqueMember={dn}))"
+	}
+	if config.EmailAttribute == "" {
+		config.EmailAttribute = "mail"
+	}
+	if config.UsernameAttribute == "" {
+		config.UsernameAttribute = "uid"
+	}
+	if config.FirstNameAttribute == "" {
+		config.FirstNameAttribute = "givenName"
+	}
+	if config.LastNameAttribute == "" {
+		config.LastNameAttribute = "sn"
+	}
+	if config.GroupAttribute == "" {
+		config.GroupAttribute = "memberOf"
+	}
+	return &Client{config: config}
+}
+
+// Authenticate verifies the password of a login and returns the user entry with its groups
+func (c *Client) Authenticate(login, password string) (*Entry, error) {
+	// An empty password would be an unauthenticated bind, which most servers accept
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := c.bindServiceAccount(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := c.findUser(conn, login)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind as %s: %w", entry.DN, err)
+	}
+
+	// Users may not be allowed to read groups: search them as the service account again
+	if c.config.GroupBaseDN != "" {
+		if err := c.bindServiceAccount(conn); err != nil {
+			return nil, err
+		}
+		groups, err := c.findGroups(conn, entry.DN)
+		if err != nil {
+			return nil, err
+		}
+		entry.Groups = appendUnique(entry.Groups, groups...)
+	}
+
+	return entry, nil
+}
+
+// connect opens a connection, upgraded with StartTLS when configured
+func (c *Client) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.config.InsecureSkipVerify}
+	conn, err := ldap.DialURL(c.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: c.config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+	conn.SetTimeout(c.config.Timeout)
+
+	if c.config.StartTLS {
+		if serverURL, err := url.Parse(c.config.URL); err == nil {
+			tlsConfig.ServerName = serverURL.Hostname()
+		}
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// bindServiceAccount binds with the service account, or anonymously when none is configured
+func (c *Client) bindServiceAccount(conn *ldap.Conn) error {
+	var err error
+	if c.config.BindDN == "" {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(c.config.BindDN, c.config.BindPassword)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to bind with the LDAP service account: %w", err)
+	}
+	return nil
+}
+
+// findUser returns the only entry matching the login
+func (c *Client) findUser(conn *ldap.Conn, login string) (*Entry, error) {
+	filter := strings.ReplaceAll(c.config.UserFilter, "{login}", ldap.EscapeFilter(login))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		c.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false, filter,
+		[]string{c.config.EmailAttribute, c.config.UsernameAttribute, c.config.FirstNameAttribute, c.config.LastNameAttribute, c.config.GroupAttribute},
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search LDAP user: %w", err)
+	}
+
+	// Refuse ambiguous logins rather than picking one of the entries
+	if result == nil || len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	found := result.Entries[0]
+	return &Entry{
+		DN:        found.DN,
+		Email:     found.GetAttributeValue(c.config.EmailAttribute),
+		Username:  found.GetAttributeValue(c.config.UsernameAttribute),
+		FirstName: found.GetAttributeValue(c.config.FirstNameAttribute),
+		LastName:  found.GetAttributeValue(c.config.LastNameAttribute),
+		Groups:    found.GetAttributeValues(c.config.GroupAttribute),
+	}, nil
+}
+
+// findGroups returns the DNs of the groups having the user as a member
+func (c *Client) findGroups(conn *ldap.Conn, userDN string) ([]string, error) {
+	filter := strings.ReplaceAll(c.config.GroupFilter, "{dn}", ldap.EscapeFilter(userDN))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		c.config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, filter,
+		[]string{"cn"}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search LDAP groups: %w", err)
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, group := range result.Entries {
+		groups = append(groups, group.DN)
+	}
+	return groups, nil
+}
+
+// appendUnique appends the values not already present, comparing DNs case-insensitively
+func appendUnique(values []string, more ...string) []string {
+	for _, value := range more {
+		found := false
+		for _, existing := range values {
+			if strings.EqualFold(existing, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,153 @@
+package directory_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"angular-n-go-template/backend/directory"
+	"angular-n-go-template/backend/directory/directorytest"
+)
+
+const (
+	serviceDN       = "cn=service,dc=example,dc=com"
+	servicePassword = "service-secret"
+	janeDN          = "uid=jane,ou=people,dc=example,dc=com"
+	adminsDN        = "cn=admins,ou=groups,dc=example,dc=com"
+	developersDN    = "cn=developers,ou=groups,dc=example,dc=com"
+)
+
+func newTestServer() *directorytest.Server {
+	return directorytest.NewServer(
+		directorytest.Entry{DN: serviceDN, Password: servicePassword},
+		directorytest.Entry{
+			DN:       janeDN,
+			Password: "jane-password",
+			Attributes: map[string][]string{
+				"uid":       {"jane"},
+				"mail":      {"jane@example.com"},
+				"givenName": {"Jane"},
+				"sn":        {"Doe"},
+				"memberOf":  {developersDN},
+			},
+		},
+		directorytest.Entry{
+			DN:         "uid=twin1,ou=people,dc=example,dc=com",
+			Password:   "twin-password",
+			Attributes: map[string][]string{"uid": {"twin1"}, "mail": {"twin@example.com"}},
+		},
+		directorytest.Entry{
+			DN:         "uid=twin2,ou=people,dc=example,dc=com",
+			Password:   "twin-password",
+			Attributes: map[string][]string{"uid": {"twin2"}, "mail": {"twin@example.com"}},
+		},
+		directorytest.Entry{DN: adminsDN, Attributes: map[string][]string{"cn": {"admins"}, "member": {janeDN}}},
+		directorytest.Entry{DN: developersDN, Attributes: map[string][]string{"cn": {"developers"}, "member": {janeDN}}},
+	)
+}
+
+func newTestClient(server *directorytest.Server, groupBaseDN string) *directory.Client {
+	return directory.NewClient(directory.Config{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: servicePassword,
+		BaseDN:       "ou=people,dc=example,dc=com",
+		GroupBaseDN:  groupBaseDN,
+	})
+}
+
+func TestClient_Authenticate(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	entry, err := newTestClient(server, "ou=groups,dc=example,dc=com").Authenticate("jane@example.com", "jane-password")
+	if err != nil {
+		t.Fatalf("expected valid credentials to be accepted, got %v", err)
+	}
+
+	want := &directory.Entry{
+		DN:        janeDN,
+		Email:     "jane@example.com",
+		Username:  "jane",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		// memberOf first, then the groups found by member without duplicates
+		Groups: []string{developersDN, adminsDN},
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("unexpected entry:\n got %+v\nwant %+v", entry, want)
+	}
+}
+
+func TestClient_AuthenticateWithMemberOfOnly(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	entry, err := newTestClient(server, "").Authenticate("jane@example.com", "jane-password")
+	if err != nil {
+		t.Fatalf("expected valid credentials to be accepted, got %v", err)
+	}
+	if !reflect.DeepEqual(entry.Groups, []string{developersDN}) {
+		t.Errorf("expected only the memberOf groups, got %v", entry.Groups)
+	}
+}
+
+func TestClient_AuthenticateRejectsInvalidCredentials(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	client := newTestClient(server, "")
+
+	cases := map[string][2]string{
+		"wrong password":    {"jane@example.com", "wrong-password"},
+		"empty password":    {"jane@example.com", ""},
+		"unknown login":     {"nobody@example.com", "jane-password"},
+		"ambiguous login":   {"twin@example.com", "twin-password"},
+		"filter injection":  {"*", "jane-password"},
+		"service account":   {serviceDN, servicePassword},
+		"injected wildcard": {"jane@example.com)(mail=*", "jane-password"},
+	}
+
+	for name, credentials := range cases {
+		if _, err := client.Authenticate(credentials[0], credentials[1]); !errors.Is(err, directory.ErrInvalidCredentials) {
+			t.Errorf("%s: expected ErrInvalidCredentials, got %v", name, err)
+		}
+	}
+}
+
+func TestClient_AuthenticateReportsServiceAccountErrors(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	client := directory.NewClient(directory.Config{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: "wrong",
+		BaseDN:       "ou=people,dc=example,dc=com",
+	})
+
+	// A misconfigured service account is an error, not a wrong password of the user
+	_, err := client.Authenticate("jane@example.com", "jane-password")
+	if err == nil || errors.Is(err, directory.ErrInvalidCredentials) {
+		t.Errorf("expected a service account error, got %v", err)
+	}
+}
+
+func TestEntry_InGroup(t *testing.T) {
+	entry := &directory.Entry{Groups: []string{"CN=Domain Admins,OU=Groups,DC=corp,DC=example,DC=com", developersDN}}
+
+	cases := map[string]bool{
+		"cn=domain admins,ou=groups,dc=corp,dc=example,dc=com": true,
+		"Domain Admins": true,
+		"developers":    true,
+		developersDN:    true,
+		"admins":        false,
+		"cn=developers,ou=other,dc=example,dc=com": false,
+		"ou=groups,dc=example,dc=com":              false,
+	}
+
+	for group, want := range cases {
+		if got := entry.InGroup(group); got != want {
+			t.Errorf("InGroup(%q) = %v, want %v", group, got, want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Package directorytest provides a minimal in-process LDAP server for tests.
+package directorytest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Filter choices of the search request (RFC 4511 section 4.5.1)
+const (
+	filterAnd      = 0
+	filterOr       = 1
+	filterNot      = 2
+	filterEquality = 3
+	filterPresent  = 7
+)
+
+// Entry is a directory entry; entries with a Password accept simple binds
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is a mock LDAP server supporting simple and anonymous binds and searches with
+// and, or, not, equality and presence filters. StartTLS and other operations are refused.
+type Server struct {
+	listener net.Listener
+	entries  []Entry
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// NewServer starts a server on a random local port serving the entries; close it with Close
+func NewServer(entries ...Entry) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{listener: listener, entries: entries, conns: make(map[net.Conn]struct{})}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// URL returns the ldap:// URL of the server
+func (s *Server) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// Close stops the server and closes open connections
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			s.bind(conn, messageID, request)
+		case ldap.ApplicationSearchRequest:
+			s.search(conn, messageID, request)
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationExtendedRequest:
+			writeResult(conn, messageID, ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError, "unsupported extended operation")
+		default:
+			writeResult(conn, messageID, int(request.Tag)+1, ldap.LDAPResultUnwillingToPerform, "unsupported operation")
+		}
+	}
+}
+
+// bind accepts anonymous binds and simple binds with the password of an entry
+func (s *Server) bind(conn net.Conn, messageID int64, request *ber.Packet) {
+	if len(request.Children) < 3 {
+		writeResult(conn, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultProtocolError, "malformed bind request")
+		return
+	}
+	name := packetString(request.Children[1])
+	password := packetString(request.Children[2])
+
+	if name == "" && password == "" {
+		writeResult(conn, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "")
+		return
+	}
+
+	entry := s.entry(name)
+	if entry == nil || entry.Password == "" || entry.Password != password {
+		writeResult(conn, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, "invalid credentials")
+		return
+	}
+	writeResult(conn, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "")
+}
+
+// search returns the entries under the base DN matching the filter
+func (s *Server) search(conn net.Conn, messageID int64, request *ber.Packet) {
+	if len(request.Children) < 8 {
+		writeResult(conn, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, "malformed search request")
+		return
+	}
+	baseDN := strings.ToLower(packetString(request.Children[0]))
+	sizeLimit, _ := request.Children[3].Value.(int64)
+	filter := request.Children[6]
+
+	var attributes []string
+	for _, attribute := range request.Children[7].Children {
+		attributes = append(attributes, packetString(attribute))
+	}
+
+	sent := int64(0)
+	for i := range s.entries {
+		entry := &s.entries[i]
+		dn := strings.ToLower(entry.DN)
+		if dn != baseDN && !strings.HasSuffix(dn, ","+baseDN) {
+			continue
+		}
+		if !matches(entry, filter) {
+			continue
+		}
+		if sizeLimit > 0 && sent == sizeLimit {
+			writeResult(conn, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded, "size limit exceeded")
+			return
+		}
+		writeEntry(conn, messageID, entry, attributes)
+		sent++
+	}
+
+	writeResult(conn, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, "")
+}
+
+// entry returns the entry with a DN, compared case-insensitively
+func (s *Server) entry(dn string) *Entry {
+	for i := range s.entries {
+		if strings.EqualFold(s.entries[i].DN, dn) {
+			return &s.entries[i]
+		}
+	}
+	return nil
+}
+
+// matches evaluates a search filter against an entry; unsupported filters match nothing
+func matches(entry *Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case filterAnd:
+		for _, child := range filter.Children {
+			if !matches(entry, child) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.Children {
+			if matches(entry, child) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !matches(entry, filter.Children[0])
+	case filterEquality:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		want := packetString(filter.Children[1])
+		for _, value := range attributeValues(entry, packetString(filter.Children[0])) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		return len(attributeValues(entry, filter.Data.String())) > 0
+	}
+	return false
+}
+
+// attributeValues returns the values of an attribute, whose name is compared case-insensitively
+func attributeValues(entry *Entry, name string) []string {
+	if strings.EqualFold(name, "objectClass") && len(entry.Attributes["objectClass"]) == 0 {
+		return []string{"top"}
+	}
+	for attribute, values := range entry.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func writeEntry(conn net.Conn, messageID int64, entry *Entry, attributes []string) {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "objectName"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range entry.Attributes {
+		if !requested(name, attributes) {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	response.AppendChild(list)
+
+	writeMessage(conn, messageID, response)
+}
+
+// requested reports whether an attribute was asked for; an empty list or "*" asks for all of them
+func requested(name string, attributes []string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+	for _, attribute := range attributes {
+		if attribute == "*" || strings.EqualFold(attribute, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeResult(conn net.Conn, messageID int64, tag, resultCode int, message string) {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ber.Tag(tag), nil, "Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "resultCode"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "diagnosticMessage"))
+	writeMessage(conn, messageID, response)
+}
+
+func writeMessage(conn net.Conn, messageID int64, response *ber.Packet) {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	envelope.AppendChild(response)
+	conn.Write(envelope.Bytes())
+}
+
+// packetString returns the content of an octet string, whatever its class
+func packetString(packet *ber.Packet) string {
+	if value, ok := packet.Value.(string); ok {
+		return value
+	}
+	return packet.Data.String()
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"os"
+)
+
+// Message represents a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// InitMailer returns the mailer selected by MAIL_DRIVER.
+// Supported drivers are "smtp", "file" (appends to MAIL_FILE_PATH) and "stdout" (default).
+func InitMailer() Mailer {
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		log.Println("Sending emails through SMTP")
+		return NewSMTPMailerFromEnv()
+	case "file":
+		path := os.Getenv("MAIL_FILE_PATH")
+		if path == "" {
+			path = "mail.log"
+		}
+		log.Printf("Writing emails to %s", path)
+		return NewFileMailer(path)
+	default:
+		log.Println("Writing emails to stdout; set MAIL_DRIVER=smtp to deliver them")
+		return NewWriterMailer(os.Stdout)
+	}
+}
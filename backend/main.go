@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"angular-n-go-template/backend/config"
 	"angular-n-go-template/backend/controllers"
+	"angular-n-go-template/backend/directory"
+	"angular-n-go-template/backend/mailer"
 	"angular-n-go-template/backend/middleware"
+	"angular-n-go-template/backend/oidc"
 	"angular-n-go-template/backend/rbac"
 	"angular-n-go-template/backend/repositories"
 	"angular-n-go-template/backend/security"
@@ -23,6 +29,11 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	// Initialize JWT signing keys
+	if err := security.InitJWT(); err != nil {
+		log.Fatal("Failed to initialize JWT signing keys:", err)
+	}
+
 	// Initialize database
 	db := security.InitDB()
 	defer db.Close()
@@ -34,12 +45,79 @@ func main() {
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
 	requestLogRepo := repositories.NewRequestLogRepository(redisClient)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(redisClient)
+	tokenRevocationRepo := repositories.NewTokenRevocationRepository(redisClient)
+	oneTimeTokenRepo := repositories.NewOneTimeTokenRepository(redisClient)
+	sessionRepo := repositories.NewSessionRepository(redisClient)
+	oidcStateRepo := repositories.NewOIDCStateRepository(redisClient)
+	mfaRepo := repositories.NewMFARepository(db)
+	invitationRepo := repositories.NewInvitationRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	loginAttemptRepo := repositories.NewLoginAttemptRepository(redisClient)
+	passwordHistoryRepo := repositories.NewPasswordHistoryRepository(db)
+	userIdentityRepo := repositories.NewUserIdentityRepository(db)
+	rateLimitRepo := repositories.NewRateLimitRepository(redisClient)
+	webAuthnCredentialRepo := repositories.NewWebAuthnCredentialRepository(db)
+	webAuthnChallengeRepo := repositories.NewWebAuthnChallengeRepository(redisClient)
+	rbacRepo := repositories.NewRBACRepository(db)
+	rbacEventRepo := repositories.NewRBACEventRepository(redisClient)
+	userRoleRepo := repositories.NewUserRoleRepository(db)
+	principalCacheRepo := repositories.NewPrincipalCacheRepository(redisClient)
+
+	// Initialize mailer
+	mail := mailer.InitMailer()
+
+	// Initialize RBAC configuration
+	rbacConfigPath := os.Getenv("RBAC_CONFIG_PATH")
+	if rbacConfigPath == "" {
+		rbacConfigPath = "config/rbac.json"
+	}
+	baseRBACConfig, err := rbac.LoadConfig(rbacConfigPath)
+	if err != nil {
+		log.Fatalf("Failed to load RBAC configuration: %v", err)
+	}
+
+	// Roles and permissions managed through the admin API are added to those of the file
+	rbacService := services.NewRBACService(baseRBACConfig, rbacRepo, rbacEventRepo, userRepo)
+	if err := rbacService.Refresh(); err != nil {
+		log.Fatalf("Failed to load stored roles and permissions: %v", err)
+	}
+	rbacConfig := rbacService.Config()
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, requestLogRepo)
-	userService := services.NewUserService(userRepo)
+	tokenService := services.NewTokenService(refreshTokenRepo, tokenRevocationRepo, sessionRepo, userRoleRepo)
+	principalService := services.NewPrincipalService(userRepo, userRoleRepo, principalCacheRepo)
+	emailVerificationService := services.NewEmailVerificationService(userRepo, oneTimeTokenRepo, principalService, mail)
+	mfaService := services.NewMFAService(mfaRepo, userRepo, webAuthnCredentialRepo)
+	loginProtectionService := services.NewLoginProtectionService(loginAttemptRepo)
+	passwordPolicyService := services.NewPasswordPolicyService(passwordHistoryRepo)
+
+	// Password authenticators: local hashes first, then the LDAP directory when configured
+	authenticators := []services.Authenticator{services.NewLocalAuthenticator(userRepo)}
+	if ldapSettings := services.LDAPSettingsFromEnv(); ldapSettings != nil {
+		ldapClient := directory.NewClient(ldapSettings.Directory)
+		authenticators = append(authenticators, services.NewLDAPAuthenticator(ldapClient, ldapSettings, userRepo, principalService, rbacConfig))
+	}
+	authService := services.NewAuthService(userRepo, requestLogRepo, tokenService, emailVerificationService, mfaService, loginProtectionService, passwordPolicyService, principalService, authenticators)
+	passwordResetService := services.NewPasswordResetService(userRepo, oneTimeTokenRepo, tokenService, passwordPolicyService, mail)
+	userService := services.NewUserService(userRepo, emailVerificationService, passwordResetService, passwordPolicyService, tokenService, principalService, rbacConfig)
 	requestLogService := services.NewRequestLogService(requestLogRepo)
 	adminSeedService := services.NewAdminSeedService(userRepo)
+	invitationService := services.NewInvitationService(invitationRepo, userRepo, passwordPolicyService, rbacConfig, mail)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo, userRoleRepo, rbacConfig)
+	sessionService := services.NewSessionService(sessionRepo)
+	impersonationService := services.NewImpersonationService(userRepo, userRoleRepo, tokenService, rbacConfig)
+	userRoleService := services.NewUserRoleService(userRoleRepo, userRepo, principalService, rbacConfig)
+	accessService := services.NewAccessService(userRepo, userRoleService, rbacConfig)
+	magicLinkService := services.NewMagicLinkService(userRepo, rateLimitRepo, tokenService, mfaService, principalService, mail)
+	webAuthnService := services.NewWebAuthnService(services.WebAuthnConfigFromEnv(), webAuthnCredentialRepo, webAuthnChallengeRepo, userRepo, tokenService, loginProtectionService)
+
+	// Single sign-on providers
+	var oidcProviders []*oidc.Provider
+	for _, providerConfig := range services.OIDCProvidersFromEnv() {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(providerConfig, nil))
+	}
+	oidcService := services.NewOIDCService(oidcProviders, oidcStateRepo, userIdentityRepo, userRepo, tokenService, mfaService, principalService)
 
 	// Seed default admin account if configured
 	if err := adminSeedService.SeedDefaultAdmin(); err != nil {
@@ -47,16 +125,34 @@ func main() {
 	}
 
 	// Initialize controllers
-	authController := controllers.NewAuthController(authService, requestLogService)
+	authController := controllers.NewAuthController(authService, requestLogService, passwordResetService, emailVerificationService, magicLinkService)
+	mfaController := controllers.NewMFAController(mfaService)
+	invitationController := controllers.NewInvitationController(invitationService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	sessionController := controllers.NewSessionController(sessionService)
+	oidcController := controllers.NewOIDCController(oidcService)
+	impersonationController := controllers.NewImpersonationController(impersonationService)
+	webAuthnController := controllers.NewWebAuthnController(webAuthnService)
+	rbacController := controllers.NewRBACController(rbacService, accessService)
+	userRoleController := controllers.NewUserRoleController(userRoleService)
 	userController := controllers.NewUserController(userService, requestLogService)
-	adminController := controllers.NewAdminController(requestLogService)
-
-	// Initialize RBAC configuration
-	rbacConfig := rbac.DefaultRBACConfig()
+	adminController := controllers.NewAdminController(requestLogService, loginProtectionService, userService)
 
 	// Initialize Gin router
 	router := gin.Default()
 
+	// Client IPs drive login backoff and rate limits, so X-Forwarded-For is only trusted
+	// from the proxies listed in TRUSTED_PROXIES (comma-separated IPs or CIDRs, none by default)
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// CORS configuration
 	corsConfig := cors.DefaultConfig()
 	corsOrigin := os.Getenv("CORS_ORIGIN")
@@ -64,8 +160,8 @@ func main() {
 		corsOrigin = "http://localhost:4200"
 	}
 	corsConfig.AllowOrigins = []string{corsOrigin}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-API-Key"}
 	corsConfig.AllowCredentials = true
 	router.Use(cors.New(corsConfig))
 
@@ -73,7 +169,18 @@ func main() {
 	router.Use(middleware.RequestLogger(requestLogService))
 
 	// Setup routes with configurable RBAC
-	config.SetupRoutes(router, authController, userController, adminController, rbacConfig)
+	routeConfigs := config.SetupRoutes(router, authController, mfaController, invitationController, apiKeyController, sessionController, oidcController, impersonationController, webAuthnController, rbacController, userRoleController, userController, adminController, rbacConfig, tokenService, apiKeyService, userRoleService, principalService)
+
+	accessService.SetRoutes(config.RouteRules(routeConfigs))
+
+	// Every permission protecting a route must be defined, at startup and after each reload
+	if err := rbacService.RequirePermissions(config.RoutePermissions(routeConfigs)); err != nil {
+		log.Fatalf("Invalid RBAC configuration %s: %v", rbacConfigPath, err)
+	}
+	rbacReloader := rbac.NewReloader(rbacConfigPath, rbacService.SetBaseConfig)
+	go rbacReloader.Watch(context.Background(), security.DurationFromEnv("RBAC_RELOAD_INTERVAL", 5*time.Second))
+	go rbacService.WatchChanges(context.Background())
+	go userRoleService.WatchExpiredGrants(context.Background())
 
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
@@ -5,84 +5,111 @@ import (
 	"strings"
 
 	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
 	"angular-n-go-template/backend/security"
+	"angular-n-go-template/backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens or API keys and rejects revoked ones.
+// The roles of the user are resolved at each request, and deactivated users are rejected.
+func AuthMiddleware(tokenService *services.TokenService, apiKeyService *services.APIKeyService, principalService *services.PrincipalService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		claims, ok := authenticate(c, tokenService, apiKeyService, principalService)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(c.GetString("requestId")))
 			c.Abort()
 			return
 		}
 
-		// Check if token starts with "Bearer "
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(c.GetString("requestId")))
-			c.Abort()
-			return
-		}
+		setClaims(c, claims)
 
-		tokenString := tokenParts[1]
+		c.Next()
+	}
+}
 
-		// Validate token
-		claims, err := security.ValidateToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.UnauthorizedErrorResponse(c.GetString("requestId")))
-			c.Abort()
-			return
+// OptionalAuthMiddleware validates JWT tokens or API keys but doesn't require them
+func OptionalAuthMiddleware(tokenService *services.TokenService, apiKeyService *services.APIKeyService, principalService *services.PrincipalService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := authenticate(c, tokenService, apiKeyService, principalService); ok {
+			setClaims(c, claims)
 		}
 
-		// Set user info in context
-		c.Set("userID", claims.UserID)
-		c.Set("userEmail", claims.Email)
-		c.Set("username", claims.Username)
-		c.Set("userRole", claims.Role)
-
 		c.Next()
 	}
 }
 
-// OptionalAuthMiddleware validates JWT tokens but doesn't require them
-func OptionalAuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Get token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.Next()
-			return
-		}
+// authenticate resolves the credentials of a request.
+// API keys are sent in the X-API-Key header or as a bearer token starting with services.APIKeyPrefix;
+// any other bearer token is validated as a JWT access token, whose roles are replaced by the current ones of the user.
+func authenticate(c *gin.Context, tokenService *services.TokenService, apiKeyService *services.APIKeyService, principalService *services.PrincipalService) (*security.Claims, bool) {
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		claims, err := apiKeyService.Authenticate(apiKey)
+		return claims, err == nil
+	}
 
-		// Check if token starts with "Bearer "
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.Next()
-			return
-		}
+	tokenString, ok := bearerToken(c)
+	if !ok {
+		return nil, false
+	}
 
-		tokenString := tokenParts[1]
+	if services.IsAPIKey(tokenString) {
+		claims, err := apiKeyService.Authenticate(tokenString)
+		return claims, err == nil
+	}
 
-		// Validate token
-		claims, err := security.ValidateToken(tokenString)
-		if err != nil {
-			c.Next()
-			return
-		}
+	// Validate token
+	claims, err := tokenService.ValidateAccessToken(c.Request.Context(), tokenString)
+	if err != nil {
+		return nil, false
+	}
 
-		// Set user info in context
-		c.Set("userID", claims.UserID)
-		c.Set("userEmail", claims.Email)
-		c.Set("username", claims.Username)
-		c.Set("userRole", claims.Role)
+	// Unlike API keys, access tokens carry the roles the user had when they were issued
+	claims, err = principalService.Apply(c.Request.Context(), claims)
+	return claims, err == nil
+}
 
-		c.Next()
+// bearerToken extracts the token from a "Bearer <token>" Authorization header
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return "", false
+	}
+
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return "", false
+	}
+
+	return tokenParts[1], true
+}
+
+// setClaims stores the authenticated user info in the request context
+func setClaims(c *gin.Context, claims *security.Claims) {
+	c.Set("tokenClaims", claims)
+	c.Set("userID", claims.UserID)
+	c.Set("userEmail", claims.Email)
+	c.Set("username", claims.Username)
+	roles := effectiveRoles(claims)
+	c.Set("userRole", roles[0])
+	c.Set("userRoles", roles)
+	c.Set("mfaAuthenticated", claims.HasAuthMethod(security.AuthMethodMFA))
+	if claims.Scopes != nil {
+		c.Set("tokenScopes", claims.Scopes)
+	}
+	if claims.Actor != nil {
+		c.Set("actorID", claims.Actor.UserID)
+	}
+}
+
+// effectiveRoles returns the roles used for permission checks, the role of the account first.
+// Unverified users only get the restricted role under the "restrict" email verification policy.
+func effectiveRoles(claims *security.Claims) []string {
+	if claims.EmailUnverified && services.EmailVerificationPolicy() == services.EmailVerificationRestrict {
+		return []string{rbac.UnverifiedRole}
 	}
+	return claims.AllRoles()
 }
 
 // RoleMiddleware checks if the user has the required role
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"testing"
+
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/security"
+)
+
+func TestEffectiveRoles_RestrictsUnverifiedUsers(t *testing.T) {
+	claims := &security.Claims{Role: "admin", Roles: []string{"moderator"}, EmailUnverified: true}
+
+	t.Setenv("EMAIL_VERIFICATION_POLICY", "restrict")
+	if roles := effectiveRoles(claims); len(roles) != 1 || roles[0] != rbac.UnverifiedRole {
+		t.Errorf("expected only the %q role under the restrict policy, got %v", rbac.UnverifiedRole, roles)
+	}
+
+	verified := *claims
+	verified.EmailUnverified = false
+	if roles := effectiveRoles(&verified); len(roles) != 2 {
+		t.Errorf("expected a verified user to keep their roles, got %v", roles)
+	}
+
+	t.Setenv("EMAIL_VERIFICATION_POLICY", "off")
+	if roles := effectiveRoles(claims); len(roles) != 2 {
+		t.Errorf("expected the roles to be kept when verification is off, got %v", roles)
+	}
+}
@@ -3,32 +3,30 @@ package middleware
 import (
 	"net/http"
 
-	"angular-n-go-template/backend/rbac"
 	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-// PermissionMiddleware checks if the user has the required permission
+// PermissionMiddleware checks if one of the user's roles has the required permission
 func PermissionMiddleware(requiredPermission string, rbacConfig *rbac.RBACConfig) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userRole, exists := c.Get("userRole")
-		if !exists {
-			c.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(c.GetString("requestId")))
-			c.Abort()
-			return
-		}
+	return MultiplePermissionsMiddleware([]string{requiredPermission}, rbacConfig)
+}
 
-		role, ok := userRole.(string)
+// MultiplePermissionsMiddleware checks if one of the user's roles has any of the required permissions
+func MultiplePermissionsMiddleware(requiredPermissions []string, rbacConfig *rbac.RBACConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := principal(c)
 		if !ok {
 			c.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(c.GetString("requestId")))
 			c.Abort()
 			return
 		}
 
-		if !rbacConfig.HasPermission(role, requiredPermission) {
-			c.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(c.GetString("requestId")))
-			c.Abort()
+		if check := rbacConfig.CheckPermissions(p, requiredPermissions); !check.Passed {
+			denyAccess(c, check)
 			return
 		}
 
@@ -36,38 +34,50 @@ func PermissionMiddleware(requiredPermission string, rbacConfig *rbac.RBACConfig
 	}
 }
 
-// MultiplePermissionsMiddleware checks if the user has any of the required permissions
-func MultiplePermissionsMiddleware(requiredPermissions []string, rbacConfig *rbac.RBACConfig) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userRole, exists := c.Get("userRole")
-		if !exists {
-			c.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(c.GetString("requestId")))
-			c.Abort()
-			return
-		}
+// principal describes the authenticated user, or API key, that access is decided for
+func principal(c *gin.Context) (*rbac.Principal, bool) {
+	userRoles, exists := c.Get("userRoles")
+	if !exists {
+		return nil, false
+	}
 
-		role, ok := userRole.(string)
-		if !ok {
-			c.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(c.GetString("requestId")))
-			c.Abort()
-			return
-		}
+	roles, ok := userRoles.([]string)
+	if !ok {
+		return nil, false
+	}
 
-		hasPermission := false
-		for _, permission := range requiredPermissions {
-			if rbacConfig.HasPermission(role, permission) {
-				hasPermission = true
-				break
-			}
+	p := &rbac.Principal{
+		Roles:            roles,
+		MFAAuthenticated: c.GetBool("mfaAuthenticated"),
+	}
+	if userID, ok := c.Get("userID"); ok {
+		if id, ok := userID.(uuid.UUID); ok {
+			p.ID = id.String()
 		}
-
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(c.GetString("requestId")))
-			c.Abort()
-			return
+	}
+	if scopesValue, scoped := c.Get("tokenScopes"); scoped {
+		// API keys without any scope are still scoped
+		p.Scopes, _ = scopesValue.([]string)
+		if p.Scopes == nil {
+			p.Scopes = []string{}
 		}
-
-		c.Next()
 	}
+
+	return p, true
 }
 
+// denyAccess answers a request whose access check failed. In debug mode the response
+// names what is missing, e.g. the permission none of the user's roles grants.
+func denyAccess(c *gin.Context, check rbac.Check) {
+	var details string
+	if gin.IsDebugging() {
+		details = check.Reason
+	}
+
+	if check.MFARequired {
+		c.JSON(http.StatusForbidden, models.ErrorResponse(c.GetString("requestId"), "MFA_REQUIRED", "Two-factor authentication required", details))
+	} else {
+		c.JSON(http.StatusForbidden, models.ErrorResponse(c.GetString("requestId"), "FORBIDDEN", "Access forbidden", details))
+	}
+	c.Abort()
+}
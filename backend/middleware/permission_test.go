@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"angular-n-go-template/backend/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runPermissionMiddleware runs the permission middleware for a principal and returns the status code
+func runPermissionMiddleware(role string, scopes []string, permission string) int {
+	recorder := serveWithRoles(rbac.DefaultRBACConfig(), []string{role}, scopes, false, permission)
+	return recorder.Code
+}
+
+// serveWithRoles runs the permission middleware for a principal holding several roles
+func serveWithRoles(rbacConfig *rbac.RBACConfig, roles, scopes []string, mfaAuthenticated bool, permission string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Set("userRole", roles[0])
+		c.Set("userRoles", roles)
+		c.Set("mfaAuthenticated", mfaAuthenticated)
+		if scopes != nil {
+			c.Set("tokenScopes", scopes)
+		}
+		c.Next()
+	}, PermissionMiddleware(permission, rbacConfig), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	return recorder
+}
+
+func TestPermissionMiddleware_APIKeyScopes(t *testing.T) {
+	// An admin API key scoped to users.read cannot use the rest of the admin permissions
+	if code := runPermissionMiddleware("admin", []string{"users.read"}, "users.read"); code != http.StatusOK {
+		t.Errorf("expected scoped permission to be granted, got %d", code)
+	}
+	if code := runPermissionMiddleware("admin", []string{"users.read"}, "users.delete"); code != http.StatusForbidden {
+		t.Errorf("expected permission outside the key scopes to be denied, got %d", code)
+	}
+
+	// Scopes never grant more than the role
+	if code := runPermissionMiddleware("user", []string{"users.read"}, "users.read"); code != http.StatusForbidden {
+		t.Errorf("expected scope not granted by the role to be denied, got %d", code)
+	}
+
+	// Sessions without scopes keep every permission of their role
+	if code := runPermissionMiddleware("admin", nil, "users.delete"); code != http.StatusOK {
+		t.Errorf("expected unscoped admin session to be granted, got %d", code)
+	}
+}
+
+func TestPermissionMiddleware_UnionOfRoles(t *testing.T) {
+	rbacConfig := rbac.DefaultRBACConfig()
+	rbacConfig.AddRole(&rbac.Role{Name: "auditor", Permissions: []string{"admin.logs.read"}})
+	rbacConfig.AddRole(&rbac.Role{Name: "operator", RequireMFA: true, Permissions: []string{"admin.stats.read", "admin.logs.read"}})
+
+	// A role granted on top of the account's role adds its permissions
+	if code := serveWithRoles(rbacConfig, []string{"user", "auditor"}, nil, false, "admin.logs.read").Code; code != http.StatusOK {
+		t.Errorf("expected the granted role to give its permission, got %d", code)
+	}
+	if code := serveWithRoles(rbacConfig, []string{"user"}, nil, false, "admin.logs.read").Code; code != http.StatusForbidden {
+		t.Errorf("expected the account role alone to be denied, got %d", code)
+	}
+
+	// A role requiring MFA does not hide the permissions other roles grant without it
+	if code := serveWithRoles(rbacConfig, []string{"user", "operator"}, nil, false, "profile.read").Code; code != http.StatusOK {
+		t.Errorf("expected a permission of the account role to be granted without MFA, got %d", code)
+	}
+	if code := serveWithRoles(rbacConfig, []string{"operator", "auditor"}, nil, false, "admin.logs.read").Code; code != http.StatusOK {
+		t.Errorf("expected a role without MFA requirement to grant the permission, got %d", code)
+	}
+
+	recorder := serveWithRoles(rbacConfig, []string{"user", "operator"}, nil, false, "admin.stats.read")
+	if recorder.Code != http.StatusForbidden || !strings.Contains(recorder.Body.String(), "MFA_REQUIRED") {
+		t.Errorf("expected MFA to be required, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if code := serveWithRoles(rbacConfig, []string{"user", "operator"}, nil, true, "admin.stats.read").Code; code != http.StatusOK {
+		t.Errorf("expected the permission to be granted after MFA, got %d", code)
+	}
+}
+
+func TestPermissionMiddleware_DebugDetails(t *testing.T) {
+	defer gin.SetMode(gin.TestMode)
+
+	serve := func(mode string) string {
+		gin.SetMode(mode)
+		router := gin.New()
+		router.GET("/", func(c *gin.Context) {
+			c.Set("userRoles", []string{"user"})
+			c.Next()
+		}, PermissionMiddleware("admin.stats.read", rbac.DefaultRBACConfig()))
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		return recorder.Body.String()
+	}
+
+	// Only debug mode tells clients which permission they lack
+	if body := serve(gin.DebugMode); !strings.Contains(body, "missing permission admin.stats.read") {
+		t.Errorf("expected the missing permission in debug mode, got %s", body)
+	}
+	if body := serve(gin.ReleaseMode); strings.Contains(body, "admin.stats.read") {
+		t.Errorf("expected no details in release mode, got %s", body)
+	}
+}
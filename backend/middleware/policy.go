@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyMiddleware checks that one of the policies grants access: one of the user's roles
+// must have the policy's permission and every condition of the policy must hold for the request.
+// targetRoles resolves the roles of the users that route parameters refer to.
+func PolicyMiddleware(policies []rbac.Policy, rbacConfig *rbac.RBACConfig, targetRoles func(userID string) ([]string, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := principal(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, models.ForbiddenErrorResponse(c.GetString("requestId")))
+			c.Abort()
+			return
+		}
+
+		check, err := rbacConfig.CheckPolicies(p, policies, policyRequest(c, p, targetRoles))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.InternalServerErrorResponse(c.GetString("requestId"), err.Error()))
+			c.Abort()
+			return
+		}
+		if !check.Passed {
+			denyAccess(c, check)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// policyRequest describes the request to policy conditions
+func policyRequest(c *gin.Context, p *rbac.Principal, targetRoles func(userID string) ([]string, error)) *rbac.Request {
+	request := &rbac.Request{
+		SubjectID:    p.ID,
+		SubjectRoles: p.Roles,
+		Params:       make(map[string]string),
+		TargetRoles:  targetRoles,
+	}
+
+	for _, param := range c.Params {
+		request.Params[param.Key] = param.Value
+	}
+
+	return request
+}
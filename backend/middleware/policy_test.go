@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"angular-n-go-template/backend/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestPolicyMiddleware_OwnRecord(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	subjectID := uuid.New()
+	policies := []rbac.Policy{
+		{Permission: "users.write.own", Conditions: []rbac.Condition{rbac.SubjectIsParam("id")}},
+		{Permission: "users.write.any", Conditions: []rbac.Condition{rbac.TargetRoleNotHigher("id")}},
+	}
+	targetRoles := func(string) ([]string, error) { return []string{"admin"}, nil }
+
+	serve := func(role, path string) int {
+		router := gin.New()
+		router.PUT("/users/:id", func(c *gin.Context) {
+			c.Set("userID", subjectID)
+			c.Set("userRoles", []string{role})
+			c.Next()
+		}, PolicyMiddleware(policies, rbac.DefaultRBACConfig(), targetRoles), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, path, nil))
+		return recorder.Code
+	}
+
+	if code := serve("user", "/users/"+subjectID.String()); code != http.StatusOK {
+		t.Errorf("expected a user to update their own record, got %d", code)
+	}
+	if code := serve("user", "/users/"+uuid.NewString()); code != http.StatusForbidden {
+		t.Errorf("expected a user to be denied someone else's record, got %d", code)
+	}
+	if code := serve("admin", "/users/"+uuid.NewString()); code != http.StatusOK {
+		t.Errorf("expected an admin to update another admin, got %d", code)
+	}
+}
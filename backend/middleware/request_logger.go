@@ -35,6 +35,14 @@ func RequestLogger(requestLogService *services.RequestLogService) gin.HandlerFun
 			}
 		}
 
+		// Get the administrator behind an impersonated request
+		var actorID *uuid.UUID
+		if actorIDValue, exists := c.Get("actorID"); exists {
+			if id, ok := actorIDValue.(uuid.UUID); ok {
+				actorID = &id
+			}
+		}
+
 		// Get error message if any
 		var errorMsg *string
 		if len(c.Errors) > 0 {
@@ -48,6 +56,7 @@ func RequestLogger(requestLogService *services.RequestLogService) gin.HandlerFun
 			Method:       c.Request.Method,
 			Path:         c.Request.URL.Path,
 			UserID:       userID,
+			ActorID:      actorID,
 			IPAddress:    c.ClientIP(),
 			UserAgent:    c.Request.UserAgent(),
 			StatusCode:   c.Writer.Status(),
@@ -0,0 +1,5 @@
+-- Track when a user proved ownership of their email address
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Accounts created before email verification existed are considered verified
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
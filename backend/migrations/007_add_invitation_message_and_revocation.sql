@@ -0,0 +1,8 @@
+-- Optional personal message included in the invitation email
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS message TEXT;
+
+-- Track invitations revoked by an administrator before being accepted
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_invitations_created_at ON invitations(created_at);
@@ -0,0 +1,2 @@
+-- Record which authenticator owns each user: local password hashes or the LDAP directory
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auth_source VARCHAR(20) NOT NULL DEFAULT 'local';
@@ -0,0 +1,2 @@
+-- Roles created through the admin API can inherit the permissions of other roles
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS inherits TEXT[] NOT NULL DEFAULT '{}';
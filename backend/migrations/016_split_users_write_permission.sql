@@ -0,0 +1,10 @@
+-- users.write is split into users.write.own and users.write.any: stored roles and API keys
+-- that had it keep the permission to update any user
+INSERT INTO role_permissions (role_name, permission)
+SELECT role_name, 'users.write.any' FROM role_permissions WHERE permission = 'users.write'
+ON CONFLICT DO NOTHING;
+
+DELETE FROM role_permissions WHERE permission = 'users.write';
+
+UPDATE api_keys SET scopes = array_replace(scopes, 'users.write', 'users.write.any')
+WHERE 'users.write' = ANY(scopes);
@@ -0,0 +1,3 @@
+-- Record whether an API key was created by a session that completed two-factor authentication.
+-- Keys created before cannot tell and lose the permissions of roles requiring it.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS mfa_authenticated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey represents a personal access token owned by a user.
+// Only the SHA-256 hash of the token is persisted; the prefix helps users recognise their keys.
+type APIKey struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Prefix    string    `json:"prefix" db:"token_prefix"`
+	TokenHash string    `json:"-" db:"token_hash"`
+	Scopes    []string  `json:"scopes" db:"scopes"`
+	// MFAAuthenticated is set when the key was created by a session that completed two-factor authentication
+	MFAAuthenticated bool       `json:"mfa_authenticated" db:"mfa_authenticated"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsExpired reports whether the key can no longer be used
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && !time.Now().Before(*k.ExpiresAt)
+}
+
+// CreateAPIKeyRequest represents the request payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresInDays limits the lifetime of the key; keys without it never expire
+	ExpiresInDays int `json:"expires_in_days,omitempty" binding:"omitempty,min=1,max=365"`
+}
+
+// CreateAPIKeyResponse contains the new key; the token is only ever shown once
+type CreateAPIKeyResponse struct {
+	APIKey *APIKey `json:"api_key"`
+	Token  string  `json:"token"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImpersonationResponse is returned when an administrator starts acting as another user.
+// The token is a plain access token for the user: it cannot be refreshed and expires at ExpiresAt.
+type ImpersonationResponse struct {
+	Token     string       `json:"token"`
+	ExpiresIn int64        `json:"expires_in"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      UserResponse `json:"user"`
+	ActorID   uuid.UUID    `json:"actor_id"`
+}
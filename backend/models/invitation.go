@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invitation represents an invitation to create an account with a pre-assigned role.
+// Only the SHA-256 hash of the invitation token is persisted.
+type Invitation struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Email      string     `json:"email" db:"email"`
+	Role       string     `json:"role" db:"role"`
+	Message    string     `json:"message,omitempty" db:"message"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	InvitedBy  *uuid.UUID `json:"invited_by,omitempty" db:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	Status     string     `json:"status" db:"-"`
+}
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// CurrentStatus returns the status of the invitation at the current time
+func (i *Invitation) CurrentStatus() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !time.Now().Before(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+// IsPending reports whether the invitation can still be accepted
+func (i *Invitation) IsPending() bool {
+	return i.CurrentStatus() == InvitationPending
+}
+
+// CreateInvitationRequest represents the request payload for inviting someone
+type CreateInvitationRequest struct {
+	Email   string `json:"email" binding:"required,email"`
+	Role    string `json:"role" binding:"required,max=50"`
+	Message string `json:"message,omitempty" binding:"omitempty,max=1000"`
+	// ExpiresInHours overrides the default lifetime of the invitation (INVITATION_EXPIRY)
+	ExpiresInHours int `json:"expires_in_hours,omitempty" binding:"omitempty,min=1,max=2160"`
+}
+
+// AcceptInvitationRequest represents the request payload for creating an account from an invitation
+type AcceptInvitationRequest struct {
+	Token     string `json:"token" binding:"required"`
+	Username  string `json:"username" binding:"required,min=3,max=50"`
+	Password  string `json:"password" binding:"required,min=8"`
+	FirstName string `json:"first_name" binding:"required,min=2,max=50"`
+	LastName  string `json:"last_name" binding:"required,min=2,max=50"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginAttempt represents a recorded login attempt, successful or not
+type LoginAttempt struct {
+	ID        uuid.UUID  `json:"id"`
+	RequestID string     `json:"request_id"`
+	Email     string     `json:"email"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	IPAddress string     `json:"ip_address"`
+	UserAgent string     `json:"user_agent"`
+	Success   bool       `json:"success"`
+	// Reason explains why the attempt failed (invalid_credentials, account_inactive, throttled...)
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ClientInfo describes the client making an authentication request
+type ClientInfo struct {
+	RequestID string
+	IPAddress string
+	UserAgent string
+	// DeviceName is an optional label chosen by the client for the session it starts
+	DeviceName string
+}
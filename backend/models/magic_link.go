@@ -0,0 +1,21 @@
+package models
+
+// MagicLinkRequest represents the request payload for emailing a login link
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkResponse represents the response to a login link request.
+// BrowserToken must be kept by the browser and sent back with the link it receives.
+type MagicLinkResponse struct {
+	Message      string `json:"message"`
+	BrowserToken string `json:"browser_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// ConsumeMagicLinkRequest represents the request payload for logging in with an emailed link
+type ConsumeMagicLinkRequest struct {
+	Token        string `json:"token" binding:"required"`
+	BrowserToken string `json:"browser_token,omitempty"`
+	DeviceName   string `json:"device_name,omitempty" binding:"omitempty,max=100"`
+}
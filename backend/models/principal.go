@@ -0,0 +1,16 @@
+package models
+
+// UserPrincipal is what access checks need to know about a user. It is resolved for each request
+// rather than read from access tokens, so changes to an account apply without waiting for them to expire.
+type UserPrincipal struct {
+	IsActive      bool `json:"is_active"`
+	EmailVerified bool `json:"email_verified"`
+	// Role is the role of the account and Roles the active roles granted on top of it
+	Role  string   `json:"role"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// AllRoles returns the role of the account followed by the roles granted on top of it
+func (p *UserPrincipal) AllRoles() []string {
+	return append([]string{p.Role}, p.Roles...)
+}
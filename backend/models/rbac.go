@@ -0,0 +1,61 @@
+package models
+
+import (
+	"angular-n-go-template/backend/rbac"
+
+	"github.com/google/uuid"
+)
+
+// RoleResponse describes a role; built-in roles come from the RBAC configuration file and cannot be changed through the API
+type RoleResponse struct {
+	*rbac.Role
+	BuiltIn bool `json:"built_in"`
+	// EffectivePermissions lists every permission the role grants, with wildcards expanded and inherited permissions included
+	EffectivePermissions []string `json:"effective_permissions"`
+}
+
+// PermissionResponse describes a permission; built-in permissions come from the RBAC configuration file
+type PermissionResponse struct {
+	*rbac.Permission
+	BuiltIn bool `json:"built_in"`
+}
+
+// CreateRoleRequest represents the request payload for creating a role
+type CreateRoleRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=50"`
+	UpdateRoleRequest
+}
+
+// UpdateRoleRequest represents the request payload for updating a role
+type UpdateRoleRequest struct {
+	Description string   `json:"description" binding:"max=255"`
+	Inherits    []string `json:"inherits"`
+	Permissions []string `json:"permissions"`
+	RequireMFA  bool     `json:"require_mfa"`
+}
+
+// CreatePermissionRequest represents the request payload for creating a permission
+type CreatePermissionRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+	UpdatePermissionRequest
+}
+
+// UpdatePermissionRequest represents the request payload for updating a permission
+type UpdatePermissionRequest struct {
+	Description string `json:"description" binding:"max=255"`
+	Resource    string `json:"resource" binding:"max=100"`
+	Action      string `json:"action" binding:"max=50"`
+}
+
+// ExplainAccessRequest represents the request payload for explaining an access decision,
+// made for either a user or a role
+type ExplainAccessRequest struct {
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+	Role   string     `json:"role,omitempty" binding:"max=50"`
+	Method string     `json:"method" binding:"required,max=10"`
+	Path   string     `json:"path" binding:"required,max=2048"`
+	// MFAAuthenticated explains the decision for a session that completed two-factor authentication
+	MFAAuthenticated bool `json:"mfa_authenticated"`
+	// Scopes explains the decision for an API key with these scopes
+	Scopes []string `json:"scopes,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken represents an opaque refresh token stored in Redis.
+// Only the SHA-256 hash of the token is persisted.
+type RefreshToken struct {
+	TokenHash string    `json:"-" redis:"token_hash"`
+	FamilyID  string    `json:"family_id" redis:"family_id"`
+	UserID    uuid.UUID `json:"user_id" redis:"user_id"`
+	Used      bool      `json:"used" redis:"used"`
+	// AMR carries the authentication methods of the original login to every token of the family
+	AMR       []string  `json:"amr" redis:"amr"`
+	CreatedAt time.Time `json:"created_at" redis:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" redis:"expires_at"`
+}
+
+// RefreshTokenRequest represents the request payload for refreshing an access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
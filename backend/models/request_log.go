@@ -13,6 +13,8 @@ type RequestLog struct {
 	Method      string    `json:"method" redis:"method"`
 	Path        string    `json:"path" redis:"path"`
 	UserID      *uuid.UUID `json:"user_id,omitempty" redis:"user_id"`
+	// ActorID is the administrator who made the request while impersonating UserID
+	ActorID     *uuid.UUID `json:"actor_id,omitempty" redis:"actor_id"`
 	IPAddress   string    `json:"ip_address" redis:"ip_address"`
 	UserAgent   string    `json:"user_agent" redis:"user_agent"`
 	StatusCode  int       `json:"status_code" redis:"status_code"`
@@ -27,6 +29,7 @@ type CreateRequestLogRequest struct {
 	Method      string     `json:"method" binding:"required"`
 	Path        string     `json:"path" binding:"required"`
 	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	ActorID     *uuid.UUID `json:"actor_id,omitempty"`
 	IPAddress   string     `json:"ip_address" binding:"required"`
 	UserAgent   string     `json:"user_agent" binding:"required"`
 	StatusCode  int        `json:"status_code" binding:"required"`
@@ -69,6 +69,12 @@ func ForbiddenErrorResponse(requestID string) APIResponse {
 	return ErrorResponse(requestID, "FORBIDDEN", "Access forbidden", "")
 }
 
+// MFARequiredErrorResponse creates an error API response for roles that require two-factor authentication
+func MFARequiredErrorResponse(requestID string) APIResponse {
+	return ErrorResponse(requestID, "MFA_REQUIRED", "Two-factor authentication required", "")
+}
 
-
-
+// TooManyRequestsErrorResponse creates an error API response for throttled requests
+func TooManyRequestsErrorResponse(requestID string, message string) APIResponse {
+	return ErrorResponse(requestID, "TOO_MANY_REQUESTS", message, "")
+}
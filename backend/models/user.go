@@ -6,28 +6,56 @@ import (
 	"github.com/google/uuid"
 )
 
+// DefaultRole is the role given to self-registered accounts
+const DefaultRole = "user"
+
+// Authentication sources, telling which authenticator verifies the password of a user
+const (
+	// AuthSourceLocal users have an Argon2id password hash stored in the users table
+	AuthSourceLocal = "local"
+	// AuthSourceLDAP users are provisioned from and authenticated against the LDAP directory
+	AuthSourceLDAP = "ldap"
+)
+
 // User represents a user in the system
 type User struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Email     string    `json:"email" db:"email"`
-	Username  string    `json:"username" db:"username"`
-	Password  string    `json:"-" db:"password"` // Hidden from JSON
-	FirstName string    `json:"first_name" db:"first_name"`
-	LastName  string    `json:"last_name" db:"last_name"`
-	Role      string    `json:"role" db:"role"`
-	IsActive  bool      `json:"is_active" db:"is_active"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Email           string     `json:"email" db:"email"`
+	Username        string     `json:"username" db:"username"`
+	Password        string     `json:"-" db:"password"` // Hidden from JSON
+	FirstName       string     `json:"first_name" db:"first_name"`
+	LastName        string     `json:"last_name" db:"last_name"`
+	Role            string     `json:"role" db:"role"`
+	IsActive        bool       `json:"is_active" db:"is_active"`
+	AuthSource      string     `json:"auth_source" db:"auth_source"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsEmailVerified reports whether the user has proven ownership of their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// RegisterRequest represents the request payload for public self-registration.
+// It deliberately has no role: self-registered accounts always get DefaultRole.
+type RegisterRequest struct {
+	Email     string `json:"email" binding:"required,email"`
+	Username  string `json:"username" binding:"required,min=3,max=50"`
+	Password  string `json:"password" binding:"required,min=8"`
+	FirstName string `json:"first_name" binding:"required,min=2,max=50"`
+	LastName  string `json:"last_name" binding:"required,min=2,max=50"`
 }
 
-// CreateUserRequest represents the request payload for creating a user
+// CreateUserRequest represents the request payload for an administrator creating a user
 type CreateUserRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 	Username  string `json:"username" binding:"required,min=3,max=50"`
 	Password  string `json:"password" binding:"required,min=8"`
 	FirstName string `json:"first_name" binding:"required,min=2,max=50"`
 	LastName  string `json:"last_name" binding:"required,min=2,max=50"`
-	Role      string `json:"role,omitempty" binding:"omitempty,oneof=admin user"`
+	Role      string `json:"role,omitempty" binding:"omitempty,max=50"`
 }
 
 // UpdateUserRequest represents the request payload for updating a user
@@ -36,46 +64,76 @@ type UpdateUserRequest struct {
 	Username  *string `json:"username,omitempty" binding:"omitempty,min=3,max=50"`
 	FirstName *string `json:"first_name,omitempty" binding:"omitempty,min=2,max=50"`
 	LastName  *string `json:"last_name,omitempty" binding:"omitempty,min=2,max=50"`
-	Role      *string `json:"role,omitempty" binding:"omitempty,oneof=admin user"`
+	Role      *string `json:"role,omitempty" binding:"omitempty,max=50"`
 	IsActive  *bool   `json:"is_active,omitempty"`
 }
 
+// UpdateProfileRequest represents the request payload for users updating their own profile
+type UpdateProfileRequest struct {
+	Username  *string `json:"username,omitempty" binding:"omitempty,min=3,max=50"`
+	FirstName *string `json:"first_name,omitempty" binding:"omitempty,min=2,max=50"`
+	LastName  *string `json:"last_name,omitempty" binding:"omitempty,min=2,max=50"`
+}
+
+// ChangePasswordRequest represents the request payload for users changing their own password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
 // LoginRequest represents the request payload for user login
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name,omitempty" binding:"omitempty,max=100"`
+}
+
+// ForgotPasswordRequest represents the request payload for starting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the request payload for completing a password reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// VerifyEmailRequest represents the request payload for confirming an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 // UserResponse represents the response payload for user data
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Role      string    `json:"role"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uuid.UUID  `json:"id"`
+	Email           string     `json:"email"`
+	Username        string     `json:"username"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Role            string     `json:"role"`
+	IsActive        bool       `json:"is_active"`
+	AuthSource      string     `json:"auth_source"`
+	EmailVerified   bool       `json:"email_verified"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // ToResponse converts a User model to UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Username:  u.Username,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Role:      u.Role,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:              u.ID,
+		Email:           u.Email,
+		Username:        u.Username,
+		FirstName:       u.FirstName,
+		LastName:        u.LastName,
+		Role:            u.Role,
+		IsActive:        u.IsActive,
+		AuthSource:      u.AuthSource,
+		EmailVerified:   u.IsEmailVerified(),
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
 	}
 }
-
-
-
-
-
-
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserRole is a role granted to a user on top of the role of their account
+type UserRole struct {
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Role      string     `json:"role" db:"role"`
+	GrantedBy *uuid.UUID `json:"granted_by,omitempty" db:"granted_by"`
+	// ExpiresAt ends the grant; grants without it last until they are revoked
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsExpired reports whether the grant no longer gives its role
+func (g *UserRole) IsExpired() bool {
+	return g.ExpiresAt != nil && !time.Now().Before(*g.ExpiresAt)
+}
+
+// GrantRoleRequest represents the request payload for granting a role to a user
+type GrantRoleRequest struct {
+	Role      string     `json:"role" binding:"required,max=50"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// UserRolesResponse lists the role of an account and the roles granted on top of it
+type UserRolesResponse struct {
+	Role   string      `json:"role"`
+	Grants []*UserRole `json:"grants"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"angular-n-go-template/backend/webauthn"
+
+	"github.com/google/uuid"
+)
+
+// WebAuthnCredential is a security key or passkey registered by a user
+type WebAuthnCredential struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	Name   string    `json:"name" db:"name"`
+	// CredentialID is the authenticator's identifier of the credential
+	CredentialID []byte `json:"-" db:"credential_id"`
+	// PublicKey is the COSE_Key checking the credential's signatures
+	PublicKey []byte `json:"-" db:"public_key"`
+	// SignCount is the last signature counter seen; it must increase with each use
+	SignCount  uint32   `json:"-" db:"sign_count"`
+	AAGUID     []byte   `json:"-" db:"aaguid"`
+	Transports []string `json:"transports" db:"transports"`
+	// BackupEligible is set for passkeys synced between devices
+	BackupEligible bool       `json:"backup_eligible" db:"backup_eligible"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Descriptor identifies the credential in the options of a ceremony
+func (c *WebAuthnCredential) Descriptor() webauthn.CredentialDescriptor {
+	return webauthn.CredentialDescriptor{
+		Type:       "public-key",
+		ID:         webauthn.EncodeBase64(c.CredentialID),
+		Transports: c.Transports,
+	}
+}
+
+// Credential returns what the relying party needs to verify an assertion of the credential
+func (c *WebAuthnCredential) Credential() *webauthn.Credential {
+	return &webauthn.Credential{
+		ID:        c.CredentialID,
+		PublicKey: c.PublicKey,
+		SignCount: c.SignCount,
+	}
+}
+
+// WebAuthn ceremonies a challenge can be answered for
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+	WebAuthnMFA          = "mfa"
+)
+
+// WebAuthnChallenge is the server-side state of a ceremony, looked up by its challenge
+type WebAuthnChallenge struct {
+	Ceremony  string `json:"ceremony"`
+	Challenge []byte `json:"challenge"`
+	// UserID is the user registering a credential or completing a second factor
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+	// MFATokenID is the MFA pending token the second factor ceremony was started with
+	MFATokenID string `json:"mfa_token_id,omitempty"`
+}
+
+// WebAuthnRegistrationRequest represents the request payload for registering a security key or passkey
+type WebAuthnRegistrationRequest struct {
+	Name       string                        `json:"name" binding:"required,min=1,max=100"`
+	Credential webauthn.RegistrationResponse `json:"credential" binding:"required"`
+}
+
+// WebAuthnLoginRequest represents the request payload for logging in with a passkey
+type WebAuthnLoginRequest struct {
+	Credential webauthn.AuthenticationResponse `json:"credential" binding:"required"`
+	DeviceName string                          `json:"device_name,omitempty" binding:"omitempty,max=100"`
+}
+
+// WebAuthnMFAStartRequest represents the request payload for starting a security key second factor
+type WebAuthnMFAStartRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// WebAuthnMFALoginRequest represents the second step of a login completed with a security key
+type WebAuthnMFALoginRequest struct {
+	MFAToken   string                          `json:"mfa_token" binding:"required"`
+	Credential webauthn.AuthenticationResponse `json:"credential" binding:"required"`
+	DeviceName string                          `json:"device_name,omitempty" binding:"omitempty,max=100"`
+}
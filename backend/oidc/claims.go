@@ -0,0 +1,46 @@
+package oidc
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenClaims holds the standard claims of an ID token used to find or create a user
+type IDTokenClaims struct {
+	Nonce             string   `json:"nonce"`
+	AuthorizedParty   string   `json:"azp,omitempty"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	GivenName         string   `json:"given_name"`
+	FamilyName        string   `json:"family_name"`
+	PreferredUsername string   `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// flexBool decodes booleans that some providers send as strings ("true")
+type flexBool bool
+
+// UnmarshalJSON accepts both JSON booleans and strings
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*b = flexBool(value)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	value, _ = strconv.ParseBool(text)
+	*b = flexBool(value)
+	return nil
+}
+
+// IsEmailVerified reports whether the provider vouches for the email address
+func (c *IDTokenClaims) IsEmailVerified() bool {
+	return c.Email != "" && bool(c.EmailVerified)
+}
@@ -0,0 +1,148 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"angular-n-go-template/backend/oidc"
+	"angular-n-go-template/backend/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "test-client"
+	testClientSecret = "test-secret"
+	testRedirectURL  = "http://localhost:4200/oidc/callback/mock"
+	testVerifier     = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+var testIdentity = oidctest.Identity{
+	Subject:       "248289761001",
+	Email:         "jane@example.com",
+	EmailVerified: true,
+	GivenName:     "Jane",
+	FamilyName:    "Doe",
+}
+
+func newTestProvider(server *oidctest.Server) *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       server.Issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	}, nil)
+}
+
+func TestProvider_AuthorizationCodeFlowWithPKCE(t *testing.T) {
+	server := oidctest.NewServer(testClientID, testClientSecret)
+	defer server.Close()
+	provider := newTestProvider(server)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-123", "nonce-456", testVerifier)
+	if err != nil {
+		t.Fatalf("failed to build authorization URL: %v", err)
+	}
+
+	query := mustQuery(t, authURL)
+	if query.Get("code_challenge") != oidc.CodeChallenge(testVerifier) || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected an S256 PKCE challenge, got %q", authURL)
+	}
+	if query.Get("redirect_uri") != testRedirectURL || query.Get("scope") != "openid email profile" {
+		t.Fatalf("unexpected redirect URI or scope in %q", authURL)
+	}
+
+	code, state, err := server.Authorize(authURL, testIdentity)
+	if err != nil {
+		t.Fatalf("mock provider refused the authorization request: %v", err)
+	}
+	if state != "state-123" {
+		t.Fatalf("expected state to round-trip, got %q", state)
+	}
+
+	tokens, err := provider.Exchange(ctx, code, testVerifier)
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, "nonce-456")
+	if err != nil {
+		t.Fatalf("failed to verify ID token: %v", err)
+	}
+	if claims.Subject != testIdentity.Subject || claims.Email != testIdentity.Email || !claims.IsEmailVerified() {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// Codes are single use
+	if _, err := provider.Exchange(ctx, code, testVerifier); err == nil {
+		t.Error("expected a replayed code to be refused")
+	}
+}
+
+func TestProvider_ExchangeRequiresCodeVerifier(t *testing.T) {
+	server := oidctest.NewServer(testClientID, testClientSecret)
+	defer server.Close()
+	provider := newTestProvider(server)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", testVerifier)
+	if err != nil {
+		t.Fatalf("failed to build authorization URL: %v", err)
+	}
+	code, _, err := server.Authorize(authURL, testIdentity)
+	if err != nil {
+		t.Fatalf("mock provider refused the authorization request: %v", err)
+	}
+
+	if _, err := provider.Exchange(ctx, code, "another-verifier-that-does-not-match-the-challenge"); err == nil {
+		t.Error("expected an intercepted code to be useless without the verifier")
+	}
+}
+
+func TestProvider_VerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	server := oidctest.NewServer(testClientID, testClientSecret)
+	defer server.Close()
+	provider := newTestProvider(server)
+	ctx := context.Background()
+
+	cases := map[string]func(claims jwt.MapClaims){
+		"wrong nonce":     func(claims jwt.MapClaims) { claims["nonce"] = "other" },
+		"wrong audience":  func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+		"wrong issuer":    func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" },
+		"expired":         func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"missing expiry":  func(claims jwt.MapClaims) { delete(claims, "exp") },
+		"missing subject": func(claims jwt.MapClaims) { delete(claims, "sub") },
+	}
+
+	for name, tamper := range cases {
+		claims := server.IDTokenClaims(testIdentity, "nonce")
+		tamper(claims)
+		if _, err := provider.VerifyIDToken(ctx, server.SignIDToken(claims), "nonce"); err == nil {
+			t.Errorf("%s: expected ID token to be rejected", name)
+		}
+	}
+
+	// Tokens signed with a shared secret instead of the provider key are refused
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, server.IDTokenClaims(testIdentity, "nonce")).SignedString([]byte(testClientSecret))
+	if _, err := provider.VerifyIDToken(ctx, hmacToken, "nonce"); err == nil {
+		t.Error("expected an HS256 ID token to be rejected")
+	}
+
+	// The untampered token is accepted
+	if _, err := provider.VerifyIDToken(ctx, server.SignIDToken(server.IDTokenClaims(testIdentity, "nonce")), "nonce"); err != nil {
+		t.Errorf("expected a valid ID token to be accepted, got %v", err)
+	}
+}
+
+func mustQuery(t *testing.T, rawURL string) url.Values {
+	t.Helper()
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("invalid URL %q: %v", rawURL, err)
+	}
+	return parsed.Query()
+}
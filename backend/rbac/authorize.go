@@ -0,0 +1,135 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Principal is who an access decision is made for
+type Principal struct {
+	ID    string
+	Roles []string
+	// Scopes restricts API keys to these permissions; it is nil for sessions
+	Scopes []string
+	// MFAAuthenticated is set for sessions that completed two-factor authentication,
+	// and for API keys created by such sessions
+	MFAAuthenticated bool
+}
+
+// Check is the outcome of one step of an access decision
+type Check struct {
+	Step     string   `json:"step"`
+	Required []string `json:"required,omitempty"`
+	Passed   bool     `json:"passed"`
+	// GrantedBy is the role that satisfied the check
+	GrantedBy string `json:"granted_by,omitempty"`
+	// MFARequired is set when the check would pass once the session completes two-factor authentication
+	MFARequired bool `json:"mfa_required,omitempty"`
+	// Reason explains why the check failed
+	Reason string `json:"reason,omitempty"`
+}
+
+// Steps of an access decision, in the order they are checked
+const (
+	StepAuthentication   = "authentication"
+	StepGroupPermissions = "group_permissions"
+	StepPermissions      = "permissions"
+	StepPolicies         = "policies"
+)
+
+// CheckPermissions checks that one of the principal's roles grants any of the required permissions
+func (r *RBACConfig) CheckPermissions(p *Principal, required []string) Check {
+	check := Check{Step: StepPermissions, Required: required}
+
+	var reasons []string
+	for _, permission := range required {
+		role, mfaRequired, reason := r.authorize(p, permission)
+		if role != "" {
+			check.Passed, check.GrantedBy, check.MFARequired = true, role, false
+			return check
+		}
+		check.MFARequired = check.MFARequired || mfaRequired
+		reasons = append(reasons, reason)
+	}
+
+	check.Reason = strings.Join(reasons, "; ")
+	return check
+}
+
+// CheckPolicies checks that one of the policies grants access: one of the principal's roles must
+// have its permission and every condition of the policy must hold for the request
+func (r *RBACConfig) CheckPolicies(p *Principal, policies []Policy, req *Request) (Check, error) {
+	check := Check{Step: StepPolicies}
+	for _, policy := range policies {
+		check.Required = append(check.Required, policy.Permission)
+	}
+
+	var reasons []string
+	for _, policy := range policies {
+		role, mfaRequired, reason := r.authorize(p, policy.Permission)
+		if role == "" && !mfaRequired {
+			reasons = append(reasons, reason)
+			continue
+		}
+
+		failed, err := policy.failedCondition(r, req)
+		if err != nil {
+			return check, err
+		}
+		if failed != nil {
+			reasons = append(reasons, fmt.Sprintf("condition %s of %s does not hold", failed.Description, policy.Permission))
+			continue
+		}
+
+		if role != "" {
+			check.Passed, check.GrantedBy, check.MFARequired = true, role, false
+			return check, nil
+		}
+		check.MFARequired = true
+		reasons = append(reasons, reason)
+	}
+
+	check.Reason = strings.Join(reasons, "; ")
+	return check, nil
+}
+
+// authorize returns the first role of the principal granting the permission to it. When none does,
+// mfaRequired tells whether a role would grant it after two-factor authentication and reason says why.
+func (r *RBACConfig) authorize(p *Principal, permission string) (role string, mfaRequired bool, reason string) {
+	effective := r.effectivePermissions()
+
+	for _, roleName := range p.Roles {
+		if !effective[roleName][permission] {
+			continue
+		}
+		if !r.RequiresMFA(roleName) || p.mfaSatisfied() {
+			role = roleName
+			break
+		}
+		mfaRequired = true
+	}
+
+	switch {
+	case role == "" && !mfaRequired:
+		return "", false, "missing permission " + permission
+	case p.Scopes != nil && !contains(p.Scopes, permission):
+		return "", false, "permission " + permission + " is not in the API key scopes"
+	case role == "":
+		return "", true, "permission " + permission + " requires two-factor authentication"
+	}
+	return role, false, ""
+}
+
+// mfaSatisfied reports whether roles requiring two-factor authentication apply to the principal
+func (p *Principal) mfaSatisfied() bool {
+	return p.MFAAuthenticated
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package rbac
+
+import "strings"
+
+// Route holds the access rules of a route of the API
+type Route struct {
+	Method string `json:"method"`
+	// Path is the full path of the route, with its parameters, e.g. /api/v1/users/:id
+	Path   string `json:"path"`
+	Public bool   `json:"public"`
+	// GroupPermissions are required by the route group, on top of the route's own rules
+	GroupPermissions []string `json:"group_permissions,omitempty"`
+	Permissions      []string `json:"permissions,omitempty"`
+	Policies         []Policy `json:"policies,omitempty"`
+}
+
+// RequiresAuthentication reports whether the route is only served to authenticated principals.
+// Public routes of groups with permissions still go through the group's checks.
+func (rt *Route) RequiresAuthentication() bool {
+	return !rt.Public || len(rt.GroupPermissions) > 0
+}
+
+// Match reports whether the route serves a request and returns its route parameters.
+// Segments starting with ':' match any segment, and a segment starting with '*' matches the rest of the path.
+func (rt *Route) Match(method, path string) (map[string]string, bool) {
+	if !strings.EqualFold(rt.Method, method) {
+		return nil, false
+	}
+
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	patternSegments := strings.Split(strings.Trim(rt.Path, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	params := make(map[string]string)
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "*") {
+			params[segment[1:]] = "/" + strings.Join(pathSegments[i:], "/")
+			return params, true
+		}
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:]] = pathSegments[i]
+		case segment != pathSegments[i]:
+			return nil, false
+		}
+	}
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// FindRoute returns the route serving a request and its route parameters. Like the router,
+// it prefers static segments, so /users/me matches before /users/:id.
+func FindRoute(routes []Route, method, path string) (*Route, map[string]string, bool) {
+	var found *Route
+	var foundParams map[string]string
+	for i := range routes {
+		params, ok := routes[i].Match(method, path)
+		if ok && (found == nil || len(params) < len(foundParams)) {
+			found, foundParams = &routes[i], params
+		}
+	}
+	return found, foundParams, found != nil
+}
+
+// Decision explains whether a principal may use a route, step by step
+type Decision struct {
+	Allowed bool              `json:"allowed"`
+	Route   *Route            `json:"route"`
+	Params  map[string]string `json:"params,omitempty"`
+	Roles   []string          `json:"roles"`
+	Checks  []Check           `json:"checks"`
+	// FailedStep is the step of the first check that failed, when access is denied
+	FailedStep string `json:"failed_step,omitempty"`
+}
+
+// deny records the check that denied access
+func (d *Decision) deny(check Check) *Decision {
+	d.Checks = append(d.Checks, check)
+	d.Allowed, d.FailedStep = false, check.Step
+	return d
+}
+
+// Explain decides whether the principal may use the route as the middleware would, and records
+// each check made. The decision stops at the first check that fails. targetRoles resolves the
+// roles of the users that route parameters refer to, for policy conditions.
+func (r *RBACConfig) Explain(route *Route, params map[string]string, p *Principal, targetRoles func(userID string) ([]string, error)) (*Decision, error) {
+	decision := &Decision{Route: route, Params: params, Roles: p.Roles, Checks: []Check{}}
+
+	if !route.RequiresAuthentication() {
+		decision.Allowed = true
+		return decision, nil
+	}
+	decision.Checks = append(decision.Checks, Check{Step: StepAuthentication, Passed: true})
+
+	if len(route.GroupPermissions) > 0 {
+		check := r.CheckPermissions(p, route.GroupPermissions)
+		check.Step = StepGroupPermissions
+		if !check.Passed {
+			return decision.deny(check), nil
+		}
+		decision.Checks = append(decision.Checks, check)
+	}
+
+	if len(route.Permissions) > 0 {
+		check := r.CheckPermissions(p, route.Permissions)
+		if !check.Passed {
+			return decision.deny(check), nil
+		}
+		decision.Checks = append(decision.Checks, check)
+	}
+
+	if len(route.Policies) > 0 {
+		request := &Request{SubjectID: p.ID, SubjectRoles: p.Roles, Params: params, TargetRoles: targetRoles}
+		check, err := r.CheckPolicies(p, route.Policies, request)
+		if err != nil {
+			return nil, err
+		}
+		if !check.Passed {
+			return decision.deny(check), nil
+		}
+		decision.Checks = append(decision.Checks, check)
+	}
+
+	decision.Allowed = true
+	return decision, nil
+}
+
+// DenyAuthentication returns the decision for a principal that cannot authenticate, e.g. a deactivated
+// account, on a route that requires authentication
+func DenyAuthentication(route *Route, params map[string]string, p *Principal, reason string) *Decision {
+	decision := &Decision{Route: route, Params: params, Roles: p.Roles, Checks: []Check{}}
+	return decision.deny(Check{Step: StepAuthentication, Reason: reason})
+}
@@ -0,0 +1,102 @@
+package rbac
+
+import "testing"
+
+func TestFindRoute(t *testing.T) {
+	routes := []Route{
+		{Method: "GET", Path: "/api/v1/users/:id"},
+		{Method: "GET", Path: "/api/v1/users/me"},
+		{Method: "PUT", Path: "/api/v1/users/:id"},
+		{Method: "GET", Path: "/api/v1/files/*path"},
+	}
+
+	cases := []struct {
+		method, path string
+		want         string
+		params       map[string]string
+	}{
+		{"GET", "/api/v1/users/me", "/api/v1/users/me", map[string]string{}},
+		{"get", "/api/v1/users/42?fields=email", "/api/v1/users/:id", map[string]string{"id": "42"}},
+		{"PUT", "/api/v1/users/42/", "/api/v1/users/:id", map[string]string{"id": "42"}},
+		{"GET", "/api/v1/files/a/b.txt", "/api/v1/files/*path", map[string]string{"path": "/a/b.txt"}},
+		{"DELETE", "/api/v1/users/42", "", nil},
+		{"GET", "/api/v1/users", "", nil},
+		{"GET", "/api/v1/users/42/roles", "", nil},
+	}
+
+	for _, tc := range cases {
+		route, params, found := FindRoute(routes, tc.method, tc.path)
+		if tc.want == "" {
+			if found {
+				t.Errorf("%s %s: expected no route, got %s", tc.method, tc.path, route.Path)
+			}
+			continue
+		}
+		if !found || route.Path != tc.want {
+			t.Errorf("%s %s: expected %s, got %v", tc.method, tc.path, tc.want, route)
+			continue
+		}
+		for name, value := range tc.params {
+			if params[name] != value {
+				t.Errorf("%s %s: expected param %s=%q, got %q", tc.method, tc.path, name, value, params[name])
+			}
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	config := DefaultRBACConfig()
+	config.AddRole(&Role{Name: "operator", RequireMFA: true, Permissions: []string{"admin.stats.read"}})
+	resolve := func(string) ([]string, error) { return []string{"admin"}, nil }
+
+	stats := &Route{Method: "GET", Path: "/api/v1/admin/stats", Permissions: []string{"admin.stats.read"}}
+	updateUser := &Route{
+		Method: "PUT",
+		Path:   "/api/v1/users/:id",
+		Policies: []Policy{
+			{Permission: "users.write.own", Conditions: []Condition{SubjectIsParam("id")}},
+			{Permission: "users.write.any", Conditions: []Condition{TargetRoleNotHigher("id")}},
+		},
+	}
+	grouped := &Route{Method: "GET", Path: "/api/v1/admin/health", Public: true, GroupPermissions: []string{"admin.stats.read"}}
+
+	cases := []struct {
+		name       string
+		route      *Route
+		principal  *Principal
+		allowed    bool
+		failedStep string
+		reason     string
+	}{
+		{"granted permission", stats, &Principal{Roles: []string{"admin"}}, true, "", ""},
+		{"missing permission", stats, &Principal{Roles: []string{"user"}}, false, StepPermissions, "missing permission admin.stats.read"},
+		{"missing MFA", stats, &Principal{Roles: []string{"operator"}}, false, StepPermissions, "permission admin.stats.read requires two-factor authentication"},
+		{"API key created without MFA", stats, &Principal{Roles: []string{"operator"}, Scopes: []string{"admin.stats.read"}}, false, StepPermissions, "permission admin.stats.read requires two-factor authentication"},
+		{"API key created with MFA", stats, &Principal{Roles: []string{"operator"}, Scopes: []string{"admin.stats.read"}, MFAAuthenticated: true}, true, "", ""},
+		{"API key scopes", stats, &Principal{Roles: []string{"admin"}, Scopes: []string{"users.read"}}, false, StepPermissions, "permission admin.stats.read is not in the API key scopes"},
+		{"own record", updateUser, &Principal{ID: "42", Roles: []string{"user"}}, true, "", ""},
+		{"someone else's record", updateUser, &Principal{ID: "7", Roles: []string{"user"}}, false, StepPolicies, "condition subject.id == param.id of users.write.own does not hold; missing permission users.write.any"},
+		{"public route of a protected group", grouped, &Principal{Roles: []string{"user"}}, false, StepGroupPermissions, "missing permission admin.stats.read"},
+	}
+
+	for _, tc := range cases {
+		params := map[string]string{"id": "42"}
+		decision, err := config.Explain(tc.route, params, tc.principal, resolve)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if decision.Allowed != tc.allowed || decision.FailedStep != tc.failedStep {
+			t.Errorf("%s: expected allowed=%v failed step %q, got %v %q", tc.name, tc.allowed, tc.failedStep, decision.Allowed, decision.FailedStep)
+			continue
+		}
+		if last := decision.Checks[len(decision.Checks)-1]; !tc.allowed && last.Reason != tc.reason {
+			t.Errorf("%s: expected reason %q, got %q", tc.name, tc.reason, last.Reason)
+		}
+	}
+
+	// Public routes are served without checks
+	decision, err := config.Explain(&Route{Method: "POST", Path: "/api/v1/auth/login", Public: true}, nil, &Principal{}, resolve)
+	if err != nil || !decision.Allowed || len(decision.Checks) != 0 {
+		t.Errorf("expected a public route to be allowed without checks, got %+v, %v", decision, err)
+	}
+}
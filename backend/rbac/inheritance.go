@@ -0,0 +1,122 @@
+package rbac
+
+import "strings"
+
+// Wildcard is the permission pattern matching every permission
+const Wildcard = "*"
+
+// IsWildcard reports whether a permission granted to a role is a pattern rather than a permission name
+func IsWildcard(permission string) bool {
+	return strings.Contains(permission, Wildcard)
+}
+
+// validWildcard reports whether a pattern is "*" or a dot-separated prefix followed by ".*"
+func validWildcard(pattern string) bool {
+	if pattern == Wildcard {
+		return true
+	}
+	prefix, found := strings.CutSuffix(pattern, "."+Wildcard)
+	return found && prefix != "" && !strings.Contains(prefix, Wildcard)
+}
+
+// MatchesWildcard reports whether a permission matches a pattern; "users.*" matches
+// "users.read" and "users.sessions.revoke" but not "users" itself
+func MatchesWildcard(pattern, permission string) bool {
+	if pattern == Wildcard {
+		return true
+	}
+	prefix, found := strings.CutSuffix(pattern, Wildcard)
+	return found && strings.HasPrefix(permission, prefix) && len(permission) > len(prefix)
+}
+
+// compileRoles computes the permission set of every role: its own permissions, with wildcards
+// expanded against the defined permissions, and those of the roles it inherits from.
+// Unknown parents and inheritance cycles, which Validate refuses, are skipped.
+func compileRoles(roles map[string]*Role, permissions map[string]*Permission) map[string]map[string]bool {
+	effective := make(map[string]map[string]bool, len(roles))
+
+	var resolve func(name string, visiting map[string]bool) map[string]bool
+	resolve = func(name string, visiting map[string]bool) map[string]bool {
+		if granted, done := effective[name]; done {
+			return granted
+		}
+		role, exists := roles[name]
+		if !exists || visiting[name] {
+			return nil
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		granted := make(map[string]bool)
+		for _, permission := range role.Permissions {
+			if !IsWildcard(permission) {
+				granted[permission] = true
+				continue
+			}
+			for defined := range permissions {
+				if MatchesWildcard(permission, defined) {
+					granted[defined] = true
+				}
+			}
+		}
+		for _, parent := range role.Inherits {
+			for permission := range resolve(parent, visiting) {
+				granted[permission] = true
+			}
+		}
+
+		effective[name] = granted
+		return granted
+	}
+
+	for name := range roles {
+		resolve(name, make(map[string]bool))
+	}
+	return effective
+}
+
+// inheritanceCycle returns the roles of an inheritance cycle, starting and ending with the same role, or nil
+func inheritanceCycle(roles map[string]*Role) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, role := range path {
+				if role == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		role, exists := roles[name]
+		if !exists {
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, parent := range role.Inherits {
+			if cycle := visit(parent); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range sortedKeys(roles) {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package rbac
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig reads an RBAC configuration from a JSON file, or a YAML file when its extension is
+// .yaml or .yml, and checks that it is consistent. Unknown fields are refused to catch typos.
+func LoadConfig(path string) (*RBACConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &RBACConfig{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(config)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if err := config.Validate(nil); err != nil {
+		return nil, fmt.Errorf("invalid RBAC configuration %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Validate checks that roles and permissions are named after their keys, that every permission
+// granted to a role is defined or a well-formed wildcard, that roles only inherit from defined
+// roles without cycles, and that the required permissions, such as those protecting routes, are
+// defined as well. The permission sets of the roles are computed when the configuration is valid.
+func (r *RBACConfig) Validate(requiredPermissions []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.Roles) == 0 {
+		return errors.New("no roles are defined")
+	}
+	if r.Permissions == nil {
+		r.Permissions = make(map[string]*Permission)
+	}
+
+	var problems []error
+	for _, name := range sortedKeys(r.Permissions) {
+		permission := r.Permissions[name]
+		if permission == nil {
+			permission = &Permission{}
+			r.Permissions[name] = permission
+		}
+		if permission.Name == "" {
+			permission.Name = name
+		}
+		if permission.Name != name {
+			problems = append(problems, fmt.Errorf("permission %q is named %q", name, permission.Name))
+		}
+	}
+
+	for _, name := range sortedKeys(r.Roles) {
+		role := r.Roles[name]
+		if role == nil {
+			role = &Role{}
+			r.Roles[name] = role
+		}
+		if role.Name == "" {
+			role.Name = name
+		}
+		if role.Name != name {
+			problems = append(problems, fmt.Errorf("role %q is named %q", name, role.Name))
+		}
+		for _, permission := range role.Permissions {
+			if IsWildcard(permission) {
+				if !validWildcard(permission) {
+					problems = append(problems, fmt.Errorf("role %q grants invalid wildcard %q, expected \"*\" or a prefix followed by \".*\"", name, permission))
+				}
+				continue
+			}
+			if _, exists := r.Permissions[permission]; !exists {
+				problems = append(problems, fmt.Errorf("role %q grants undefined permission %q", name, permission))
+			}
+		}
+		for _, parent := range role.Inherits {
+			if _, exists := r.Roles[parent]; !exists {
+				problems = append(problems, fmt.Errorf("role %q inherits from undefined role %q", name, parent))
+			}
+		}
+	}
+
+	if cycle := inheritanceCycle(r.Roles); cycle != nil {
+		problems = append(problems, fmt.Errorf("roles inherit from each other in a cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	for _, permission := range requiredPermissions {
+		if _, exists := r.Permissions[permission]; !exists {
+			problems = append(problems, fmt.Errorf("required permission %q is not defined", permission))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.Join(problems...)
+	}
+
+	r.effective = compileRoles(r.Roles, r.Permissions)
+	return nil
+}
+
+// Replace swaps in the roles and permissions of another configuration in one step, so
+// concurrent permission checks see either the previous configuration or the new one
+func (r *RBACConfig) Replace(other *RBACConfig) {
+	effective := other.effectivePermissions()
+	other.mu.RLock()
+	roles, permissions := other.Roles, other.Permissions
+	other.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Roles = roles
+	r.Permissions = permissions
+	r.effective = effective
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
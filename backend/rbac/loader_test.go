@@ -0,0 +1,156 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	overwrite(t, path, content)
+	return path
+}
+
+func overwrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func TestLoadConfig_MatchesDefaultConfig(t *testing.T) {
+	loaded, err := LoadConfig("../config/rbac.json")
+	if err != nil {
+		t.Fatalf("failed to load config/rbac.json: %v", err)
+	}
+
+	defaults := DefaultRBACConfig()
+	if !reflect.DeepEqual(loaded.Roles, defaults.Roles) {
+		t.Error("expected the roles of config/rbac.json to match DefaultRBACConfig")
+	}
+	if !reflect.DeepEqual(loaded.Permissions, defaults.Permissions) {
+		t.Error("expected the permissions of config/rbac.json to match DefaultRBACConfig")
+	}
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	path := writeConfig(t, "rbac.yaml", `
+roles:
+  admin:
+    description: Administrator
+    require_mfa: true
+    permissions: [users.read]
+permissions:
+  users.read:
+    description: Read user information
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load YAML config: %v", err)
+	}
+	if !config.HasPermission("admin", "users.read") || !config.RequiresMFA("admin") {
+		t.Error("expected the admin role to be loaded from YAML")
+	}
+	if role, _ := config.GetRole("admin"); role.Name != "admin" {
+		t.Errorf("expected the role name to default to its key, got %q", role.Name)
+	}
+}
+
+func TestLoadConfig_RefusesInvalidFiles(t *testing.T) {
+	cases := map[string]string{
+		"undefined permission": `{"roles": {"user": {"permissions": ["users.read"]}}, "permissions": {}}`,
+		"mismatched name":      `{"roles": {"user": {"name": "admin"}}}`,
+		"unknown field":        `{"roles": {"user": {"require_mfa": true, "requires_mfa": true}}}`,
+		"no roles":             `{"permissions": {}}`,
+		"syntax error":         `{"roles": `,
+	}
+
+	for name, content := range cases {
+		if _, err := LoadConfig(writeConfig(t, "rbac.json", content)); err == nil {
+			t.Errorf("%s: expected the config to be refused", name)
+		}
+	}
+}
+
+func TestValidate_RequiredPermissions(t *testing.T) {
+	config := DefaultRBACConfig()
+
+	if err := config.Validate([]string{"users.read", "profile.write"}); err != nil {
+		t.Errorf("expected defined permissions to be accepted, got %v", err)
+	}
+
+	err := config.Validate([]string{"users.read", "reports.export"})
+	if err == nil || !strings.Contains(err.Error(), "reports.export") {
+		t.Errorf("expected the undefined permission to be reported, got %v", err)
+	}
+}
+
+func TestReloader_KeepsCurrentConfigWhenInvalid(t *testing.T) {
+	path := writeConfig(t, "rbac.json", `{"roles": {"user": {"permissions": ["profile.read"]}}, "permissions": {"profile.read": {}}}`)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	reloader := NewReloader(path, func(loaded *RBACConfig) error {
+		if err := loaded.Validate([]string{"profile.read"}); err != nil {
+			return err
+		}
+		config.Replace(loaded)
+		return nil
+	})
+
+	// A role granting an undefined permission is refused
+	overwrite(t, path, `{"roles": {"user": {"permissions": ["profile.read", "users.read"]}}, "permissions": {"profile.read": {}}}`)
+	if err := reloader.Reload(); err == nil {
+		t.Error("expected an invalid config to be refused")
+	}
+	if config.HasPermission("user", "users.read") {
+		t.Error("expected the current config to be kept")
+	}
+
+	// So is a config dropping a permission required by a route
+	overwrite(t, path, `{"roles": {"user": {"permissions": []}}, "permissions": {}}`)
+	if err := reloader.Reload(); err == nil {
+		t.Error("expected a config missing a required permission to be refused")
+	}
+	if !config.HasPermission("user", "profile.read") {
+		t.Error("expected the current config to be kept")
+	}
+
+	overwrite(t, path, `{"roles": {"user": {"permissions": ["profile.read", "users.read"]}}, "permissions": {"profile.read": {}, "users.read": {}}}`)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("expected a valid config to be reloaded, got %v", err)
+	}
+	if !config.HasPermission("user", "users.read") {
+		t.Error("expected the reloaded config to be applied")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := DefaultRBACConfig()
+	custom := &RBACConfig{
+		Roles: map[string]*Role{
+			"auditor": {Name: "auditor", Permissions: []string{"admin.logs.read", "reports.read"}},
+			"user":    {Name: "user", Permissions: []string{"users.delete"}},
+		},
+		Permissions: map[string]*Permission{
+			"reports.read": {Name: "reports.read"},
+		},
+	}
+
+	merged := base.Merge(custom)
+	if !merged.HasPermission("auditor", "reports.read") {
+		t.Error("expected custom roles and permissions to be added")
+	}
+	if merged.HasPermission("user", "users.delete") || !merged.HasPermission("user", "profile.write") {
+		t.Error("expected base roles to take precedence over custom ones")
+	}
+	if _, exists := base.GetRole("auditor"); exists {
+		t.Error("expected the base configuration to be left unchanged")
+	}
+}
@@ -0,0 +1,82 @@
+package rbac
+
+import "fmt"
+
+// Request is what policy conditions know about a request
+type Request struct {
+	// SubjectID and SubjectRoles identify the principal making the request
+	SubjectID    string
+	SubjectRoles []string
+	// Params holds the route parameters by name
+	Params map[string]string
+	// TargetRoles resolves the roles of a user from their ID; unknown users have none
+	TargetRoles func(userID string) ([]string, error)
+}
+
+// Condition restricts a policy to the requests it holds for
+type Condition struct {
+	// Description tells what the condition checks, e.g. "subject.id == param.id"
+	Description string `json:"description"`
+	check       func(config *RBACConfig, req *Request) (bool, error)
+}
+
+// Check reports whether the condition holds for a request
+func (c Condition) Check(config *RBACConfig, req *Request) (bool, error) {
+	return c.check(config, req)
+}
+
+// Policy grants access to the subjects one of whose roles has Permission, when every condition holds
+type Policy struct {
+	Permission string      `json:"permission"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// ConditionsHold reports whether every condition of the policy holds for a request.
+// The permission is checked by the caller, which knows about API key scopes and MFA.
+func (p Policy) ConditionsHold(config *RBACConfig, req *Request) (bool, error) {
+	failed, err := p.failedCondition(config, req)
+	return failed == nil && err == nil, err
+}
+
+// failedCondition returns the first condition of the policy that does not hold for a request, if any
+func (p Policy) failedCondition(config *RBACConfig, req *Request) (*Condition, error) {
+	for i, condition := range p.Conditions {
+		holds, err := condition.Check(config, req)
+		if err != nil {
+			return nil, err
+		}
+		if !holds {
+			return &p.Conditions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SubjectIsParam holds when the subject is the user identified by a route parameter
+func SubjectIsParam(param string) Condition {
+	return Condition{
+		Description: fmt.Sprintf("subject.id == param.%s", param),
+		check: func(_ *RBACConfig, req *Request) (bool, error) {
+			return req.SubjectID != "" && req.SubjectID == req.Params[param], nil
+		},
+	}
+}
+
+// TargetRoleNotHigher holds when the user identified by a route parameter has no permission
+// the subject's roles lack, as for CanActOnRoles. Unknown users have nothing to protect.
+func TargetRoleNotHigher(param string) Condition {
+	return Condition{
+		Description: fmt.Sprintf("roles of param.%s <= subject.roles", param),
+		check: func(config *RBACConfig, req *Request) (bool, error) {
+			if req.TargetRoles == nil {
+				return false, fmt.Errorf("no resolver for the roles of param.%s", param)
+			}
+
+			targetRoles, err := req.TargetRoles(req.Params[param])
+			if err != nil {
+				return false, err
+			}
+			return config.CanActOnRoles(req.SubjectRoles, targetRoles), nil
+		},
+	}
+}
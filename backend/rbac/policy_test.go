@@ -0,0 +1,66 @@
+package rbac
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicy_OwnAndAnyConditions(t *testing.T) {
+	config := DefaultRBACConfig()
+	targetRoles := map[string][]string{
+		"user-1":  {"user"},
+		"admin-1": {"admin"},
+	}
+	resolve := func(userID string) ([]string, error) {
+		return targetRoles[userID], nil
+	}
+
+	own := Policy{Permission: "users.write.own", Conditions: []Condition{SubjectIsParam("id")}}
+	anyUser := Policy{Permission: "users.write.any", Conditions: []Condition{TargetRoleNotHigher("id")}}
+
+	cases := []struct {
+		name    string
+		policy  Policy
+		subject string
+		roles   []string
+		target  string
+		want    bool
+	}{
+		{"own record", own, "user-1", []string{"user"}, "user-1", true},
+		{"someone else's record", own, "user-2", []string{"user"}, "user-1", false},
+		{"anonymous subject", own, "", nil, "", false},
+		{"user with fewer permissions", anyUser, "admin-2", []string{"admin"}, "user-1", true},
+		{"user with more permissions", anyUser, "mod-1", []string{"moderator"}, "admin-1", false},
+		{"unknown user", anyUser, "mod-1", []string{"moderator"}, "missing", true},
+	}
+
+	for _, tc := range cases {
+		req := &Request{
+			SubjectID:    tc.subject,
+			SubjectRoles: tc.roles,
+			Params:       map[string]string{"id": tc.target},
+			TargetRoles:  resolve,
+		}
+		holds, err := tc.policy.ConditionsHold(config, req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		if holds != tc.want {
+			t.Errorf("%s: conditions hold = %v, want %v", tc.name, holds, tc.want)
+		}
+	}
+}
+
+func TestTargetRoleNotHigher_ReportsResolverErrors(t *testing.T) {
+	failure := errors.New("database unavailable")
+	req := &Request{
+		SubjectRoles: []string{"admin"},
+		Params:       map[string]string{"id": "user-1"},
+		TargetRoles:  func(string) ([]string, error) { return nil, failure },
+	}
+
+	holds, err := TargetRoleNotHigher("id").Check(DefaultRBACConfig(), req)
+	if holds || !errors.Is(err, failure) {
+		t.Errorf("expected the resolver error to be returned, got %v, %v", holds, err)
+	}
+}
@@ -1,29 +1,41 @@
 package rbac
 
 import (
+	"sort"
 	"sync"
 )
 
 // Role represents a user role with its permissions
 type Role struct {
-	Name        string   `json:"name"`
-	Permissions []string `json:"permissions"`
-	Description string   `json:"description"`
+	Name string `json:"name" yaml:"name"`
+	// Inherits lists the roles whose permissions this role also grants
+	Inherits []string `json:"inherits,omitempty" yaml:"inherits,omitempty"`
+	// Permissions lists permission names, or wildcards such as "admin.*" or "*" granting every matching permission
+	Permissions []string `json:"permissions" yaml:"permissions"`
+	Description string   `json:"description" yaml:"description"`
+	// RequireMFA denies the role's permissions to sessions that did not complete two-factor authentication
+	RequireMFA bool `json:"require_mfa,omitempty" yaml:"require_mfa,omitempty"`
 }
 
 // Permission represents a system permission
 type Permission struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Resource    string `json:"resource"`
-	Action      string `json:"action"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Resource    string `json:"resource" yaml:"resource"`
+	Action      string `json:"action" yaml:"action"`
 }
 
+// UnverifiedRole is the role whose permissions apply to users that have not verified
+// their email address when the "restrict" email verification policy is enabled
+const UnverifiedRole = "unverified"
+
 // RBACConfig holds the RBAC configuration
 type RBACConfig struct {
-	Roles       map[string]*Role       `json:"roles"`
-	Permissions map[string]*Permission `json:"permissions"`
+	Roles       map[string]*Role       `json:"roles" yaml:"roles"`
+	Permissions map[string]*Permission `json:"permissions" yaml:"permissions"`
 	mu          sync.RWMutex
+	// effective holds the permission set of each role, computed after each change
+	effective map[string]map[string]bool
 }
 
 // DefaultRBACConfig returns the default RBAC configuration
@@ -41,11 +53,17 @@ func DefaultRBACConfig() *RBACConfig {
 			Resource:    "users",
 			Action:      "read",
 		},
-		"users.write": {
-			Name:        "users.write",
-			Description: "Create and update users",
+		"users.write.own": {
+			Name:        "users.write.own",
+			Description: "Update own user record",
 			Resource:    "users",
-			Action:      "write",
+			Action:      "write.own",
+		},
+		"users.write.any": {
+			Name:        "users.write.any",
+			Description: "Update any user with the same or fewer permissions",
+			Resource:    "users",
+			Action:      "write.any",
 		},
 		"users.delete": {
 			Name:        "users.delete",
@@ -71,6 +89,18 @@ func DefaultRBACConfig() *RBACConfig {
 			Resource:    "admin.users",
 			Action:      "manage",
 		},
+		"admin.users.impersonate": {
+			Name:        "admin.users.impersonate",
+			Description: "Act as another user with the same or fewer permissions",
+			Resource:    "admin.users",
+			Action:      "impersonate",
+		},
+		"admin.rbac.manage": {
+			Name:        "admin.rbac.manage",
+			Description: "Manage roles and permissions",
+			Resource:    "admin.rbac",
+			Action:      "manage",
+		},
 		"profile.read": {
 			Name:        "profile.read",
 			Description: "Read own profile",
@@ -93,63 +123,133 @@ func DefaultRBACConfig() *RBACConfig {
 			Permissions: []string{
 				"profile.read",
 				"profile.write",
+				"users.write.own",
 			},
 		},
 		"admin": {
 			Name:        "admin",
 			Description: "Administrator with full system access",
+			Inherits:    []string{"moderator"},
 			Permissions: []string{
-				"profile.read",
-				"profile.write",
-				"users.read",
-				"users.write",
+				"users.write.any",
 				"users.delete",
-				"admin.logs.read",
-				"admin.stats.read",
-				"admin.users.manage",
+				"admin.*",
 			},
 		},
 		"moderator": {
 			Name:        "moderator",
 			Description: "Moderator with limited admin permissions",
+			Inherits:    []string{"user"},
 			Permissions: []string{
-				"profile.read",
-				"profile.write",
 				"users.read",
 				"admin.logs.read",
 			},
 		},
+		UnverifiedRole: {
+			Name:        UnverifiedRole,
+			Description: "Permissions left to users until they verify their email address",
+			Permissions: []string{
+				"profile.read",
+			},
+		},
 	}
 
 	// Set permissions and roles
 	config.mu.Lock()
 	defer config.mu.Unlock()
-	
+
 	config.Permissions = permissions
 	config.Roles = roles
 
 	return config
 }
 
-// HasPermission checks if a role has a specific permission
+// HasPermission checks if a role has a specific permission, directly, through a wildcard or through an inherited role
 func (r *RBACConfig) HasPermission(roleName, permission string) bool {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	return r.effectivePermissions()[roleName][permission]
+}
 
-	role, exists := r.Roles[roleName]
-	if !exists {
-		return false
+// EffectivePermissions lists every permission a role grants, sorted by name
+func (r *RBACConfig) EffectivePermissions(roleName string) []string {
+	permissions := []string{}
+	for permission := range r.effectivePermissions()[roleName] {
+		permissions = append(permissions, permission)
 	}
+	sort.Strings(permissions)
+	return permissions
+}
 
-	for _, perm := range role.Permissions {
-		if perm == permission {
-			return true
+// CanActOnRole reports whether a user of actorRole may act as a user of targetRole, which
+// requires every permission of the target role to be granted to the actor role as well
+func (r *RBACConfig) CanActOnRole(actorRole, targetRole string) bool {
+	return r.CanActOnRoles([]string{actorRole}, []string{targetRole})
+}
+
+// CanActOnRoles is CanActOnRole for users holding several roles: every permission of the
+// target roles must be granted by at least one of the actor roles. Unknown roles are refused.
+func (r *RBACConfig) CanActOnRoles(actorRoles, targetRoles []string) bool {
+	effective := r.effectivePermissions()
+
+	for _, role := range append(append([]string{}, actorRoles...), targetRoles...) {
+		if _, exists := effective[role]; !exists {
+			return false
+		}
+	}
+
+	for _, targetRole := range targetRoles {
+		for permission := range effective[targetRole] {
+			if !anyRoleGrants(effective, actorRoles, permission) {
+				return false
+			}
 		}
 	}
 
+	return true
+}
+
+// HasAnyRolePermission checks if at least one of the roles has a specific permission
+func (r *RBACConfig) HasAnyRolePermission(roleNames []string, permission string) bool {
+	return anyRoleGrants(r.effectivePermissions(), roleNames, permission)
+}
+
+// anyRoleGrants checks the permission against the effective permission sets of the roles
+func anyRoleGrants(effective map[string]map[string]bool, roleNames []string, permission string) bool {
+	for _, roleName := range roleNames {
+		if effective[roleName][permission] {
+			return true
+		}
+	}
 	return false
 }
 
+// effectivePermissions returns the permission set of every role, computing it after a change.
+// The sets are never modified once computed, so they can be read without holding the lock.
+func (r *RBACConfig) effectivePermissions() map[string]map[string]bool {
+	r.mu.RLock()
+	effective := r.effective
+	r.mu.RUnlock()
+	if effective != nil {
+		return effective
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.effective == nil {
+		r.effective = compileRoles(r.Roles, r.Permissions)
+	}
+	return r.effective
+}
+
+// RequiresMFA checks if a role only grants its permissions to sessions authenticated with two factors
+func (r *RBACConfig) RequiresMFA(roleName string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	role, exists := r.Roles[roleName]
+	return exists && role.RequireMFA
+}
+
 // GetRole returns a role by name
 func (r *RBACConfig) GetRole(roleName string) (*Role, bool) {
 	r.mu.RLock()
@@ -178,6 +278,7 @@ func (r *RBACConfig) AddRole(role *Role) {
 	defer r.mu.Unlock()
 
 	r.Roles[role.Name] = role
+	r.effective = nil
 }
 
 // RemoveRole removes a role from the configuration
@@ -186,6 +287,7 @@ func (r *RBACConfig) RemoveRole(roleName string) {
 	defer r.mu.Unlock()
 
 	delete(r.Roles, roleName)
+	r.effective = nil
 }
 
 // AddPermission adds a new permission to the configuration
@@ -194,6 +296,7 @@ func (r *RBACConfig) AddPermission(permission *Permission) {
 	defer r.mu.Unlock()
 
 	r.Permissions[permission.Name] = permission
+	r.effective = nil
 }
 
 // GetPermission returns a permission by name
@@ -204,3 +307,36 @@ func (r *RBACConfig) GetPermission(permissionName string) (*Permission, bool) {
 	permission, exists := r.Permissions[permissionName]
 	return permission, exists
 }
+
+// GetAllPermissions returns all defined permissions
+func (r *RBACConfig) GetAllPermissions() map[string]*Permission {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Return a copy to prevent external modifications
+	permissions := make(map[string]*Permission)
+	for name, permission := range r.Permissions {
+		permissions[name] = permission
+	}
+	return permissions
+}
+
+// Merge returns a new configuration with the roles and permissions of both configurations.
+// Those of r take precedence over roles and permissions of the same name in custom.
+func (r *RBACConfig) Merge(custom *RBACConfig) *RBACConfig {
+	merged := &RBACConfig{
+		Roles:       make(map[string]*Role),
+		Permissions: make(map[string]*Permission),
+	}
+
+	for _, source := range []*RBACConfig{custom, r} {
+		for _, permission := range source.GetAllPermissions() {
+			merged.AddPermission(permission)
+		}
+		for _, role := range source.GetAllRoles() {
+			merged.AddRole(role)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,149 @@
+package rbac
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCanActOnRole(t *testing.T) {
+	config := DefaultRBACConfig()
+
+	cases := []struct {
+		actor, target string
+		want          bool
+	}{
+		{"admin", "user", true},
+		{"admin", "moderator", true},
+		{"admin", "admin", true},
+		{"moderator", "user", true},
+		{"moderator", "admin", false},
+		{"user", "moderator", false},
+		{"admin", "missing", false},
+		{"missing", "user", false},
+	}
+
+	for _, tc := range cases {
+		if got := config.CanActOnRole(tc.actor, tc.target); got != tc.want {
+			t.Errorf("CanActOnRole(%q, %q) = %v, want %v", tc.actor, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestCanActOnRoles(t *testing.T) {
+	config := DefaultRBACConfig()
+	config.AddRole(&Role{Name: "auditor", Permissions: []string{"admin.logs.read", "admin.stats.read"}})
+
+	cases := []struct {
+		actor, target []string
+		want          bool
+	}{
+		// Permissions may come from different roles of the actor
+		{[]string{"moderator", "auditor"}, []string{"auditor"}, true},
+		{[]string{"moderator"}, []string{"auditor"}, false},
+		{[]string{"user", "auditor"}, []string{"moderator"}, false},
+		// Every role of the target counts
+		{[]string{"moderator"}, []string{"user", "auditor"}, false},
+		{[]string{"admin"}, []string{"moderator", "auditor"}, true},
+		{[]string{"admin", "missing"}, []string{"user"}, false},
+	}
+
+	for _, tc := range cases {
+		if got := config.CanActOnRoles(tc.actor, tc.target); got != tc.want {
+			t.Errorf("CanActOnRoles(%v, %v) = %v, want %v", tc.actor, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestEffectivePermissions_DefaultRoles(t *testing.T) {
+	config := DefaultRBACConfig()
+
+	admin := config.EffectivePermissions("admin")
+	if len(admin) != len(config.Permissions) {
+		t.Errorf("expected admin to be granted every permission, got %v", admin)
+	}
+
+	want := []string{"admin.logs.read", "profile.read", "profile.write", "users.read", "users.write.own"}
+	if got := config.EffectivePermissions("moderator"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected moderator permissions %v, got %v", want, got)
+	}
+
+	if got := config.EffectivePermissions("missing"); len(got) != 0 {
+		t.Errorf("expected no permissions for an unknown role, got %v", got)
+	}
+}
+
+func TestHasPermission_InheritanceAndWildcards(t *testing.T) {
+	config := &RBACConfig{
+		Roles: map[string]*Role{
+			"viewer":  {Name: "viewer", Permissions: []string{"reports.read"}},
+			"editor":  {Name: "editor", Inherits: []string{"viewer"}, Permissions: []string{"users.*"}},
+			"support": {Name: "support", Inherits: []string{"editor"}},
+			"root":    {Name: "root", Permissions: []string{"*"}},
+		},
+		Permissions: map[string]*Permission{
+			"reports.read":          {Name: "reports.read"},
+			"users.read":            {Name: "users.read"},
+			"users.sessions.revoke": {Name: "users.sessions.revoke"},
+			"usersettings.read":     {Name: "usersettings.read"},
+		},
+	}
+	if err := config.Validate(nil); err != nil {
+		t.Fatalf("expected the config to be valid, got %v", err)
+	}
+
+	cases := []struct {
+		role, permission string
+		want             bool
+	}{
+		{"editor", "reports.read", true},
+		{"editor", "users.read", true},
+		{"editor", "users.sessions.revoke", true},
+		{"editor", "usersettings.read", false},
+		{"support", "reports.read", true},
+		{"support", "users.read", true},
+		{"viewer", "users.read", false},
+		{"root", "usersettings.read", true},
+		{"editor", "users.*", false},
+	}
+	for _, tc := range cases {
+		if got := config.HasPermission(tc.role, tc.permission); got != tc.want {
+			t.Errorf("HasPermission(%q, %q) = %v, want %v", tc.role, tc.permission, got, tc.want)
+		}
+	}
+
+	// Checks follow changes made to the configuration
+	config.AddPermission(&Permission{Name: "users.delete"})
+	if !config.HasPermission("support", "users.delete") {
+		t.Error("expected a new permission to match existing wildcards")
+	}
+}
+
+func TestValidate_RefusesInheritanceCycles(t *testing.T) {
+	config := &RBACConfig{
+		Roles: map[string]*Role{
+			"a": {Name: "a", Inherits: []string{"b"}},
+			"b": {Name: "b", Inherits: []string{"c"}},
+			"c": {Name: "c", Inherits: []string{"a"}},
+		},
+	}
+
+	err := config.Validate(nil)
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Errorf("expected the cycle to be reported, got %v", err)
+	}
+}
+
+func TestValidate_RefusesInvalidWildcardsAndParents(t *testing.T) {
+	for _, role := range []*Role{
+		{Name: "r", Permissions: []string{"users*"}},
+		{Name: "r", Permissions: []string{"*.read"}},
+		{Name: "r", Permissions: []string{".*"}},
+		{Name: "r", Inherits: []string{"missing"}},
+	} {
+		config := &RBACConfig{Roles: map[string]*Role{"r": role}}
+		if err := config.Validate(nil); err == nil {
+			t.Errorf("expected role %+v to be refused", role)
+		}
+	}
+}
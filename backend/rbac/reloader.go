@@ -0,0 +1,101 @@
+package rbac
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Reloader keeps an RBAC configuration in sync with the file it was loaded from. A reloaded
+// file is only applied once it is valid; otherwise the previous configuration stays.
+type Reloader struct {
+	path string
+	// apply puts a reloaded configuration in use, or refuses it with an error
+	apply func(*RBACConfig) error
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader creates a reloader for a configuration loaded from path
+func NewReloader(path string, apply func(*RBACConfig) error) *Reloader {
+	reloader := &Reloader{
+		path:  path,
+		apply: apply,
+	}
+	reloader.changed()
+	return reloader
+}
+
+// Reload reads the file again and applies the configuration if the file is valid
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, err := LoadConfig(r.path)
+	if err != nil {
+		return err
+	}
+	return r.apply(loaded)
+}
+
+// Watch reloads the configuration when the process receives SIGHUP and, when interval is
+// positive, when the file's modification time or size changes. It returns when ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	var ticks <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.changed()
+			r.reload("SIGHUP")
+		case <-ticks:
+			if r.changed() {
+				r.reload("file change")
+			}
+		}
+	}
+}
+
+// reload reloads the configuration and logs the outcome
+func (r *Reloader) reload(trigger string) {
+	if err := r.Reload(); err != nil {
+		log.Printf("Failed to reload RBAC configuration from %s, keeping the current one: %v", r.path, err)
+		return
+	}
+	log.Printf("Reloaded RBAC configuration from %s after %s", r.path, trigger)
+}
+
+// changed records the file's modification time and size, reporting whether they changed since the last call
+func (r *Reloader) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false
+	}
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+	return true
+}
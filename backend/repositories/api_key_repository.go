@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// apiKeyColumns lists the columns selected for an API key, in the order expected by scanAPIKey
+const apiKeyColumns = `id, user_id, name, token_prefix, token_hash, scopes, mfa_authenticated, expires_at, last_used_at, created_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns into an APIKey
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := row.Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.TokenHash,
+		pq.Array(&key.Scopes), &key.MFAAuthenticated, &key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt,
+	)
+	return key, err
+}
+
+// APIKeyRepository handles API key data operations
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create creates a new API key
+func (r *APIKeyRepository) Create(key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, name, token_prefix, token_hash, scopes, mfa_authenticated, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(query, key.ID, key.UserID, key.Name, key.Prefix, key.TokenHash,
+		pq.Array(key.Scopes), key.MFAAuthenticated, key.ExpiresAt, key.CreatedAt)
+
+	return err
+}
+
+// GetByTokenHash retrieves an API key by the hash of its token
+func (r *APIKeyRepository) GetByTokenHash(tokenHash string) (*models.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys WHERE token_hash = $1
+	`
+
+	key, err := scanAPIKey(r.db.QueryRow(query, tokenHash))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key not found")
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GetByUserID retrieves every API key of a user, newest first
+func (r *APIKeyRepository) GetByUserID(userID uuid.UUID) ([]*models.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Delete deletes an API key belonging to a user, returning false if there was none
+func (r *APIKeyRepository) Delete(id, userID uuid.UUID) (bool, error) {
+	query := `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// TouchLastUsed records the use of an API key, unless a use within interval was already recorded
+// by a concurrent request
+func (r *APIKeyRepository) TouchLastUsed(id uuid.UUID, usedAt time.Time, interval time.Duration) error {
+	query := `
+		UPDATE api_keys SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+	`
+
+	_, err := r.db.Exec(query, id, usedAt, usedAt.Add(-interval))
+	return err
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/google/uuid"
+)
+
+// invitationColumns lists the columns selected for an invitation, in the order expected by scanInvitation
+const invitationColumns = `id, email, role, COALESCE(message, ''), token_hash, invited_by, expires_at, accepted_at, revoked_at, created_at`
+
+// scanInvitation scans a row selected with invitationColumns into an Invitation
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	err := row.Scan(
+		&invitation.ID, &invitation.Email, &invitation.Role, &invitation.Message, &invitation.TokenHash,
+		&invitation.InvitedBy, &invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.RevokedAt, &invitation.CreatedAt,
+	)
+	invitation.Status = invitation.CurrentStatus()
+	return invitation, err
+}
+
+// InvitationRepository handles invitation data operations
+type InvitationRepository struct {
+	db *sql.DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *sql.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// Create creates a new invitation
+func (r *InvitationRepository) Create(invitation *models.Invitation) error {
+	query := `
+		INSERT INTO invitations (id, email, role, message, token_hash, invited_by, expires_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(query, invitation.ID, invitation.Email, invitation.Role, invitation.Message, invitation.TokenHash,
+		invitation.InvitedBy, invitation.ExpiresAt, invitation.CreatedAt)
+
+	return err
+}
+
+// GetByID retrieves an invitation by ID
+func (r *InvitationRepository) GetByID(id uuid.UUID) (*models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations WHERE id = $1
+	`
+
+	invitation, err := scanInvitation(r.db.QueryRow(query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invitation not found")
+		}
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// GetAll retrieves invitations with pagination, newest first.
+// When pendingOnly is set, accepted, revoked and expired invitations are left out.
+func (r *InvitationRepository) GetAll(pendingOnly bool, limit, offset int) ([]*models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations
+		WHERE NOT $1 OR (accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW())
+		ORDER BY created_at DESC LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(query, pendingOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*models.Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, nil
+}
+
+// GetByTokenHash retrieves an invitation by the hash of its token
+func (r *InvitationRepository) GetByTokenHash(tokenHash string) (*models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations WHERE token_hash = $1
+	`
+
+	invitation, err := scanInvitation(r.db.QueryRow(query, tokenHash))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invitation not found")
+		}
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// Accept marks an invitation as used and creates the account of the invitee in one transaction,
+// so an account that cannot be created leaves the invitation usable.
+// It returns false when the invitation had already been accepted or was revoked.
+func (r *InvitationRepository) Accept(id uuid.UUID, user *models.User, acceptedAt time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE invitations SET accepted_at = $2 WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+	result, err := tx.Exec(query, id, acceptedAt)
+	if err != nil {
+		return false, err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return false, err
+	}
+
+	if err := insertUser(tx, user); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Revoke cancels an invitation that has not been accepted or revoked yet.
+// It returns false when the invitation could no longer be revoked.
+func (r *InvitationRepository) Revoke(id uuid.UUID, revokedAt time.Time) (bool, error) {
+	query := `UPDATE invitations SET revoked_at = $2 WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(query, id, revokedAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Number of login attempts kept in the global log and in each account's log
+const (
+	maxLoginAttempts        = 10000
+	maxAccountLoginAttempts = 1000
+)
+
+// LoginAttemptRepository handles login failure counters, temporary blocks and the login attempt log using Redis.
+// Counters and blocks are kept per scope ("account" or "ip") and key (email or IP address).
+type LoginAttemptRepository struct {
+	client *redis.Client
+}
+
+// NewLoginAttemptRepository creates a new login attempt repository
+func NewLoginAttemptRepository(client *redis.Client) *LoginAttemptRepository {
+	return &LoginAttemptRepository{client: client}
+}
+
+func loginFailuresKey(scope, key string) string {
+	return fmt.Sprintf("login_failures:%s:%s", scope, key)
+}
+
+func loginBlockKey(scope, key string) string {
+	return fmt.Sprintf("login_block:%s:%s", scope, key)
+}
+
+func accountLoginAttemptsKey(email string) string {
+	return fmt.Sprintf("login_attempts:account:%s", email)
+}
+
+// IncrementFailures counts a failed login and returns the number of failures within the window
+func (r *LoginAttemptRepository) IncrementFailures(ctx context.Context, scope, key string, window time.Duration) (int64, error) {
+	pipe := r.client.TxPipeline()
+	failures := pipe.Incr(ctx, loginFailuresKey(scope, key))
+	pipe.Expire(ctx, loginFailuresKey(scope, key), window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return failures.Val(), nil
+}
+
+// Block refuses logins for the scope and key for the given duration
+func (r *LoginAttemptRepository) Block(ctx context.Context, scope, key string, duration time.Duration) error {
+	return r.client.Set(ctx, loginBlockKey(scope, key), 1, duration).Err()
+}
+
+// BlockedFor returns how long logins stay refused for the scope and key, or zero if they are allowed
+func (r *LoginAttemptRepository) BlockedFor(ctx context.Context, scope, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, loginBlockKey(scope, key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Reset clears the failure counter and any block for the scope and key
+func (r *LoginAttemptRepository) Reset(ctx context.Context, scope, key string) error {
+	return r.client.Del(ctx, loginFailuresKey(scope, key), loginBlockKey(scope, key)).Err()
+}
+
+// Create appends a login attempt to the global log and, when it targets an existing account, to the log
+// of that account. Attempts against unknown emails stay out of account logs so that submitting made-up
+// addresses cannot create logs without bound.
+func (r *LoginAttemptRepository) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.LPush(ctx, "login_attempts", data)
+	pipe.LTrim(ctx, "login_attempts", 0, maxLoginAttempts-1)
+	if attempt.UserID != nil {
+		pipe.LPush(ctx, accountLoginAttemptsKey(attempt.Email), data)
+		pipe.LTrim(ctx, accountLoginAttemptsKey(attempt.Email), 0, maxAccountLoginAttempts-1)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetRecent retrieves the most recent login attempts, optionally only those targeting one email
+func (r *LoginAttemptRepository) GetRecent(ctx context.Context, email string, limit int) ([]*models.LoginAttempt, error) {
+	key := "login_attempts"
+	if email != "" {
+		key = accountLoginAttemptsKey(email)
+	}
+
+	entries, err := r.client.LRange(ctx, key, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]*models.LoginAttempt, 0, len(entries))
+	for _, entry := range entries {
+		attempt := &models.LoginAttempt{}
+		if err := json.Unmarshal([]byte(entry), attempt); err != nil {
+			continue
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
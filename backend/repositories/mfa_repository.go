@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrMFANotEnrolled is returned when a user has no TOTP enrollment
+var ErrMFANotEnrolled = errors.New("mfa enrollment not found")
+
+// MFARepository handles TOTP enrollments and recovery codes
+type MFARepository struct {
+	db *sql.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *sql.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// GetByUserID retrieves the TOTP enrollment of a user
+func (r *MFARepository) GetByUserID(userID uuid.UUID) (*models.UserMFA, error) {
+	query := `
+		SELECT user_id, secret, enabled_at, last_used_step, created_at
+		FROM user_mfa WHERE user_id = $1
+	`
+
+	mfa := &models.UserMFA{}
+	err := r.db.QueryRow(query, userID).Scan(
+		&mfa.UserID, &mfa.Secret, &mfa.EnabledAt, &mfa.LastUsedStep, &mfa.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+
+	return mfa, nil
+}
+
+// Save creates or replaces a pending (not yet enabled) enrollment for a user
+func (r *MFARepository) Save(mfa *models.UserMFA) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret, enabled_at, last_used_step, created_at)
+		VALUES ($1, $2, NULL, 0, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, enabled_at = NULL, last_used_step = 0, created_at = EXCLUDED.created_at
+	`
+
+	_, err := r.db.Exec(query, mfa.UserID, mfa.Secret, mfa.CreatedAt)
+	return err
+}
+
+// Enable marks an enrollment as confirmed
+func (r *MFARepository) Enable(userID uuid.UUID, enabledAt time.Time) error {
+	query := `UPDATE user_mfa SET enabled_at = $2 WHERE user_id = $1`
+	_, err := r.db.Exec(query, userID, enabledAt)
+	return err
+}
+
+// Delete removes the enrollment and recovery codes of a user
+func (r *MFARepository) Delete(userID uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records the time step of an accepted TOTP code.
+// It returns false when a code from the same or a later step was already accepted, which prevents replays.
+func (r *MFARepository) UseStep(userID uuid.UUID, step int64) (bool, error) {
+	query := `UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ReplaceRecoveryCodes discards the recovery codes of a user and stores new code hashes
+func (r *MFARepository) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		query := `INSERT INTO mfa_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(query, uuid.New(), userID, codeHash, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode consumes an unused recovery code, returning false if none matches
+func (r *MFARepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes SET used_at = $3
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, codeHash, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes a user has left
+func (r *MFARepository) CountUnusedRecoveryCodes(userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
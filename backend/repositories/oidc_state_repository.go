@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// OIDCStateRepository handles the state of pending OpenID Connect authorization requests using Redis.
+// States are stored by hash and can only be consumed once.
+type OIDCStateRepository struct {
+	client *redis.Client
+}
+
+// NewOIDCStateRepository creates a new OIDC state repository
+func NewOIDCStateRepository(client *redis.Client) *OIDCStateRepository {
+	return &OIDCStateRepository{client: client}
+}
+
+func oidcStateKey(stateHash string) string {
+	return fmt.Sprintf("oidc_state:%s", stateHash)
+}
+
+// Create stores the state of an authorization request until it expires
+func (r *OIDCStateRepository) Create(ctx context.Context, stateHash string, state *models.OIDCState, ttl time.Duration) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, oidcStateKey(stateHash), data, ttl).Err()
+}
+
+// Consume atomically deletes and returns the state of an authorization request
+func (r *OIDCStateRepository) Consume(ctx context.Context, stateHash string) (*models.OIDCState, error) {
+	data, err := r.client.GetDel(ctx, oidcStateKey(stateHash)).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("oidc state not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &models.OIDCState{}
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// OneTimeTokenRepository handles single-use, expiring tokens (password reset, email verification...) using Redis.
+// Tokens are stored by hash and scoped by purpose so a token issued for one flow cannot be used in another.
+type OneTimeTokenRepository struct {
+	client *redis.Client
+}
+
+// NewOneTimeTokenRepository creates a new one-time token repository
+func NewOneTimeTokenRepository(client *redis.Client) *OneTimeTokenRepository {
+	return &OneTimeTokenRepository{client: client}
+}
+
+func oneTimeTokenKey(purpose, tokenHash string) string {
+	return fmt.Sprintf("one_time_token:%s:%s", purpose, tokenHash)
+}
+
+func userOneTimeTokenKey(purpose string, userID uuid.UUID) string {
+	return fmt.Sprintf("user_one_time_token:%s:%s", purpose, userID.String())
+}
+
+// oneTimeTokenValue joins the user a token was issued to and its subject
+func oneTimeTokenValue(userID uuid.UUID, subject string) string {
+	if subject == "" {
+		return userID.String()
+	}
+	return userID.String() + ":" + subject
+}
+
+// parseOneTimeTokenValue splits a stored token into the user it was issued to and its subject
+func parseOneTimeTokenValue(value string) (uuid.UUID, string, error) {
+	id, subject, _ := strings.Cut(value, ":")
+	userID, err := uuid.Parse(id)
+	return userID, subject, err
+}
+
+// releaseUserTokenScript deletes the token index of a user only while it still points to the given token
+var releaseUserTokenScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Create stores a token for a user, invalidating any token previously issued to that user for the same purpose.
+// subject binds the token to a value the caller checks when the token is used, e.g. the hash of an email address; it may be empty.
+func (r *OneTimeTokenRepository) Create(ctx context.Context, purpose, tokenHash string, userID uuid.UUID, subject string, ttl time.Duration) error {
+	previousHash, err := r.client.Get(ctx, userOneTimeTokenKey(purpose, userID)).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	if previousHash != "" {
+		pipe.Del(ctx, oneTimeTokenKey(purpose, previousHash))
+	}
+	pipe.Set(ctx, oneTimeTokenKey(purpose, tokenHash), oneTimeTokenValue(userID, subject), ttl)
+	pipe.Set(ctx, userOneTimeTokenKey(purpose, userID), tokenHash, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Peek returns the user a token was issued to and its subject without consuming it
+func (r *OneTimeTokenRepository) Peek(ctx context.Context, purpose, tokenHash string) (uuid.UUID, string, error) {
+	value, err := r.client.Get(ctx, oneTimeTokenKey(purpose, tokenHash)).Result()
+	if err == redis.Nil {
+		return uuid.Nil, "", fmt.Errorf("token not found")
+	}
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	return parseOneTimeTokenValue(value)
+}
+
+// Consume atomically deletes a token and returns the user it was issued to and its subject
+func (r *OneTimeTokenRepository) Consume(ctx context.Context, purpose, tokenHash string) (uuid.UUID, string, error) {
+	value, err := r.client.GetDel(ctx, oneTimeTokenKey(purpose, tokenHash)).Result()
+	if err == redis.Nil {
+		return uuid.Nil, "", fmt.Errorf("token not found")
+	}
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	userID, subject, err := parseOneTimeTokenValue(value)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	// Keep the index of a newer token issued in the meantime so it can still be revoked
+	releaseUserTokenScript.Run(ctx, r.client, []string{userOneTimeTokenKey(purpose, userID)}, tokenHash)
+	return userID, subject, nil
+}
+
+// Revoke deletes the token issued to a user for a purpose, if any
+func (r *OneTimeTokenRepository) Revoke(ctx context.Context, purpose string, userID uuid.UUID) error {
+	tokenHash, err := r.client.GetDel(ctx, userOneTimeTokenKey(purpose, userID)).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.client.Del(ctx, oneTimeTokenKey(purpose, tokenHash)).Err()
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// PrincipalCacheRepository caches the current roles and state of users using Redis
+type PrincipalCacheRepository struct {
+	client *redis.Client
+}
+
+// NewPrincipalCacheRepository creates a new principal cache repository
+func NewPrincipalCacheRepository(client *redis.Client) *PrincipalCacheRepository {
+	return &PrincipalCacheRepository{client: client}
+}
+
+func principalKey(userID uuid.UUID) string {
+	return fmt.Sprintf("principal:%s", userID.String())
+}
+
+// Get returns the cached principal of a user, or nil when none is cached
+func (r *PrincipalCacheRepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserPrincipal, error) {
+	data, err := r.client.Get(ctx, principalKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &models.UserPrincipal{}
+	if err := json.Unmarshal([]byte(data), principal); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// Set caches the principal of a user for ttl
+func (r *PrincipalCacheRepository) Set(ctx context.Context, userID uuid.UUID, principal *models.UserPrincipal, ttl time.Duration) error {
+	data, err := json.Marshal(principal)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, principalKey(userID), data, ttl).Err()
+}
+
+// Delete removes the cached principal of a user
+func (r *PrincipalCacheRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	return r.client.Del(ctx, principalKey(userID)).Err()
+}
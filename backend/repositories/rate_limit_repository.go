@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimitRepository counts requests in fixed time windows using Redis.
+// Counters are kept per scope (e.g. "magic_link_ip") and key (email, IP address...).
+type RateLimitRepository struct {
+	client *redis.Client
+}
+
+// NewRateLimitRepository creates a new rate limit repository
+func NewRateLimitRepository(client *redis.Client) *RateLimitRepository {
+	return &RateLimitRepository{client: client}
+}
+
+func rateLimitKey(scope, key string) string {
+	return fmt.Sprintf("rate_limit:%s:%s", scope, key)
+}
+
+// Hit counts a request and returns the number of requests in the current window and the time left until it ends
+func (r *RateLimitRepository) Hit(ctx context.Context, scope, key string, window time.Duration) (int64, time.Duration, error) {
+	pipe := r.client.TxPipeline()
+	count := pipe.Incr(ctx, rateLimitKey(scope, key))
+	ttl := pipe.PTTL(ctx, rateLimitKey(scope, key))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	// The first request of a window starts it; a counter left without expiry is restarted as well
+	remaining := ttl.Val()
+	if remaining < 0 {
+		if err := r.client.PExpire(ctx, rateLimitKey(scope, key), window).Err(); err != nil {
+			return 0, 0, err
+		}
+		remaining = window
+	}
+	return count.Val(), remaining, nil
+}
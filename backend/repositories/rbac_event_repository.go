@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// rbacChangesChannel is the Redis channel announcing changes to the stored roles and permissions
+const rbacChangesChannel = "rbac:changes"
+
+// RBACEventRepository tells every instance of the API when roles or permissions change, using Redis pub/sub
+type RBACEventRepository struct {
+	client *redis.Client
+}
+
+// NewRBACEventRepository creates a new RBAC event repository
+func NewRBACEventRepository(client *redis.Client) *RBACEventRepository {
+	return &RBACEventRepository{client: client}
+}
+
+// PublishChange announces that the stored roles or permissions changed
+func (r *RBACEventRepository) PublishChange(ctx context.Context) error {
+	return r.client.Publish(ctx, rbacChangesChannel, "changed").Err()
+}
+
+// SubscribeChanges returns a channel receiving a value when roles or permissions change.
+// Changes announced while the previous one is being handled are coalesced. The channel is closed when ctx is done.
+func (r *RBACEventRepository) SubscribeChanges(ctx context.Context) <-chan struct{} {
+	pubsub := r.client.Subscribe(ctx, rbacChangesChannel)
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes
+}
@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"angular-n-go-template/backend/rbac"
+
+	"github.com/lib/pq"
+)
+
+// RBACRepository handles the roles and permissions managed through the admin API
+type RBACRepository struct {
+	db *sql.DB
+}
+
+// NewRBACRepository creates a new RBAC repository
+func NewRBACRepository(db *sql.DB) *RBACRepository {
+	return &RBACRepository{db: db}
+}
+
+// Load retrieves every stored role, with the permissions it grants, and every stored permission
+func (r *RBACRepository) Load() (*rbac.RBACConfig, error) {
+	config := &rbac.RBACConfig{
+		Roles:       make(map[string]*rbac.Role),
+		Permissions: make(map[string]*rbac.Permission),
+	}
+
+	rows, err := r.db.Query(`SELECT name, description, resource, action FROM permissions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		permission := &rbac.Permission{}
+		if err := rows.Scan(&permission.Name, &permission.Description, &permission.Resource, &permission.Action); err != nil {
+			return nil, err
+		}
+		config.Permissions[permission.Name] = permission
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT r.name, r.description, r.require_mfa, r.inherits,
+			COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r LEFT JOIN role_permissions rp ON rp.role_name = r.name
+		GROUP BY r.name
+	`
+
+	roleRows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer roleRows.Close()
+
+	for roleRows.Next() {
+		role := &rbac.Role{}
+		if err := roleRows.Scan(&role.Name, &role.Description, &role.RequireMFA, pq.Array(&role.Inherits), pq.Array(&role.Permissions)); err != nil {
+			return nil, err
+		}
+		config.Roles[role.Name] = role
+	}
+
+	return config, roleRows.Err()
+}
+
+// CreateRole stores a new role with the permissions it grants
+func (r *RBACRepository) CreateRole(role *rbac.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO roles (name, description, require_mfa, inherits, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`
+	if _, err := tx.Exec(query, role.Name, role.Description, role.RequireMFA, pq.Array(role.Inherits), time.Now()); err != nil {
+		return err
+	}
+
+	if err := insertRolePermissions(tx, role); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateRole replaces the description, MFA requirement, parent roles and permissions of a role, returning false if there was none
+func (r *RBACRepository) UpdateRole(role *rbac.Role) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE roles SET description = $2, require_mfa = $3, inherits = $4, updated_at = $5 WHERE name = $1`
+	result, err := tx.Exec(query, role.Name, role.Description, role.RequireMFA, pq.Array(role.Inherits), time.Now())
+	if err != nil {
+		return false, err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_name = $1`, role.Name); err != nil {
+		return false, err
+	}
+	if err := insertRolePermissions(tx, role); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// insertRolePermissions links a role to each of its permissions
+func insertRolePermissions(tx *sql.Tx, role *rbac.Role) error {
+	for _, permission := range role.Permissions {
+		query := `INSERT INTO role_permissions (role_name, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(query, role.Name, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteRole deletes a role and its permission links, returning false if there was none
+func (r *RBACRepository) DeleteRole(name string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM roles WHERE name = $1`, name)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// CreatePermission stores a new permission
+func (r *RBACRepository) CreatePermission(permission *rbac.Permission) error {
+	query := `
+		INSERT INTO permissions (name, description, resource, action, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`
+
+	_, err := r.db.Exec(query, permission.Name, permission.Description, permission.Resource, permission.Action, time.Now())
+	return err
+}
+
+// UpdatePermission updates the description, resource and action of a permission, returning false if there was none
+func (r *RBACRepository) UpdatePermission(permission *rbac.Permission) (bool, error) {
+	query := `UPDATE permissions SET description = $2, resource = $3, action = $4, updated_at = $5 WHERE name = $1`
+
+	result, err := r.db.Exec(query, permission.Name, permission.Description, permission.Resource, permission.Action, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeletePermission deletes a permission and removes it from the roles granting it, returning false if there was none
+func (r *RBACRepository) DeletePermission(name string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE permission = $1`, name); err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM permissions WHERE name = $1`, name)
+	if err != nil {
+		return false, err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// ErrRefreshTokenNotFound is returned when a refresh token does not exist or has expired
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+// RefreshTokenRepository handles refresh token storage using Redis.
+// Token families are tracked as sessions by the SessionRepository.
+type RefreshTokenRepository struct {
+	client *redis.Client
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(client *redis.Client) *RefreshTokenRepository {
+	return &RefreshTokenRepository{client: client}
+}
+
+func refreshTokenKey(tokenHash string) string {
+	return fmt.Sprintf("refresh_token:%s", tokenHash)
+}
+
+// familyRefreshTokensKey indexes the refresh tokens of a family so revoking its session can delete them
+func familyRefreshTokensKey(familyID string) string {
+	return fmt.Sprintf("family_refresh_tokens:%s", familyID)
+}
+
+// Create stores a refresh token until it expires
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	key := refreshTokenKey(token.TokenHash)
+
+	tokenData := map[string]interface{}{
+		"family_id":  token.FamilyID,
+		"user_id":    token.UserID.String(),
+		"used":       0,
+		"amr":        strings.Join(token.AMR, ","),
+		"created_at": token.CreatedAt.Unix(),
+		"expires_at": token.ExpiresAt.Unix(),
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, tokenData)
+	pipe.ExpireAt(ctx, key, token.ExpiresAt)
+	pipe.SAdd(ctx, familyRefreshTokensKey(token.FamilyID), token.TokenHash)
+	pipe.ExpireAt(ctx, familyRefreshTokensKey(token.FamilyID), token.ExpiresAt)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetByHash retrieves a refresh token by the hash of its value
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	result, err := r.client.HGetAll(ctx, refreshTokenKey(tokenHash)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, ErrRefreshTokenNotFound
+	}
+
+	userID, err := uuid.Parse(result["user_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	token := &models.RefreshToken{
+		TokenHash: tokenHash,
+		FamilyID:  result["family_id"],
+		UserID:    userID,
+		Used:      result["used"] != "0",
+	}
+
+	if amr := result["amr"]; amr != "" {
+		token.AMR = strings.Split(amr, ",")
+	}
+
+	if createdAt, err := strconv.ParseInt(result["created_at"], 10, 64); err == nil {
+		token.CreatedAt = time.Unix(createdAt, 0)
+	}
+	if expiresAt, err := strconv.ParseInt(result["expires_at"], 10, 64); err == nil {
+		token.ExpiresAt = time.Unix(expiresAt, 0)
+	}
+
+	return token, nil
+}
+
+// markUsedScript increments the use counter of a refresh token, returning -1 instead of recreating
+// the token without an expiry when it expired since it was read
+var markUsedScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], "used", 1)
+`)
+
+// MarkUsed atomically flags a refresh token as used.
+// It returns false when the token had already been used before this call.
+func (r *RefreshTokenRepository) MarkUsed(ctx context.Context, tokenHash string) (bool, error) {
+	uses, err := markUsedScript.Run(ctx, r.client, []string{refreshTokenKey(tokenHash)}).Int64()
+	if err != nil {
+		return false, err
+	}
+	if uses < 0 {
+		return false, ErrRefreshTokenNotFound
+	}
+	return uses == 1, nil
+}
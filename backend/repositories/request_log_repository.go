@@ -42,6 +42,10 @@ func (r *RequestLogRepository) Create(ctx context.Context, log *models.RequestLo
 		logData["user_id"] = log.UserID.String()
 	}
 	
+	if log.ActorID != nil {
+		logData["actor_id"] = log.ActorID.String()
+	}
+	
 	if log.Error != nil {
 		logData["error"] = *log.Error
 	}
@@ -86,6 +90,14 @@ func (r *RequestLogRepository) GetByRequestID(ctx context.Context, requestID str
 		log.UserID = &parsedUserID
 	}
 	
+	if actorID, ok := result["actor_id"]; ok && actorID != "" {
+		parsedActorID, err := uuid.Parse(actorID)
+		if err != nil {
+			return nil, err
+		}
+		log.ActorID = &parsedActorID
+	}
+	
 	if statusCode, ok := result["status_code"]; ok {
 		fmt.Sscanf(statusCode, "%d", &log.StatusCode)
 	}
@@ -202,6 +214,14 @@ func (r *RequestLogRepository) parseRequestLog(result map[string]string) (*model
 		log.UserID = &parsedUserID
 	}
 	
+	if actorID, ok := result["actor_id"]; ok && actorID != "" {
+		parsedActorID, err := uuid.Parse(actorID)
+		if err != nil {
+			return nil, err
+		}
+		log.ActorID = &parsedActorID
+	}
+	
 	if statusCode, ok := result["status_code"]; ok {
 		fmt.Sscanf(statusCode, "%d", &log.StatusCode)
 	}
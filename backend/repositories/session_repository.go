@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// SessionRepository handles login sessions using Redis.
+// A session lives as long as its refresh token family; deleting it revokes its refresh and access tokens.
+type SessionRepository struct {
+	client *redis.Client
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(client *redis.Client) *SessionRepository {
+	return &SessionRepository{client: client}
+}
+
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("session:%s", sessionID)
+}
+
+func userSessionsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user_sessions:%s", userID.String())
+}
+
+// refreshSessionScript updates the fields of a session given as ARGV pairs after its lifetime in milliseconds,
+// returning 0 instead of recreating a session revoked since it was checked
+var refreshSessionScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], unpack(ARGV, 2))
+redis.call("PEXPIRE", KEYS[1], ARGV[1])
+return 1
+`)
+
+// touchSessionScript records the activity of a session at ARGV[1] when the last one is at least ARGV[2]
+// seconds older, returning 0 when the session does not exist
+var touchSessionScript = redis.NewScript(`
+local lastSeenAt = redis.call("HGET", KEYS[1], "last_seen_at")
+if not lastSeenAt then
+	return 0
+end
+if tonumber(lastSeenAt) == nil or tonumber(ARGV[1]) - tonumber(lastSeenAt) >= tonumber(ARGV[2]) then
+	redis.call("HSET", KEYS[1], "last_seen_at", ARGV[1])
+end
+return 1
+`)
+
+// Create stores a new session for the given lifetime
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session, ttl time.Duration) error {
+	key := sessionKey(session.ID)
+
+	sessionData := map[string]interface{}{
+		"user_id":      session.UserID.String(),
+		"device_name":  session.DeviceName,
+		"user_agent":   session.UserAgent,
+		"ip_address":   session.IPAddress,
+		"created_at":   session.CreatedAt.Unix(),
+		"last_seen_at": session.LastSeenAt.Unix(),
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, sessionData)
+	pipe.Expire(ctx, key, ttl)
+	pipe.SAdd(ctx, userSessionsKey(session.UserID), session.ID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetByID retrieves a session
+func (r *SessionRepository) GetByID(ctx context.Context, sessionID string) (*models.Session, error) {
+	result, err := r.client.HGetAll(ctx, sessionKey(sessionID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	userID, err := uuid.Parse(result["user_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		DeviceName: result["device_name"],
+		UserAgent:  result["user_agent"],
+		IPAddress:  result["ip_address"],
+	}
+
+	if createdAt, err := strconv.ParseInt(result["created_at"], 10, 64); err == nil {
+		session.CreatedAt = time.Unix(createdAt, 0)
+	}
+	if lastSeenAt, err := strconv.ParseInt(result["last_seen_at"], 10, 64); err == nil {
+		session.LastSeenAt = time.Unix(lastSeenAt, 0)
+	}
+
+	return session, nil
+}
+
+// GetByUserID retrieves the active sessions of a user, forgetting those that have expired
+func (r *SessionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Session, error) {
+	sessionIDs, err := r.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*models.Session, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		session, err := r.GetByID(ctx, sessionID)
+		if err != nil {
+			r.client.SRem(ctx, userSessionsKey(userID), sessionID)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// Exists checks whether a session is still active
+func (r *SessionRepository) Exists(ctx context.Context, sessionID string) (bool, error) {
+	count, err := r.client.Exists(ctx, sessionKey(sessionID)).Result()
+	return count > 0, err
+}
+
+// Refresh records a refresh of the session from a client and pushes back its expiry.
+// It returns false when the session does not exist.
+func (r *SessionRepository) Refresh(ctx context.Context, sessionID, ipAddress, userAgent string, lastSeenAt time.Time, ttl time.Duration) (bool, error) {
+	args := []interface{}{ttl.Milliseconds(), "last_seen_at", lastSeenAt.Unix()}
+	if ipAddress != "" {
+		args = append(args, "ip_address", ipAddress)
+	}
+	if userAgent != "" {
+		args = append(args, "user_agent", userAgent)
+	}
+
+	refreshed, err := refreshSessionScript.Run(ctx, r.client, []string{sessionKey(sessionID)}, args...).Int64()
+	return refreshed == 1, err
+}
+
+// Touch checks that a session is active and updates its last activity, at most once per interval.
+// It returns false when the session does not exist.
+func (r *SessionRepository) Touch(ctx context.Context, sessionID string, now time.Time, interval time.Duration) (bool, error) {
+	active, err := touchSessionScript.Run(ctx, r.client, []string{sessionKey(sessionID)}, now.Unix(), int64(interval.Seconds())).Int64()
+	return active == 1, err
+}
+
+// Delete revokes a session of a user.
+// It returns false when the user had no such session.
+func (r *SessionRepository) Delete(ctx context.Context, sessionID string, userID uuid.UUID) (bool, error) {
+	removed, err := r.client.SRem(ctx, userSessionsKey(userID), sessionID).Result()
+	if err != nil || removed == 0 {
+		return false, err
+	}
+
+	if err := r.deleteSessions(ctx, []string{sessionID}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteAllForUser revokes every session of a user
+func (r *SessionRepository) DeleteAllForUser(ctx context.Context, userID uuid.UUID) error {
+	sessionIDs, err := r.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	if err := r.deleteSessions(ctx, sessionIDs); err != nil {
+		return err
+	}
+	return r.client.Del(ctx, userSessionsKey(userID)).Err()
+}
+
+// deleteSessions deletes sessions together with the refresh tokens of their families,
+// so that no token of a revoked family can be presented again
+func (r *SessionRepository) deleteSessions(ctx context.Context, sessionIDs []string) error {
+	pipe := r.client.TxPipeline()
+	for _, sessionID := range sessionIDs {
+		tokenHashes, err := r.client.SMembers(ctx, familyRefreshTokensKey(sessionID)).Result()
+		if err != nil {
+			return err
+		}
+		for _, tokenHash := range tokenHashes {
+			pipe.Del(ctx, refreshTokenKey(tokenHash))
+		}
+		pipe.Del(ctx, sessionKey(sessionID), familyRefreshTokensKey(sessionID))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
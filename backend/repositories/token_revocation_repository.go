@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// TokenRevocationRepository handles the access token denylist and per-user token generations using Redis
+type TokenRevocationRepository struct {
+	client *redis.Client
+}
+
+// NewTokenRevocationRepository creates a new token revocation repository
+func NewTokenRevocationRepository(client *redis.Client) *TokenRevocationRepository {
+	return &TokenRevocationRepository{client: client}
+}
+
+func revokedTokenKey(tokenID string) string {
+	return fmt.Sprintf("revoked_token:%s", tokenID)
+}
+
+func tokenAttemptsKey(tokenID string) string {
+	return fmt.Sprintf("token_attempts:%s", tokenID)
+}
+
+func tokenGenerationKey(userID uuid.UUID) string {
+	return fmt.Sprintf("token_generation:%s", userID.String())
+}
+
+// RevokeToken adds a token ID to the denylist until the token would have expired anyway
+func (r *TokenRevocationRepository) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return r.client.Set(ctx, revokedTokenKey(tokenID), 1, ttl).Err()
+}
+
+// ConsumeToken adds a token ID to the denylist and reports whether it was not already there,
+// so that concurrent uses of a single-use token cannot both succeed
+func (r *TokenRevocationRepository) ConsumeToken(ctx context.Context, tokenID string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, nil
+	}
+	return r.client.SetNX(ctx, revokedTokenKey(tokenID), 1, ttl).Result()
+}
+
+// IsRevoked checks whether a token ID is on the denylist
+func (r *TokenRevocationRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	count, err := r.client.Exists(ctx, revokedTokenKey(tokenID)).Result()
+	return count > 0, err
+}
+
+// GetGeneration returns the current token generation of a user
+func (r *TokenRevocationRepository) GetGeneration(ctx context.Context, userID uuid.UUID) (int64, error) {
+	generation, err := r.client.Get(ctx, tokenGenerationKey(userID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// IncrementGeneration bumps the token generation of a user, invalidating every token issued before
+func (r *TokenRevocationRepository) IncrementGeneration(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.client.Incr(ctx, tokenGenerationKey(userID)).Result()
+}
+
+// IncrementAttempts counts a failed attempt made with a token and returns the total so far
+func (r *TokenRevocationRepository) IncrementAttempts(ctx context.Context, tokenID string, ttl time.Duration) (int64, error) {
+	pipe := r.client.TxPipeline()
+	attempts := pipe.Incr(ctx, tokenAttemptsKey(tokenID))
+	pipe.Expire(ctx, tokenAttemptsKey(tokenID), ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return attempts.Val(), nil
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/google/uuid"
+)
+
+// userIdentityColumns lists the columns selected for an identity, in the order expected by scanUserIdentity
+const userIdentityColumns = `id, user_id, provider, subject, COALESCE(email, ''), created_at, last_login_at`
+
+// scanUserIdentity scans a row selected with userIdentityColumns into a UserIdentity
+func scanUserIdentity(row rowScanner) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	err := row.Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&identity.Email, &identity.CreatedAt, &identity.LastLoginAt,
+	)
+	return identity, err
+}
+
+// UserIdentityRepository handles external identity data operations
+type UserIdentityRepository struct {
+	db *sql.DB
+}
+
+// NewUserIdentityRepository creates a new user identity repository
+func NewUserIdentityRepository(db *sql.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{db: db}
+}
+
+// Create links an external identity to a user
+func (r *UserIdentityRepository) Create(identity *models.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (id, user_id, provider, subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+	`
+
+	_, err := r.db.Exec(query, identity.ID, identity.UserID, identity.Provider, identity.Subject,
+		identity.Email, identity.CreatedAt, identity.LastLoginAt)
+
+	return err
+}
+
+// GetByProviderSubject retrieves the identity of a provider account
+func (r *UserIdentityRepository) GetByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	query := `
+		SELECT ` + userIdentityColumns + `
+		FROM user_identities WHERE provider = $1 AND subject = $2
+	`
+
+	identity, err := scanUserIdentity(r.db.QueryRow(query, provider, subject))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("identity not found")
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+// GetByUserID retrieves every identity linked to a user
+func (r *UserIdentityRepository) GetByUserID(userID uuid.UUID) ([]*models.UserIdentity, error) {
+	query := `
+		SELECT ` + userIdentityColumns + `
+		FROM user_identities WHERE user_id = $1 ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*models.UserIdentity
+	for rows.Next() {
+		identity, err := scanUserIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+// TouchLastLogin records a login with an identity
+func (r *UserIdentityRepository) TouchLastLogin(id uuid.UUID, email string, at time.Time) error {
+	query := `UPDATE user_identities SET last_login_at = $2, email = COALESCE(NULLIF($3, ''), email) WHERE id = $1`
+	_, err := r.db.Exec(query, id, at, email)
+	return err
+}
+
+// Delete unlinks an identity from a user.
+// It returns false when the user has no such identity.
+func (r *UserIdentityRepository) Delete(id, userID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM user_identities WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
@@ -2,13 +2,35 @@ package repositories
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
+	"time"
 
 	"angular-n-go-template/backend/models"
 
 	"github.com/google/uuid"
 )
 
+// ErrUserNotFound is returned when no user matches a lookup
+var ErrUserNotFound = errors.New("user not found")
+
+// userColumns lists the columns selected for a user, in the order expected by scanUser
+const userColumns = `id, email, username, password, first_name, last_name, role, is_active, auth_source, email_verified_at, created_at, updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser scans a row selected with userColumns into a User
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Username, &user.Password,
+		&user.FirstName, &user.LastName, &user.Role, &user.IsActive, &user.AuthSource, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
+	)
+	return user, err
+}
+
 // UserRepository handles user data operations
 type UserRepository struct {
 	db *sql.DB
@@ -21,130 +43,154 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 // Create creates a new user
 func (r *UserRepository) Create(user *models.User) error {
+	return insertUser(r.db, user)
+}
+
+// execer runs statements on a database or within a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertUser inserts a user row
+func insertUser(db execer, user *models.User) error {
 	query := `
-		INSERT INTO users (id, email, username, password, first_name, last_name, role, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO users (id, email, username, password, first_name, last_name, role, is_active, auth_source, email_verified_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
-	
-	_, err := r.db.Exec(query, user.ID, user.Email, user.Username, user.Password, 
-		user.FirstName, user.LastName, user.Role, user.IsActive, user.CreatedAt, user.UpdatedAt)
-	
+
+	_, err := db.Exec(query, user.ID, user.Email, user.Username, user.Password,
+		user.FirstName, user.LastName, user.Role, user.IsActive, user.AuthSource, user.EmailVerifiedAt, user.CreatedAt, user.UpdatedAt)
+
 	return err
 }
 
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, email, username, password, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users WHERE id = $1
 	`
-	
-	user := &models.User{}
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Email, &user.Username, &user.Password,
-		&user.FirstName, &user.LastName, &user.Role, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
-	)
-	
+
+	user, err := scanUser(r.db.QueryRow(query, id))
+
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
-	
+
 	return user, nil
 }
 
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
-		SELECT id, email, username, password, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users WHERE email = $1
 	`
-	
-	user := &models.User{}
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Email, &user.Username, &user.Password,
-		&user.FirstName, &user.LastName, &user.Role, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
-	)
-	
+
+	user, err := scanUser(r.db.QueryRow(query, email))
+
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
-	
+
 	return user, nil
 }
 
 // GetByUsername retrieves a user by username
 func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 	query := `
-		SELECT id, email, username, password, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users WHERE username = $1
 	`
-	
-	user := &models.User{}
-	err := r.db.QueryRow(query, username).Scan(
-		&user.ID, &user.Email, &user.Username, &user.Password,
-		&user.FirstName, &user.LastName, &user.Role, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
-	)
-	
+
+	user, err := scanUser(r.db.QueryRow(query, username))
+
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
-	
+
 	return user, nil
 }
 
 // GetAll retrieves all users with pagination
 func (r *UserRepository) GetAll(limit, offset int) ([]*models.User, error) {
 	query := `
-		SELECT id, email, username, password, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2
 	`
-	
+
 	rows, err := r.db.Query(query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	
+
 	var users []*models.User
 	for rows.Next() {
-		user := &models.User{}
-		err := rows.Scan(
-			&user.ID, &user.Email, &user.Username, &user.Password,
-			&user.FirstName, &user.LastName, &user.Role, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
 		users = append(users, user)
 	}
-	
+
 	return users, nil
 }
 
 // Update updates a user
 func (r *UserRepository) Update(user *models.User) error {
+	return updateUser(r.db, user)
+}
+
+// updateUser updates a user row
+func updateUser(db execer, user *models.User) error {
 	query := `
 		UPDATE users 
 		SET email = $2, username = $3, password = $4, first_name = $5, last_name = $6, 
-		    role = $7, is_active = $8, updated_at = $9
+		    role = $7, is_active = $8, auth_source = $9, email_verified_at = $10, updated_at = $11
 		WHERE id = $1
 	`
-	
-	_, err := r.db.Exec(query, user.ID, user.Email, user.Username, user.Password,
-		user.FirstName, user.LastName, user.Role, user.IsActive, user.UpdatedAt)
-	
+
+	_, err := db.Exec(query, user.ID, user.Email, user.Username, user.Password,
+		user.FirstName, user.LastName, user.Role, user.IsActive, user.AuthSource, user.EmailVerifiedAt, user.UpdatedAt)
+
 	return err
 }
 
+// claimedCredentialTables lists the tables holding the ways into an account other than its password
+var claimedCredentialTables = []string{"webauthn_credentials", "api_keys", "user_identities", "mfa_recovery_codes", "user_mfa"}
+
+// ClaimUnverified updates a user whose email address was proven by someone who may not have registered
+// the account, and removes in the same transaction everything whoever registered it could sign in with:
+// security keys, API keys, linked identities and the authenticator app with its recovery codes
+func (r *UserRepository) ClaimUnverified(user *models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateUser(tx, user); err != nil {
+		return err
+	}
+	for _, table := range claimedCredentialTables {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = $1`, user.ID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 // Delete deletes a user by ID
 func (r *UserRepository) Delete(id uuid.UUID) error {
 	query := `DELETE FROM users WHERE id = $1`
@@ -168,8 +214,13 @@ func (r *UserRepository) UsernameExists(username string) (bool, error) {
 	return count > 0, err
 }
 
-
-
-
-
-
+// RoleInUse checks if any user has a role, as the role of their account or through a grant that has not expired
+func (r *UserRepository) RoleInUse(role string) (bool, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM users WHERE role = $1)
+		OR EXISTS(SELECT 1 FROM user_roles WHERE role = $1 AND (expires_at IS NULL OR expires_at > $2))
+	`
+	var inUse bool
+	err := r.db.QueryRow(query, role, time.Now()).Scan(&inUse)
+	return inUse, err
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/google/uuid"
+)
+
+// UserRoleRepository handles the roles granted to users on top of the role of their account
+type UserRoleRepository struct {
+	db *sql.DB
+}
+
+// NewUserRoleRepository creates a new user role repository
+func NewUserRoleRepository(db *sql.DB) *UserRoleRepository {
+	return &UserRoleRepository{db: db}
+}
+
+// Grant gives a role to a user, replacing the expiry and grantor of an existing grant of the same role
+func (r *UserRoleRepository) Grant(grant *models.UserRole) error {
+	query := `
+		INSERT INTO user_roles (user_id, role, granted_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, role) DO UPDATE
+		SET granted_by = EXCLUDED.granted_by, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+	`
+
+	_, err := r.db.Exec(query, grant.UserID, grant.Role, grant.GrantedBy, grant.ExpiresAt, grant.CreatedAt)
+	return err
+}
+
+// GetActiveByUserID retrieves the grants of a user that have not expired, by role name
+func (r *UserRoleRepository) GetActiveByUserID(userID uuid.UUID) ([]*models.UserRole, error) {
+	query := `
+		SELECT user_id, role, granted_by, expires_at, created_at
+		FROM user_roles
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY role
+	`
+
+	rows, err := r.db.Query(query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []*models.UserRole{}
+	for rows.Next() {
+		grant := &models.UserRole{}
+		if err := rows.Scan(&grant.UserID, &grant.Role, &grant.GrantedBy, &grant.ExpiresAt, &grant.CreatedAt); err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, nil
+}
+
+// Revoke removes a role granted to a user, returning false if there was no such grant
+func (r *UserRoleRepository) Revoke(userID uuid.UUID, role string) (bool, error) {
+	query := `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`
+
+	result, err := r.db.Exec(query, userID, role)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeleteExpired removes the grants that expired before a given time and returns how many there were
+func (r *UserRoleRepository) DeleteExpired(before time.Time) (int64, error) {
+	query := `DELETE FROM user_roles WHERE expires_at IS NOT NULL AND expires_at <= $1`
+
+	result, err := r.db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// WebAuthnChallengeRepository handles the challenges of pending WebAuthn ceremonies using Redis.
+// Challenges are stored by hash and can only be consumed once.
+type WebAuthnChallengeRepository struct {
+	client *redis.Client
+}
+
+// NewWebAuthnChallengeRepository creates a new WebAuthn challenge repository
+func NewWebAuthnChallengeRepository(client *redis.Client) *WebAuthnChallengeRepository {
+	return &WebAuthnChallengeRepository{client: client}
+}
+
+func webAuthnChallengeKey(challengeHash string) string {
+	return fmt.Sprintf("webauthn_challenge:%s", challengeHash)
+}
+
+// Create stores the state of a ceremony until it expires
+func (r *WebAuthnChallengeRepository) Create(ctx context.Context, challengeHash string, challenge *models.WebAuthnChallenge, ttl time.Duration) error {
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, webAuthnChallengeKey(challengeHash), data, ttl).Err()
+}
+
+// Consume atomically deletes and returns the state of a ceremony
+func (r *WebAuthnChallengeRepository) Consume(ctx context.Context, challengeHash string) (*models.WebAuthnChallenge, error) {
+	data, err := r.client.GetDel(ctx, webAuthnChallengeKey(challengeHash)).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("webauthn challenge not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &models.WebAuthnChallenge{}
+	if err := json.Unmarshal([]byte(data), challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// webAuthnCredentialColumns lists the columns selected for a credential, in the order expected by scanWebAuthnCredential
+const webAuthnCredentialColumns = `id, user_id, name, credential_id, public_key, sign_count, COALESCE(aaguid, ''::bytea), transports, backup_eligible, last_used_at, created_at`
+
+// scanWebAuthnCredential scans a row selected with webAuthnCredentialColumns into a WebAuthnCredential
+func scanWebAuthnCredential(row rowScanner) (*models.WebAuthnCredential, error) {
+	credential := &models.WebAuthnCredential{}
+	var signCount int64
+	err := row.Scan(
+		&credential.ID, &credential.UserID, &credential.Name, &credential.CredentialID, &credential.PublicKey,
+		&signCount, &credential.AAGUID, pq.Array(&credential.Transports), &credential.BackupEligible,
+		&credential.LastUsedAt, &credential.CreatedAt,
+	)
+	credential.SignCount = uint32(signCount)
+	return credential, err
+}
+
+// WebAuthnCredentialRepository handles security key and passkey data operations
+type WebAuthnCredentialRepository struct {
+	db *sql.DB
+}
+
+// NewWebAuthnCredentialRepository creates a new WebAuthn credential repository
+func NewWebAuthnCredentialRepository(db *sql.DB) *WebAuthnCredentialRepository {
+	return &WebAuthnCredentialRepository{db: db}
+}
+
+// Create stores a newly registered credential
+func (r *WebAuthnCredentialRepository) Create(credential *models.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials (id, user_id, name, credential_id, public_key, sign_count, aaguid, transports, backup_eligible, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.Exec(query, credential.ID, credential.UserID, credential.Name, credential.CredentialID,
+		credential.PublicKey, int64(credential.SignCount), credential.AAGUID, pq.Array(credential.Transports),
+		credential.BackupEligible, credential.CreatedAt)
+
+	return err
+}
+
+// GetByCredentialID retrieves a credential by the authenticator's credential ID
+func (r *WebAuthnCredentialRepository) GetByCredentialID(credentialID []byte) (*models.WebAuthnCredential, error) {
+	query := `
+		SELECT ` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials WHERE credential_id = $1
+	`
+
+	credential, err := scanWebAuthnCredential(r.db.QueryRow(query, credentialID))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("credential not found")
+		}
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// GetByUserID retrieves every credential of a user, oldest first
+func (r *WebAuthnCredentialRepository) GetByUserID(userID uuid.UUID) ([]*models.WebAuthnCredential, error) {
+	query := `
+		SELECT ` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := []*models.WebAuthnCredential{}
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+// Exists reports whether a user has registered at least one credential
+func (r *WebAuthnCredentialRepository) Exists(userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM webauthn_credentials WHERE user_id = $1)`
+
+	var exists bool
+	err := r.db.QueryRow(query, userID).Scan(&exists)
+	return exists, err
+}
+
+// UpdateSignCount records a use of a credential with its new signature counter.
+// The update only applies while the stored counter is lower, so concurrent replays cannot both succeed.
+func (r *WebAuthnCredentialRepository) UpdateSignCount(id uuid.UUID, signCount uint32, usedAt time.Time) (bool, error) {
+	query := `
+		UPDATE webauthn_credentials SET sign_count = $2, last_used_at = $3
+		WHERE id = $1 AND (sign_count < $2 OR $2 = 0)
+	`
+
+	result, err := r.db.Exec(query, id, int64(signCount), usedAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// Delete deletes a credential belonging to a user, returning false if there was none
+func (r *WebAuthnCredentialRepository) Delete(id, userID uuid.UUID) (bool, error) {
+	query := `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
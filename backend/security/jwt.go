@@ -2,14 +2,19 @@ package security
 
 import (
 	"errors"
+	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-var jwtSecret = []byte(getJWTSecret())
+var (
+	keySetMu sync.RWMutex
+	keySet   *KeySet
+)
 
 // Claims represents the JWT claims
 type Claims struct {
@@ -17,55 +22,169 @@ type Claims struct {
 	Email    string    `json:"email"`
 	Username string    `json:"username"`
 	Role     string    `json:"role"`
+	// Roles lists the roles granted to the user on top of Role that were active at issuance
+	Roles []string `json:"roles,omitempty"`
+	// SessionID identifies the refresh token family the access token was issued for
+	SessionID string `json:"sid,omitempty"`
+	// Generation is the user's token generation at issuance; bumping it revokes older tokens
+	Generation int64 `json:"gen"`
+	// EmailUnverified is set when the user had not verified their email address at issuance
+	EmailUnverified bool `json:"email_unverified,omitempty"`
+	// AMR lists the authentication methods used to obtain the token ("pwd", "mfa")
+	AMR []string `json:"amr,omitempty"`
+	// Purpose marks tokens that only grant access to one step of a flow; access tokens leave it empty
+	Purpose string `json:"purpose,omitempty"`
+	// Scopes restricts the permissions of the principal to this list when set (API keys)
+	Scopes []string `json:"scopes,omitempty"`
+	// Actor is set on impersonation tokens: the subject is the impersonated user, the actor who really acts
+	Actor *Actor `json:"act,omitempty"`
+	// Binding is the hash of the secret kept by the browser that requested a magic link
+	Binding string `json:"bnd,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor identifies the user acting on behalf of the subject of a token (RFC 8693 "act" claim)
+type Actor struct {
+	UserID   uuid.UUID `json:"sub"`
+	Email    string    `json:"email"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	// SessionID is the actor's own session; the impersonation ends with it
+	SessionID string `json:"sid"`
+}
+
+// Authentication methods recorded in the amr claim
+const (
+	AuthMethodPassword  = "pwd"
+	AuthMethodMFA       = "mfa"
+	AuthMethodAPIKey    = "api_key"
+	AuthMethodOIDC      = "oidc"
+	AuthMethodMagicLink = "magic_link"
+	AuthMethodWebAuthn  = "webauthn"
+)
+
+// MFAPendingPurpose marks the short-lived token proving the first login factor while the second is pending
+const MFAPendingPurpose = "mfa_pending"
+
+// MagicLinkPurpose marks the single-use token emailed to log in without a password
+const MagicLinkPurpose = "magic_link"
+
 // getJWTSecret returns the JWT secret from environment variables
 func getJWTSecret() string {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
-		secret = "your-secret-key-change-in-production"
+		secret = defaultJWTSecret
 	}
 	return secret
 }
 
-// GenerateToken generates a JWT token for the given user
-func GenerateToken(userID uuid.UUID, email, username, role string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Token expires in 24 hours
-
-	claims := &Claims{
-		UserID:   userID,
-		Email:    email,
-		Username: username,
-		Role:     role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "angular-n-go-template",
-			Subject:   userID.String(),
-		},
+// InitJWT loads the signing keys used for JWTs.
+// When JWT_KEYS_DIR is set, tokens are signed with the RS256 or EdDSA key selected by
+// JWT_SIGNING_KEY_ID (or the newest key in the directory) and every key in the
+// directory is accepted for verification. Otherwise tokens fall back to HS256 with
+// JWT_SECRET, which is refused in release mode when left at its default value.
+func InitJWT() error {
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	if keysDir != "" {
+		loaded, err := LoadKeySet(keysDir, os.Getenv("JWT_SIGNING_KEY_ID"))
+		if err != nil {
+			return err
+		}
+
+		setKeySet(loaded)
+		log.Printf("Loaded %d JWT verification key(s), signing with %s (%s)", len(loaded.verification), loaded.signing.kid, loaded.signing.method.Alg())
+		return nil
+	}
+
+	secret := getJWTSecret()
+	if secret == defaultJWTSecret && os.Getenv("GIN_MODE") == "release" {
+		return errors.New("refusing to start in release mode with the default JWT secret: set JWT_SECRET or JWT_KEYS_DIR")
+	}
+
+	setKeySet(newHMACKeySet(secret))
+	log.Println("Signing JWTs with HS256; set JWT_KEYS_DIR to use asymmetric keys")
+	return nil
+}
+
+// setKeySet replaces the active key set
+func setKeySet(k *KeySet) {
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+
+	keySet = k
+}
+
+// currentKeySet returns the active key set, defaulting to HS256 with JWT_SECRET when InitJWT has not been called
+func currentKeySet() *KeySet {
+	keySetMu.RLock()
+	k := keySet
+	keySetMu.RUnlock()
+
+	if k != nil {
+		return k
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
-	if err != nil {
-		return "", err
+	k = newHMACKeySet(getJWTSecret())
+	setKeySet(k)
+	return k
+}
+
+// PublicJWKS returns the public keys used to verify tokens issued by this service
+func PublicJWKS() JWKS {
+	return currentKeySet().PublicJWKS()
+}
+
+// AccessTokenTTL returns the lifetime of access tokens from JWT_EXPIRY (default 15 minutes)
+func AccessTokenTTL() time.Duration {
+	return DurationFromEnv("JWT_EXPIRY", 15*time.Minute)
+}
+
+// RefreshTokenTTL returns the lifetime of refresh tokens from REFRESH_TOKEN_EXPIRY (default 30 days)
+func RefreshTokenTTL() time.Duration {
+	return DurationFromEnv("REFRESH_TOKEN_EXPIRY", 30*24*time.Hour)
+}
+
+// DurationFromEnv parses a duration environment variable, falling back to the default when unset or invalid
+func DurationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+// GenerateToken signs a short-lived JWT access token for the given claims.
+// The registered claims (jti, expiry, issuer, subject) are filled in here.
+func GenerateToken(claims *Claims) (string, error) {
+	return GenerateTokenWithTTL(claims, AccessTokenTTL())
+}
+
+// GenerateTokenWithTTL signs a JWT for the given claims that expires after ttl
+func GenerateTokenWithTTL(claims *Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    "angular-n-go-template",
+		Subject:   claims.UserID.String(),
 	}
 
-	return tokenString, nil
+	return currentKeySet().Sign(claims)
 }
 
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, currentKeySet().Keyfunc)
 
 	if err != nil {
 		return nil, err
@@ -78,8 +197,35 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// RemainingLifetime returns how long the token stays valid, or zero if it has expired
+func (c *Claims) RemainingLifetime() time.Duration {
+	if c.ExpiresAt == nil {
+		return 0
+	}
 
+	remaining := time.Until(c.ExpiresAt.Time)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
 
+// HasAuthMethod reports whether the token was obtained using the given authentication method
+func (c *Claims) HasAuthMethod(method string) bool {
+	for _, m := range c.AMR {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
 
+// IsImpersonation reports whether the token was issued to an administrator acting as the subject
+func (c *Claims) IsImpersonation() bool {
+	return c.Actor != nil
+}
 
-
+// AllRoles returns the role of the account followed by the roles granted on top of it
+func (c *Claims) AllRoles() []string {
+	return append([]string{c.Role}, c.Roles...)
+}
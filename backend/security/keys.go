@@ -0,0 +1,225 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// defaultJWTSecret is the development fallback used when JWT_SECRET is not set
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
+// jwtKey is a key identified by its kid together with the algorithm it is used with
+type jwtKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// KeySet holds the active signing key and every key accepted for verification
+type KeySet struct {
+	signing      jwtKey
+	verification map[string]jwtKey
+}
+
+// JWK represents a public JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// newHMACKeySet builds a key set signing with HS256 and a shared secret
+func newHMACKeySet(secret string) *KeySet {
+	key := []byte(secret)
+	return &KeySet{
+		signing: jwtKey{method: jwt.SigningMethodHS256, key: key},
+		verification: map[string]jwtKey{
+			"": {method: jwt.SigningMethodHS256, key: key},
+		},
+	}
+}
+
+// LoadKeySet loads PEM encoded keys from a directory.
+// Each file is named "<kid>.pem". Private keys (RSA or Ed25519) can sign and verify;
+// public keys are only accepted for verification, which lets retired keys keep
+// validating tokens issued before a rotation. The signing key is activeKID, or the
+// private key with the lexicographically greatest kid when activeKID is empty.
+func LoadKeySet(dir, activeKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := &KeySet{verification: make(map[string]jwtKey)}
+	signers := make(map[string]jwtKey)
+
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, verifier, err := parsePEMKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+		}
+
+		keySet.verification[kid] = verifier
+		if signer != nil {
+			signers[kid] = *signer
+		}
+	}
+
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no private signing key found in %s", dir)
+	}
+
+	if activeKID == "" {
+		kids := make([]string, 0, len(signers))
+		for kid := range signers {
+			kids = append(kids, kid)
+		}
+		sort.Strings(kids)
+		activeKID = kids[len(kids)-1]
+	}
+
+	signer, exists := signers[activeKID]
+	if !exists {
+		return nil, fmt.Errorf("signing key %q not found in %s", activeKID, dir)
+	}
+	keySet.signing = signer
+
+	return keySet, nil
+}
+
+// parsePEMKey parses a single PEM block into a verification key and, for private keys, a signing key
+func parsePEMKey(kid string, data []byte) (*jwtKey, jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, jwtKey{}, fmt.Errorf("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, jwtKey{}, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, jwtKey{}, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &jwtKey{kid: kid, method: jwt.SigningMethodRS256, key: key},
+			jwtKey{kid: kid, method: jwt.SigningMethodRS256, key: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &jwtKey{kid: kid, method: jwt.SigningMethodEdDSA, key: key},
+			jwtKey{kid: kid, method: jwt.SigningMethodEdDSA, key: key.Public()}, nil
+	case *rsa.PublicKey:
+		return nil, jwtKey{kid: kid, method: jwt.SigningMethodRS256, key: key}, nil
+	case ed25519.PublicKey:
+		return nil, jwtKey{kid: kid, method: jwt.SigningMethodEdDSA, key: key}, nil
+	default:
+		return nil, jwtKey{}, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+// Sign signs the claims with the active signing key, setting the kid header
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	if k.signing.kid != "" {
+		token.Header["kid"] = k.signing.kid
+	}
+	return token.SignedString(k.signing.key)
+}
+
+// Keyfunc resolves the verification key for a token from its kid header
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, exists := k.verification[kid]
+	if !exists {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	return key.key, nil
+}
+
+// PublicJWKS returns the public verification keys as a JSON Web Key Set.
+// Shared HMAC secrets are never published.
+func (k *KeySet) PublicJWKS() JWKS {
+	kids := make([]string, 0, len(k.verification))
+	for kid := range k.verification {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		if jwk, ok := toJWK(k.verification[kid]); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// toJWK converts an asymmetric verification key to its JWK representation
+func toJWK(key jwtKey) (JWK, bool) {
+	switch publicKey := key.key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: key.kid,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: key.kid,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(publicKey),
+		}, true
+	}
+	return JWK{}, false
+}
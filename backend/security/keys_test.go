@@ -0,0 +1,101 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func TestLoadKeySet_RotationAndJWKS(t *testing.T) {
+	dir := t.TempDir()
+
+	// Retired RSA key, only its public half is kept for verification
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	writePEM(t, dir, "2026-01.pem", "PUBLIC KEY", rsaPublicDER)
+
+	// Current Ed25519 key
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	writePEM(t, dir, "2026-02.pem", "PRIVATE KEY", edDER)
+
+	keySet, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	if keySet.signing.kid != "2026-02" || keySet.signing.method.Alg() != "EdDSA" {
+		t.Fatalf("expected EdDSA key 2026-02 to sign, got %s (%s)", keySet.signing.kid, keySet.signing.method.Alg())
+	}
+
+	setKeySet(keySet)
+	defer setKeySet(nil)
+
+	token, err := GenerateToken(&Claims{UserID: uuid.New(), Role: "user"})
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+	if _, err := ValidateToken(token); err != nil {
+		t.Fatalf("token signed with current key should validate: %v", err)
+	}
+
+	// A token signed by the retired key must still validate during rotation
+	oldKeys := &KeySet{
+		signing:      jwtKey{kid: "2026-01", method: keySet.verification["2026-01"].method, key: rsaKey},
+		verification: keySet.verification,
+	}
+	oldToken, err := oldKeys.Sign(&Claims{UserID: uuid.New(), Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(oldToken); err != nil {
+		t.Fatalf("token signed with retired key should validate: %v", err)
+	}
+
+	jwks := PublicJWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 public keys, got %d", len(jwks.Keys))
+	}
+	if jwks.Keys[0].Kty != "RSA" || jwks.Keys[1].Kty != "OKP" {
+		t.Errorf("unexpected key types: %s, %s", jwks.Keys[0].Kty, jwks.Keys[1].Kty)
+	}
+}
+
+func TestInitJWT_RefusesDefaultSecretInRelease(t *testing.T) {
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("GIN_MODE", "release")
+	defer setKeySet(nil)
+
+	if err := InitJWT(); err == nil {
+		t.Fatal("expected InitJWT to refuse the default secret in release mode")
+	}
+
+	t.Setenv("JWT_SECRET", "a-real-secret")
+	if err := InitJWT(); err != nil {
+		t.Fatalf("expected InitJWT to accept a configured secret: %v", err)
+	}
+	if len(PublicJWKS().Keys) != 0 {
+		t.Error("HMAC secrets must not be published in the JWKS")
+	}
+}
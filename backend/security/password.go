@@ -5,6 +5,8 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/argon2"
@@ -18,20 +20,39 @@ type PasswordConfig struct {
 	KeyLen  uint32
 }
 
-// DefaultPasswordConfig returns the default password configuration
+// DefaultPasswordConfig returns the default password configuration (RFC 9106 second recommended option)
 func DefaultPasswordConfig() *PasswordConfig {
 	return &PasswordConfig{
-		Time:    1,
+		Time:    3,
 		Memory:  64 * 1024,
 		Threads: 4,
 		KeyLen:  32,
 	}
 }
 
+// CurrentPasswordConfig returns the password configuration used for new hashes.
+// ARGON2_TIME, ARGON2_MEMORY (in KiB) and ARGON2_THREADS override the defaults.
+func CurrentPasswordConfig() *PasswordConfig {
+	config := DefaultPasswordConfig()
+	config.Time = uint32(uintFromEnv("ARGON2_TIME", uint64(config.Time), 32))
+	config.Memory = uint32(uintFromEnv("ARGON2_MEMORY", uint64(config.Memory), 32))
+	config.Threads = uint8(uintFromEnv("ARGON2_THREADS", uint64(config.Threads), 8))
+	return config
+}
+
+// uintFromEnv parses a positive integer environment variable of the given bit size, falling back to the default
+func uintFromEnv(key string, fallback uint64, bitSize int) uint64 {
+	value, err := strconv.ParseUint(os.Getenv(key), 10, bitSize)
+	if err != nil || value == 0 {
+		return fallback
+	}
+	return value
+}
+
 // HashPassword hashes a password using Argon2id
 func HashPassword(password string) (string, error) {
-	config := DefaultPasswordConfig()
-	
+	config := CurrentPasswordConfig()
+
 	// Generate a random salt
 	salt := make([]byte, 16)
 	if _, err := rand.Read(salt); err != nil {
@@ -50,40 +71,54 @@ func HashPassword(password string) (string, error) {
 	return encodedHash, nil
 }
 
-// VerifyPassword verifies a password against a hash
-func VerifyPassword(password, encodedHash string) (bool, error) {
+// VerifyPassword verifies a password against a hash.
+// needsRehash reports that the hash was created with parameters other than the current configuration,
+// so callers can store a new hash while they still have the password in clear.
+func VerifyPassword(password, encodedHash string) (valid bool, needsRehash bool, err error) {
 	// Split the encoded hash
 	parts := strings.Split(encodedHash, "$")
-	if len(parts) != 6 {
-		return false, fmt.Errorf("invalid hash format")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, false, fmt.Errorf("invalid hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, err
 	}
 
 	// Parse the configuration
 	var memory, time uint32
 	var threads uint8
-	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads)
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads)
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	// Decode the salt and hash
 	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	// Hash the password with the same parameters
 	otherHash := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(hash)))
 
 	// Compare the hashes
-	return subtle.ConstantTimeCompare(hash, otherHash) == 1, nil
-}
-
-
+	if subtle.ConstantTimeCompare(hash, otherHash) != 1 {
+		return false, false, nil
+	}
 
+	current := CurrentPasswordConfig()
+	needsRehash = version != argon2.Version ||
+		memory != current.Memory ||
+		time != current.Time ||
+		threads != current.Threads ||
+		uint32(len(hash)) != current.KeyLen
 
+	return true, needsRehash, nil
+}
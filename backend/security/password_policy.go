@@ -0,0 +1,161 @@
+package security
+
+import (
+	"bufio"
+	_ "embed"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords is the set of passwords rejected as too common, lowercased
+var commonPasswords = parseCommonPasswords(commonPasswordList)
+
+// parseCommonPasswords reads one password per line, ignoring blank lines and # comments
+func parseCommonPasswords(list string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = struct{}{}
+	}
+	return passwords
+}
+
+// PasswordPolicy describes the rules a new password must follow
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MinCharacterClasses is the number of classes (lowercase, uppercase, digits, symbols) a password must mix
+	MinCharacterClasses int
+	// RejectCommon refuses passwords from the embedded list of common passwords
+	RejectCommon bool
+	// RejectPersonalInfo refuses passwords containing the username or the email address
+	RejectPersonalInfo bool
+	// HistorySize is the number of previous passwords a user cannot reuse (0 disables the check)
+	HistorySize int
+}
+
+// DefaultPasswordPolicy returns the default password policy
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:           8,
+		MaxLength:           128,
+		MinCharacterClasses: 1,
+		RejectCommon:        true,
+		RejectPersonalInfo:  true,
+		HistorySize:         5,
+	}
+}
+
+// CurrentPasswordPolicy returns the password policy configured with the PASSWORD_* environment variables
+func CurrentPasswordPolicy() *PasswordPolicy {
+	policy := DefaultPasswordPolicy()
+	policy.MinLength = intFromEnv("PASSWORD_MIN_LENGTH", policy.MinLength)
+	policy.MaxLength = intFromEnv("PASSWORD_MAX_LENGTH", policy.MaxLength)
+	policy.MinCharacterClasses = intFromEnv("PASSWORD_MIN_CHARACTER_CLASSES", policy.MinCharacterClasses)
+	policy.RejectCommon = boolFromEnv("PASSWORD_REJECT_COMMON", policy.RejectCommon)
+	policy.RejectPersonalInfo = boolFromEnv("PASSWORD_REJECT_PERSONAL_INFO", policy.RejectPersonalInfo)
+	policy.HistorySize = intFromEnv("PASSWORD_HISTORY_SIZE", policy.HistorySize)
+	return policy
+}
+
+// intFromEnv parses a non-negative integer environment variable, falling back to the default when unset or invalid
+func intFromEnv(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// boolFromEnv parses a boolean environment variable, falling back to the default when unset or invalid
+func boolFromEnv(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Validate returns the rules a password breaks, or nil when it is acceptable.
+// personalInfo holds values the password must not contain, such as the username and email address.
+func (p *PasswordPolicy) Validate(password string, personalInfo ...string) []string {
+	var violations []string
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		violations = append(violations, "must be at least "+strconv.Itoa(p.MinLength)+" characters long")
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, "must be at most "+strconv.Itoa(p.MaxLength)+" characters long")
+	}
+
+	if classes := characterClasses(password); classes < p.MinCharacterClasses {
+		violations = append(violations, "must mix at least "+strconv.Itoa(p.MinCharacterClasses)+
+			" of lowercase letters, uppercase letters, digits and symbols")
+	}
+
+	lowered := strings.ToLower(password)
+	if p.RejectCommon {
+		if _, common := commonPasswords[lowered]; common {
+			violations = append(violations, "is too common")
+		}
+	}
+
+	if p.RejectPersonalInfo {
+		for _, value := range personalInfo {
+			if containsPersonalInfo(lowered, value) {
+				violations = append(violations, "must not contain your username or email address")
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// characterClasses counts the classes of characters used in a password
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, used := range []bool{lower, upper, digit, symbol} {
+		if used {
+			count++
+		}
+	}
+	return count
+}
+
+// containsPersonalInfo reports whether a lowercased password contains a username or the local part of an email
+func containsPersonalInfo(password, value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if at := strings.Index(value, "@"); at >= 0 {
+		value = value[:at]
+	}
+	// Very short values would reject too many unrelated passwords
+	if len(value) < 3 {
+		return false
+	}
+	return strings.Contains(password, value)
+}
@@ -0,0 +1,70 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPassword_ReportsOutdatedParameters(t *testing.T) {
+	// Keep the test fast with cheap parameters
+	t.Setenv("ARGON2_TIME", "1")
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_THREADS", "1")
+
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	valid, needsRehash, err := VerifyPassword("correct horse battery staple", hash)
+	if err != nil || !valid || needsRehash {
+		t.Fatalf("expected a valid hash with current parameters, got valid=%v needsRehash=%v err=%v", valid, needsRehash, err)
+	}
+
+	valid, _, err = VerifyPassword("wrong password", hash)
+	if err != nil || valid {
+		t.Fatalf("expected a wrong password to be rejected, got valid=%v err=%v", valid, err)
+	}
+
+	// Raising the cost makes existing hashes outdated, but they still verify
+	t.Setenv("ARGON2_TIME", "2")
+	valid, needsRehash, err = VerifyPassword("correct horse battery staple", hash)
+	if err != nil || !valid || !needsRehash {
+		t.Fatalf("expected a valid hash needing a rehash, got valid=%v needsRehash=%v err=%v", valid, needsRehash, err)
+	}
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	policy.MinCharacterClasses = 3
+
+	cases := []struct {
+		password  string
+		violation string
+	}{
+		{"Sh0rt!", "at least 8 characters"},
+		{"alllowercaseletters", "must mix at least 3"},
+		{"P@ssw0rd", "too common"},
+		{"Alice-1234!", "username or email"},
+		{"Xk7#mQ2!vR", ""},
+	}
+
+	for _, c := range cases {
+		violations := policy.Validate(c.password, "alice", "alice.smith@example.com")
+		joined := strings.Join(violations, "; ")
+		if c.violation == "" {
+			if len(violations) > 0 {
+				t.Errorf("expected %q to be accepted, got %q", c.password, joined)
+			}
+			continue
+		}
+		if !strings.Contains(joined, c.violation) {
+			t.Errorf("expected %q to violate %q, got %q", c.password, c.violation, joined)
+		}
+	}
+
+	// The local part of the email counts as personal information
+	if violations := policy.Validate("alice.smith!99Z", "someone", "alice.smith@example.com"); len(violations) == 0 {
+		t.Error("expected a password containing the email local part to be rejected")
+	}
+}
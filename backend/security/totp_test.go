@@ -0,0 +1,72 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the base32 encoding of the SHA-1 seed "12345678901234567890" from RFC 6238 appendix B
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// Expected values are the last six digits of the RFC 6238 SHA-1 test vectors
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode failed: %v", err)
+		}
+		if code != expected {
+			t.Errorf("at %d: expected %s, got %s", unix, expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP_AcceptsAdjacentStepsOnly(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := TOTPStep(now)
+
+	previous, _ := TOTPCode(rfc6238Secret, step-1)
+	if matched, ok := ValidateTOTP(rfc6238Secret, previous, now); !ok || matched != step-1 {
+		t.Errorf("code from the previous step should be accepted, got step %d, ok %v", matched, ok)
+	}
+
+	stale, _ := TOTPCode(rfc6238Secret, step-2)
+	if _, ok := ValidateTOTP(rfc6238Secret, stale, now); ok {
+		t.Error("code from two steps ago should be rejected")
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, "12345", now); ok {
+		t.Error("code with the wrong number of digits should be rejected")
+	}
+}
+
+func TestEncryptSecret_RoundTrip(t *testing.T) {
+	t.Setenv("SECRET_ENCRYPTION_KEY", "test-key")
+
+	encrypted, err := EncryptSecret(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("EncryptSecret failed: %v", err)
+	}
+	if encrypted == rfc6238Secret {
+		t.Fatal("secret was not encrypted")
+	}
+
+	decrypted, err := DecryptSecret(encrypted)
+	if err != nil || decrypted != rfc6238Secret {
+		t.Fatalf("expected round trip to return the secret, got %q (%v)", decrypted, err)
+	}
+
+	t.Setenv("SECRET_ENCRYPTION_KEY", "another-key")
+	if _, err := DecryptSecret(encrypted); err == nil {
+		t.Error("decrypting with a different key should fail")
+	}
+}
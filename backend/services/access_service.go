@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/repositories"
+)
+
+// AccessService explains the access decisions made for the routes of the API
+type AccessService struct {
+	userRepo        *repositories.UserRepository
+	userRoleService *UserRoleService
+	rbacConfig      *rbac.RBACConfig
+	routes          []rbac.Route
+}
+
+// NewAccessService creates a new access service
+func NewAccessService(userRepo *repositories.UserRepository, userRoleService *UserRoleService, rbacConfig *rbac.RBACConfig) *AccessService {
+	return &AccessService{
+		userRepo:        userRepo,
+		userRoleService: userRoleService,
+		rbacConfig:      rbacConfig,
+	}
+}
+
+// SetRoutes sets the access rules of the routes, once they are registered
+func (s *AccessService) SetRoutes(routes []rbac.Route) {
+	s.routes = routes
+}
+
+// Explain resolves the route serving a request and decides, step by step, whether a user or a role
+// may use it. Users get the roles and email verification restrictions their sessions would get.
+func (s *AccessService) Explain(req *models.ExplainAccessRequest) (*rbac.Decision, error) {
+	if (req.UserID == nil) == (req.Role == "") {
+		return nil, &ValidationError{Message: "Either user_id or role is required"}
+	}
+
+	route, params, found := rbac.FindRoute(s.routes, req.Method, req.Path)
+	if !found {
+		return nil, fmt.Errorf("route not found")
+	}
+
+	principal := &rbac.Principal{Scopes: req.Scopes, MFAAuthenticated: req.MFAAuthenticated}
+
+	if req.UserID == nil {
+		if _, exists := s.rbacConfig.GetRole(req.Role); !exists {
+			return nil, &ValidationError{Message: "Unknown role: " + req.Role}
+		}
+		principal.Roles = []string{req.Role}
+		return s.rbacConfig.Explain(route, params, principal, s.userRoleService.ActiveRoles)
+	}
+
+	user, err := s.userRepo.GetByID(*req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	principal.ID = user.ID.String()
+
+	principal.Roles, err = s.userRoleService.ActiveRoles(principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsEmailVerified() && EmailVerificationPolicy() == EmailVerificationRestrict {
+		principal.Roles = []string{rbac.UnverifiedRole}
+	}
+
+	if route.RequiresAuthentication() {
+		switch {
+		case !user.IsActive:
+			return rbac.DenyAuthentication(route, params, principal, "the account is deactivated"), nil
+		case !user.IsEmailVerified() && EmailVerificationPolicy() == EmailVerificationBlock:
+			return rbac.DenyAuthentication(route, params, principal, "the email address is not verified"), nil
+		}
+	}
+
+	return s.rbacConfig.Explain(route, params, principal, s.userRoleService.ActiveRoles)
+}
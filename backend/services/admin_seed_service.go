@@ -3,6 +3,7 @@ package services
 import (
 	"log"
 	"os"
+	"time"
 
 	"angular-n-go-template/backend/models"
 	"angular-n-go-template/backend/repositories"
@@ -60,16 +61,21 @@ func (s *AdminSeedService) SeedDefaultAdmin() error {
 		return err
 	}
 
-	// Create admin user
+	// Create admin user; the configured address is trusted as verified
+	now := time.Now()
 	adminUser := &models.User{
-		ID:        uuid.New(),
-		Email:     adminEmail,
-		Username:  adminUsername,
-		Password:  hashedPassword,
-		FirstName: adminFirstName,
-		LastName:  adminLastName,
-		Role:      "admin",
-		IsActive:  true,
+		ID:              uuid.New(),
+		Email:           adminEmail,
+		Username:        adminUsername,
+		Password:        hashedPassword,
+		FirstName:       adminFirstName,
+		LastName:        adminLastName,
+		Role:            "admin",
+		IsActive:        true,
+		AuthSource:      models.AuthSourceLocal,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
 	}
 
 	// Save admin user to database
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key so they can be told apart from JWTs and spotted by secret scanners
+const APIKeyPrefix = "pat_"
+
+// apiKeyDisplayLength is the number of leading characters of a key stored in clear to identify it
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// apiKeyTouchInterval is how stale the recorded last use of a key may get before it is updated
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyService handles personal access tokens used by machine clients
+type APIKeyService struct {
+	apiKeyRepo   *repositories.APIKeyRepository
+	userRepo     *repositories.UserRepository
+	userRoleRepo *repositories.UserRoleRepository
+	rbacConfig   *rbac.RBACConfig
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(apiKeyRepo *repositories.APIKeyRepository, userRepo *repositories.UserRepository, userRoleRepo *repositories.UserRoleRepository, rbacConfig *rbac.RBACConfig) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:   apiKeyRepo,
+		userRepo:     userRepo,
+		userRoleRepo: userRoleRepo,
+		rbacConfig:   rbacConfig,
+	}
+}
+
+// IsAPIKey reports whether a bearer credential is an API key rather than a JWT
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// CreateAPIKey creates a key for a user. Scopes must be permissions the user's roles currently grant
+// to the creating session: those of roles requiring two-factor authentication need mfaAuthenticated.
+func (s *APIKeyService) CreateAPIKey(userID uuid.UUID, mfaAuthenticated bool, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	granted, err := grantedRoleNames(s.userRoleRepo, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	roles := append([]string{user.Role}, granted...)
+
+	principal := &rbac.Principal{Roles: roles, MFAAuthenticated: mfaAuthenticated}
+	for _, scope := range req.Scopes {
+		check := s.rbacConfig.CheckPermissions(principal, []string{scope})
+		if check.MFARequired {
+			return nil, &ValidationError{Message: "Scope requires two-factor authentication: " + scope}
+		}
+		if !check.Passed {
+			return nil, &ValidationError{Message: "Scope not granted by your roles: " + scope}
+		}
+	}
+
+	random, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	token := APIKeyPrefix + random
+
+	now := time.Now()
+	key := &models.APIKey{
+		ID:               uuid.New(),
+		UserID:           userID,
+		Name:             req.Name,
+		Prefix:           token[:apiKeyDisplayLength],
+		TokenHash:        security.HashToken(token),
+		Scopes:           req.Scopes,
+		MFAAuthenticated: mfaAuthenticated,
+		CreatedAt:        now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{APIKey: key, Token: token}, nil
+}
+
+// GetAPIKeys lists the keys of a user
+func (s *APIKeyService) GetAPIKeys(userID uuid.UUID) ([]*models.APIKey, error) {
+	return s.apiKeyRepo.GetByUserID(userID)
+}
+
+// RevokeAPIKey deletes a key belonging to a user
+func (s *APIKeyService) RevokeAPIKey(userID, keyID uuid.UUID) error {
+	deleted, err := s.apiKeyRepo.Delete(keyID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &ValidationError{Message: "API key not found"}
+	}
+	return nil
+}
+
+// Authenticate resolves an API key to the claims of its owner, limited to the key's scopes
+func (s *APIKeyService) Authenticate(token string) (*security.Claims, error) {
+	key, err := s.apiKeyRepo.GetByTokenHash(security.HashToken(token))
+	if err != nil || key.IsExpired() {
+		return nil, &ValidationError{Message: "Invalid API key"}
+	}
+
+	user, err := s.userRepo.GetByID(key.UserID)
+	if err != nil || !user.IsActive {
+		return nil, &ValidationError{Message: "Invalid API key"}
+	}
+
+	// Roles are resolved at each request, so grants apply to keys as soon as they change
+	granted, err := grantedRoleNames(s.userRoleRepo, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only write the last use when it is stale, so busy keys do not update the database at each request
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchLastUsed(key.ID, now, apiKeyTouchInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keys carry the second factor of the session that created them
+	amr := []string{security.AuthMethodAPIKey}
+	if key.MFAAuthenticated {
+		amr = append(amr, security.AuthMethodMFA)
+	}
+
+	return &security.Claims{
+		UserID:          user.ID,
+		Email:           user.Email,
+		Username:        user.Username,
+		Role:            user.Role,
+		Roles:           granted,
+		EmailUnverified: !user.IsEmailVerified(),
+		AMR:             amr,
+		Scopes:          key.Scopes,
+	}, nil
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"angular-n-go-template/backend/models"
@@ -11,28 +13,70 @@ import (
 	"github.com/google/uuid"
 )
 
+// Registration modes, selected with REGISTRATION_MODE
+const (
+	// RegistrationOpen lets anyone create an account with the default role
+	RegistrationOpen = "open"
+	// RegistrationInviteOnly only creates accounts from invitations
+	RegistrationInviteOnly = "invite_only"
+)
+
+// RegistrationMode returns the configured registration mode (default "open")
+func RegistrationMode() string {
+	if os.Getenv("REGISTRATION_MODE") == RegistrationInviteOnly {
+		return RegistrationInviteOnly
+	}
+	return RegistrationOpen
+}
+
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo        *repositories.UserRepository
-	requestLogRepo  *repositories.RequestLogRepository
+	userRepo                 *repositories.UserRepository
+	requestLogRepo           *repositories.RequestLogRepository
+	tokenService             *TokenService
+	emailVerificationService *EmailVerificationService
+	mfaService               *MFAService
+	loginProtection          *LoginProtectionService
+	passwordPolicy           *PasswordPolicyService
+	principalService         *PrincipalService
+	authenticators           []Authenticator
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(userRepo *repositories.UserRepository, requestLogRepo *repositories.RequestLogRepository) *AuthService {
+func NewAuthService(userRepo *repositories.UserRepository, requestLogRepo *repositories.RequestLogRepository, tokenService *TokenService, emailVerificationService *EmailVerificationService, mfaService *MFAService, loginProtection *LoginProtectionService, passwordPolicy *PasswordPolicyService, principalService *PrincipalService, authenticators []Authenticator) *AuthService {
 	return &AuthService{
-		userRepo:       userRepo,
-		requestLogRepo: requestLogRepo,
+		userRepo:                 userRepo,
+		requestLogRepo:           requestLogRepo,
+		tokenService:             tokenService,
+		emailVerificationService: emailVerificationService,
+		mfaService:               mfaService,
+		loginProtection:          loginProtection,
+		passwordPolicy:           passwordPolicy,
+		principalService:         principalService,
+		authenticators:           authenticators,
 	}
 }
 
-// LoginResponse represents the response for login
+// LoginResponse represents the response for login.
+// When the user has two-factor authentication enabled, the first login step only
+// returns MFARequired and an MFAToken to exchange for tokens at /auth/login/mfa.
 type LoginResponse struct {
-	Token string             `json:"token"`
-	User  models.UserResponse `json:"user"`
+	Token        string               `json:"token,omitempty"`
+	RefreshToken string               `json:"refresh_token,omitempty"`
+	ExpiresIn    int64                `json:"expires_in,omitempty"`
+	User         *models.UserResponse `json:"user,omitempty"`
+	MFARequired  bool                 `json:"mfa_required,omitempty"`
+	MFAToken     string               `json:"mfa_token,omitempty"`
+	// MFAMethods lists the second factors the user can answer with ("totp", "webauthn")
+	MFAMethods []string `json:"mfa_methods,omitempty"`
 }
 
-// Register registers a new user
-func (s *AuthService) Register(req *models.CreateUserRequest) (*models.UserResponse, error) {
+// Register registers a new user with the default role
+func (s *AuthService) Register(req *models.RegisterRequest) (*models.UserResponse, error) {
+	if RegistrationMode() == RegistrationInviteOnly {
+		return nil, &ValidationError{Message: "Registration is by invitation only"}
+	}
+
 	// Check if email already exists
 	emailExists, err := s.userRepo.EmailExists(req.Email)
 	if err != nil {
@@ -51,91 +95,269 @@ func (s *AuthService) Register(req *models.CreateUserRequest) (*models.UserRespo
 		return nil, &ValidationError{Message: "Username already exists"}
 	}
 
+	if err := s.passwordPolicy.Validate(req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := security.HashPassword(req.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	// Set default role if not provided
-	role := req.Role
-	if role == "" {
-		role = "user"
-	}
-
 	// Create user
-	user := &models.User{
-		ID:        uuid.New(),
-		Email:     req.Email,
-		Username:  req.Username,
-		Password:  hashedPassword,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Role:      role,
-		IsActive:  true,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
+	user := newRegisteredUser(req, hashedPassword)
 
 	err = s.userRepo.Create(user)
 	if err != nil {
 		return nil, err
 	}
+	s.passwordPolicy.Remember(user.ID, user.Password)
+
+	// Ask the user to prove they own the mailbox
+	s.emailVerificationService.SendVerification(user)
 
 	response := user.ToResponse()
 	return &response, nil
 }
 
+// newRegisteredUser builds the account of a self-registered user.
+// Privileged roles can only be granted by an administrator or through an invitation.
+func newRegisteredUser(req *models.RegisterRequest, hashedPassword string) *models.User {
+	now := time.Now()
+	return &models.User{
+		ID:         uuid.New(),
+		Email:      req.Email,
+		Username:   req.Username,
+		Password:   hashedPassword,
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		Role:       models.DefaultRole,
+		IsActive:   true,
+		AuthSource: models.AuthSourceLocal,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
 // Login authenticates a user and returns a token
-func (s *AuthService) Login(req *models.LoginRequest) (*LoginResponse, error) {
-	// Get user by email
+func (s *AuthService) Login(req *models.LoginRequest, client models.ClientInfo) (*LoginResponse, error) {
+	ctx := context.Background()
+
+	// Get user by email; unknown logins may still be provisioned by an external authenticator
 	user, err := s.userRepo.GetByEmail(req.Email)
 	if err != nil {
-		return nil, &ValidationError{Message: "Invalid email or password"}
+		user = nil
+	}
+	var userID *uuid.UUID
+	if user != nil {
+		userID = &user.ID
+	}
+
+	// Refuse throttled accounts and clients before spending time on password hashing
+	if err := s.loginProtection.Check(ctx, req.Email, client.IPAddress); err != nil {
+		s.loginProtection.LogAttempt(ctx, req.Email, userID, client, false, "throttled")
+		return nil, err
 	}
 
 	// Check if user is active
-	if !user.IsActive {
-		return nil, &ValidationError{Message: "Account is deactivated"}
+	if user != nil && !user.IsActive {
+		return nil, s.failLogin(ctx, req.Email, userID, client, "account_inactive", "Account is deactivated")
 	}
 
 	// Verify password
-	valid, err := security.VerifyPassword(req.Password, user.Password)
+	authenticated, err := s.authenticate(req.Email, req.Password, user)
 	if err != nil {
 		return nil, err
 	}
-	if !valid {
-		return nil, &ValidationError{Message: "Invalid email or password"}
+	if authenticated == nil {
+		return nil, s.failLogin(ctx, req.Email, userID, client, "invalid_credentials", "Invalid email or password")
+	}
+	user = authenticated
+
+	s.loginProtection.LogAttempt(ctx, req.Email, &user.ID, client, true, "")
+
+	if err := checkEmailVerified(user); err != nil {
+		return nil, err
 	}
 
 	// Ensure user has a valid role (fix for existing users with empty roles)
 	if user.Role == "" {
-		user.Role = "user"
+		user.Role = models.DefaultRole
 		// Update the user in the database
 		err = s.userRepo.Update(user)
+		if err == nil {
+			err = s.principalService.Invalidate(ctx, user.ID)
+		}
 		if err != nil {
 			// Log the error but don't fail the login
 			fmt.Printf("Warning: Failed to update user role: %v\n", err)
 		}
 	}
 
-	// Generate JWT token
-	token, err := security.GenerateToken(user.ID, user.Email, user.Username, user.Role)
+	// Ask for the second factor before issuing tokens; the failures counted against the account
+	// are only cleared once it succeeds, so a known password does not reset the code guessing budget
+	if challenge, err := requireSecondFactor(s.mfaService, s.tokenService, user, []string{security.AuthMethodPassword}); challenge != nil || err != nil {
+		return challenge, err
+	}
+	if err := s.loginProtection.RecordSuccess(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	// Start a new token family for this login
+	tokens, err := s.tokenService.IssueTokens(ctx, user, []string{security.AuthMethodPassword}, client)
 	if err != nil {
 		return nil, err
 	}
 
-	response := &LoginResponse{
-		Token: token,
-		User:  user.ToResponse(),
+	return newLoginResponse(tokens, user), nil
+}
+
+// requireSecondFactor returns the response asking for a second factor when the user has one,
+// or nil when tokens can be issued. amr lists the authentication methods of the first factor.
+func requireSecondFactor(mfaService *MFAService, tokenService *TokenService, user *models.User, amr []string) (*LoginResponse, error) {
+	methods, err := mfaService.Methods(user.ID)
+	if err != nil || len(methods) == 0 {
+		return nil, err
+	}
+
+	mfaToken, err := tokenService.IssueMFAToken(user, amr)
+	if err != nil {
+		return nil, err
 	}
+	return &LoginResponse{MFARequired: true, MFAToken: mfaToken, MFAMethods: methods}, nil
+}
+
+// authenticate verifies credentials with the authenticator owning the account. Unknown logins are
+// offered to each authenticator in order, so one able to provision users can create the account.
+func (s *AuthService) authenticate(login, password string, user *models.User) (*models.User, error) {
+	for _, authenticator := range s.authenticators {
+		if user != nil && authenticator.Source() != user.AuthSource {
+			continue
+		}
 
-	return response, nil
+		authenticated, err := authenticator.Authenticate(login, password, user)
+		if err != nil || authenticated != nil {
+			return authenticated, err
+		}
+	}
+	return nil, nil
+}
+
+// failLogin counts a failed login against the account and the client, records it and returns the error to report
+func (s *AuthService) failLogin(ctx context.Context, email string, userID *uuid.UUID, client models.ClientInfo, reason, message string) error {
+	s.loginProtection.LogAttempt(ctx, email, userID, client, false, reason)
+	if err := s.loginProtection.RecordFailure(ctx, email, client.IPAddress); err != nil {
+		return err
+	}
+	return &ValidationError{Message: message}
+}
+
+// CompleteMFALogin finishes a two-step login with the MFA token from Login and a TOTP or recovery code
+func (s *AuthService) CompleteMFALogin(req *models.MFALoginRequest, client models.ClientInfo) (*LoginResponse, error) {
+	ctx := context.Background()
+
+	claims, err := s.tokenService.ValidateMFAToken(ctx, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid or expired MFA token"}
+	}
+
+	if !user.IsActive {
+		return nil, &ValidationError{Message: "Account is deactivated"}
+	}
+
+	// Codes are guessed against the account like passwords, across as many MFA tokens as logins allow
+	if err := s.loginProtection.Check(ctx, user.Email, client.IPAddress); err != nil {
+		s.loginProtection.LogAttempt(ctx, user.Email, &user.ID, client, false, "throttled")
+		return nil, err
+	}
+
+	valid, err := s.mfaService.VerifyCode(user.ID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		if err := s.tokenService.RecordFailedMFAAttempt(ctx, claims); err != nil {
+			return nil, err
+		}
+		return nil, s.failLogin(ctx, user.Email, &user.ID, client, "invalid_mfa_code", "Invalid authentication code")
+	}
+
+	if err := s.tokenService.ConsumeMFAToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	if err := s.loginProtection.RecordSuccess(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	// Keep the first factor used before the MFA step (password or single sign-on)
+	amr := append(append([]string{}, claims.AMR...), security.AuthMethodMFA)
+	tokens, err := s.tokenService.IssueTokens(ctx, user, amr, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoginResponse(tokens, user), nil
+}
+
+// Refresh rotates a refresh token and returns a new token pair for the same family
+func (s *AuthService) Refresh(req *models.RefreshTokenRequest, client models.ClientInfo) (*LoginResponse, error) {
+	ctx := context.Background()
+
+	token, err := s.tokenService.RotateRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload the user so role changes and deactivation apply to the new access token
+	user, err := s.userRepo.GetByID(token.UserID)
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid refresh token"}
+	}
+
+	if !user.IsActive {
+		return nil, &ValidationError{Message: "Account is deactivated"}
+	}
+
+	if err := checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokenService.ContinueFamily(ctx, user, token, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoginResponse(tokens, user), nil
+}
+
+// checkEmailVerified refuses unverified users under the "block" email verification policy
+func checkEmailVerified(user *models.User) error {
+	if !user.IsEmailVerified() && EmailVerificationPolicy() == EmailVerificationBlock {
+		return &ValidationError{Message: "Email address has not been verified"}
+	}
+	return nil
+}
+
+// newLoginResponse builds the login response for a freshly issued token pair
+func newLoginResponse(tokens *TokenPair, user *models.User) *LoginResponse {
+	response := user.ToResponse()
+	return &LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         &response,
+	}
 }
 
 // ValidateToken validates a JWT token and returns user info
 func (s *AuthService) ValidateToken(tokenString string) (*models.UserResponse, error) {
-	claims, err := security.ValidateToken(tokenString)
+	claims, err := s.tokenService.ValidateAccessToken(context.Background(), tokenString)
 	if err != nil {
 		return nil, &ValidationError{Message: "Invalid token"}
 	}
@@ -153,15 +375,27 @@ func (s *AuthService) ValidateToken(tokenString string) (*models.UserResponse, e
 	return &response, nil
 }
 
-// Logout logs out a user (in a real application, you might want to blacklist the token)
-func (s *AuthService) Logout(userID uuid.UUID) error {
-	// In a real application, you might want to:
-	// 1. Add the token to a blacklist in Redis
-	// 2. Remove the token from the client
-	// For now, we'll just return success
-	return nil
-}
+// GetProfile returns the profile of an authenticated user
+func (s *AuthService) GetProfile(userID uuid.UUID) (*models.UserResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, &ValidationError{Message: "User not found"}
+	}
 
+	if !user.IsActive {
+		return nil, &ValidationError{Message: "Account is deactivated"}
+	}
 
+	response := user.ToResponse()
+	return &response, nil
+}
 
+// Logout revokes the access token used for the request and its refresh token family
+func (s *AuthService) Logout(claims *security.Claims) error {
+	return s.tokenService.RevokeSession(context.Background(), claims)
+}
 
+// LogoutEverywhere revokes every outstanding token of a user across all devices
+func (s *AuthService) LogoutEverywhere(userID uuid.UUID) error {
+	return s.tokenService.RevokeAllForUser(context.Background(), userID)
+}
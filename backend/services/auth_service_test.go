@@ -0,0 +1,51 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"angular-n-go-template/backend/models"
+)
+
+func TestRegister_CannotRequestElevatedRole(t *testing.T) {
+	// Anonymous registration payloads trying to pick a privileged role
+	payloads := []string{
+		`{"email":"eve@example.com","username":"eve","password":"password123","first_name":"Eve","last_name":"Evil","role":"admin"}`,
+		`{"email":"eve@example.com","username":"eve","password":"password123","first_name":"Eve","last_name":"Evil","Role":"moderator"}`,
+	}
+
+	for _, payload := range payloads {
+		var req models.RegisterRequest
+		if err := json.Unmarshal([]byte(payload), &req); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+
+		user := newRegisteredUser(&req, "hashed")
+		if user.Role != models.DefaultRole {
+			t.Errorf("expected self-registered user to get role %q, got %q", models.DefaultRole, user.Role)
+		}
+	}
+
+	if _, hasRole := reflect.TypeOf(models.RegisterRequest{}).FieldByName("Role"); hasRole {
+		t.Error("RegisterRequest must not accept a role")
+	}
+}
+
+func TestRegister_RefusedInInviteOnlyMode(t *testing.T) {
+	t.Setenv("REGISTRATION_MODE", RegistrationInviteOnly)
+
+	// No repositories are needed: the request must be refused before touching storage
+	service := &AuthService{}
+	_, err := service.Register(&models.RegisterRequest{
+		Email:     "someone@example.com",
+		Username:  "someone",
+		Password:  "password123",
+		FirstName: "Some",
+		LastName:  "One",
+	})
+
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected a validation error in invite-only mode, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+)
+
+// Authenticator verifies login credentials against one source of users
+type Authenticator interface {
+	// Source is the auth_source of the users this authenticator owns
+	Source() string
+	// Authenticate verifies the password of a login. user is the existing account with this login,
+	// or nil when there is none. It returns the authenticated account, provisioned or updated as
+	// needed, or nil when the credentials are invalid.
+	Authenticate(login, password string, user *models.User) (*models.User, error)
+}
+
+// LocalAuthenticator checks passwords against the Argon2id hashes stored in the users table
+type LocalAuthenticator struct {
+	userRepo *repositories.UserRepository
+}
+
+// NewLocalAuthenticator creates a new local password authenticator
+func NewLocalAuthenticator(userRepo *repositories.UserRepository) *LocalAuthenticator {
+	return &LocalAuthenticator{
+		userRepo: userRepo,
+	}
+}
+
+// Source returns models.AuthSourceLocal
+func (a *LocalAuthenticator) Source() string {
+	return models.AuthSourceLocal
+}
+
+// Authenticate verifies the password of an existing local user; local accounts are never provisioned at login
+func (a *LocalAuthenticator) Authenticate(login, password string, user *models.User) (*models.User, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	valid, needsRehash, err := security.VerifyPassword(password, user.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, nil
+	}
+
+	// Upgrade hashes made with outdated Argon2 parameters while the password is known
+	if needsRehash {
+		a.rehashPassword(user, password)
+	}
+
+	return user, nil
+}
+
+// rehashPassword stores a new hash of the password with the current parameters; failures are only logged
+func (a *LocalAuthenticator) rehashPassword(user *models.User, password string) {
+	hashedPassword, err := security.HashPassword(password)
+	if err != nil {
+		fmt.Printf("Warning: Failed to rehash password: %v\n", err)
+		return
+	}
+
+	user.Password = hashedPassword
+	user.UpdatedAt = time.Now()
+	if err := a.userRepo.Update(user); err != nil {
+		fmt.Printf("Warning: Failed to rehash password: %v\n", err)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"angular-n-go-template/backend/mailer"
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// emailVerificationPurpose scopes email verification tokens in the one-time token store
+const emailVerificationPurpose = "email_verification"
+
+// Email verification policies, selected with EMAIL_VERIFICATION_POLICY
+const (
+	// EmailVerificationOff lets unverified users do everything their role allows
+	EmailVerificationOff = "off"
+	// EmailVerificationRestrict limits unverified users to the permissions of rbac.UnverifiedRole
+	EmailVerificationRestrict = "restrict"
+	// EmailVerificationBlock refuses to log in unverified users
+	EmailVerificationBlock = "block"
+)
+
+// EmailVerificationPolicy returns the configured email verification policy (default "off")
+func EmailVerificationPolicy() string {
+	switch policy := os.Getenv("EMAIL_VERIFICATION_POLICY"); policy {
+	case EmailVerificationRestrict, EmailVerificationBlock:
+		return policy
+	default:
+		return EmailVerificationOff
+	}
+}
+
+// EmailVerificationService handles proving ownership of email addresses
+type EmailVerificationService struct {
+	userRepo         *repositories.UserRepository
+	oneTimeTokenRepo *repositories.OneTimeTokenRepository
+	principalService *PrincipalService
+	mailer           mailer.Mailer
+}
+
+// NewEmailVerificationService creates a new email verification service
+func NewEmailVerificationService(userRepo *repositories.UserRepository, oneTimeTokenRepo *repositories.OneTimeTokenRepository, principalService *PrincipalService, mail mailer.Mailer) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:         userRepo,
+		oneTimeTokenRepo: oneTimeTokenRepo,
+		principalService: principalService,
+		mailer:           mail,
+	}
+}
+
+// SendVerification emails a verification link to the user's current address in the background
+func (s *EmailVerificationService) SendVerification(user *models.User) {
+	recipient := *user
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := s.sendVerificationEmail(ctx, &recipient); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+	}()
+}
+
+// sendVerificationEmail issues a verification token and emails it to the user
+func (s *EmailVerificationService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+
+	ttl := security.DurationFromEnv("EMAIL_VERIFICATION_TOKEN_EXPIRY", 48*time.Hour)
+	// The link only verifies the address it was sent to
+	if err := s.oneTimeTokenRepo.Create(ctx, emailVerificationPurpose, security.HashToken(token), user.ID, security.HashToken(user.Email), ttl); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease confirm that %s is your email address by opening the link below. "+
+			"It expires in %s.\n\n%s",
+			user.FirstName, user.Email, ttl, appURL("/verify-email", url.Values{"token": {token}})),
+	})
+}
+
+// ResendVerification sends a new verification link to a user who has not verified their email yet
+func (s *EmailVerificationService) ResendVerification(userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsEmailVerified() {
+		return &ValidationError{Message: "Email is already verified"}
+	}
+
+	s.SendVerification(user)
+	return nil
+}
+
+// RevokeVerification invalidates the pending verification link of a user, e.g. when their email changes
+func (s *EmailVerificationService) RevokeVerification(ctx context.Context, userID uuid.UUID) error {
+	return s.oneTimeTokenRepo.Revoke(ctx, emailVerificationPurpose, userID)
+}
+
+// VerifyEmail marks the user's email as verified using a verification token
+func (s *EmailVerificationService) VerifyEmail(token string) (*models.UserResponse, error) {
+	userID, emailHash, err := s.oneTimeTokenRepo.Consume(context.Background(), emailVerificationPurpose, security.HashToken(token))
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid or expired verification token"}
+	}
+
+	// Links sent to a previous address of the user do not verify the current one
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || emailHash != security.HashToken(user.Email) {
+		return nil, &ValidationError{Message: "Invalid or expired verification token"}
+	}
+
+	if !user.IsEmailVerified() {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		user.UpdatedAt = now
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+
+		// Lift the restrictions of unverified users from their next request
+		if err := s.principalService.Invalidate(context.Background(), user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// accountClaimer stores the claim of an unverified account (repositories.UserRepository)
+type accountClaimer interface {
+	ClaimUnverified(user *models.User) error
+}
+
+// sessionRevoker signs a user out of every device (TokenService)
+type sessionRevoker interface {
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// principalInvalidator drops the cached roles and state of a user (PrincipalService)
+type principalInvalidator interface {
+	Invalidate(ctx context.Context, userID uuid.UUID) error
+}
+
+// claimUnverifiedAccount verifies the address of an account once someone proved they own the mailbox,
+// e.g. through an identity provider. Whoever registered the account may not be that person, so the
+// password they chose is replaced by a random one, the security keys, API keys, linked identities and
+// authenticator app they may have added are removed and every session they hold is revoked; the owner
+// can set a password later with a password reset.
+func claimUnverifiedAccount(ctx context.Context, accounts accountClaimer, sessions sessionRevoker, principals principalInvalidator, user *models.User) error {
+	randomPassword, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := security.HashPassword(randomPassword)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.Password = hashedPassword
+	user.EmailVerifiedAt = &now
+	user.UpdatedAt = now
+	if err := accounts.ClaimUnverified(user); err != nil {
+		return err
+	}
+
+	if err := sessions.RevokeAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+	return principals.Invalidate(ctx, user.ID)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// memoryAccountStore keeps the credentials attached to accounts and removes them on claims
+// as repositories.UserRepository.ClaimUnverified does
+type memoryAccountStore struct {
+	users      map[uuid.UUID]*models.User
+	passkeys   map[uuid.UUID][]string
+	apiKeys    map[uuid.UUID][]string
+	identities map[uuid.UUID][]string
+	totp       map[uuid.UUID]bool
+}
+
+func newMemoryAccountStore() *memoryAccountStore {
+	return &memoryAccountStore{
+		users:      map[uuid.UUID]*models.User{},
+		passkeys:   map[uuid.UUID][]string{},
+		apiKeys:    map[uuid.UUID][]string{},
+		identities: map[uuid.UUID][]string{},
+		totp:       map[uuid.UUID]bool{},
+	}
+}
+
+func (s *memoryAccountStore) ClaimUnverified(user *models.User) error {
+	copied := *user
+	s.users[user.ID] = &copied
+	delete(s.passkeys, user.ID)
+	delete(s.apiKeys, user.ID)
+	delete(s.identities, user.ID)
+	delete(s.totp, user.ID)
+	return nil
+}
+
+// recordingRevoker records the users signed out everywhere and whose principal was invalidated
+type recordingRevoker struct {
+	revoked     []uuid.UUID
+	invalidated []uuid.UUID
+}
+
+func (r *recordingRevoker) RevokeAllForUser(_ context.Context, userID uuid.UUID) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+func (r *recordingRevoker) Invalidate(_ context.Context, userID uuid.UUID) error {
+	r.invalidated = append(r.invalidated, userID)
+	return nil
+}
+
+// newSquattedAccount returns an unverified account registered by someone else with the given password
+func newSquattedAccount(t *testing.T, password string) *models.User {
+	hashedPassword, err := security.HashPassword(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	return &models.User{ID: uuid.New(), Email: "owner@example.com", Password: hashedPassword, Role: "user", IsActive: true}
+}
+
+func TestClaimUnverifiedAccount_RemovesSquatterPasskeys(t *testing.T) {
+	store, revoker := newMemoryAccountStore(), &recordingRevoker{}
+	user := newSquattedAccount(t, "squatter-password")
+
+	// Whoever registered the account adds a passkey to keep using it after the owner signs in
+	store.passkeys[user.ID] = []string{"squatter-passkey"}
+	store.identities[user.ID] = []string{"squatter-identity"}
+
+	if err := claimUnverifiedAccount(context.Background(), store, revoker, revoker, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(store.passkeys[user.ID]) != 0 || len(store.identities[user.ID]) != 0 {
+		t.Errorf("expected the passkeys and identities of the account to be removed, got %v and %v", store.passkeys[user.ID], store.identities[user.ID])
+	}
+	claimed := store.users[user.ID]
+	if claimed == nil || !claimed.IsEmailVerified() {
+		t.Fatal("expected the claimed account to be stored as verified")
+	}
+	if valid, _, _ := security.VerifyPassword("squatter-password", claimed.Password); valid {
+		t.Error("expected the password chosen at registration to be replaced")
+	}
+	if len(revoker.revoked) != 1 || len(revoker.invalidated) != 1 {
+		t.Errorf("expected the sessions and principal of the account to be dropped, got %v and %v", revoker.revoked, revoker.invalidated)
+	}
+}
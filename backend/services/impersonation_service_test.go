@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckCanImpersonate(t *testing.T) {
+	adminID := uuid.New()
+	targetID := uuid.New()
+	interactive := &security.Claims{UserID: adminID, Role: "admin", SessionID: "session", AMR: []string{security.AuthMethodPassword}}
+
+	if err := checkCanImpersonate(interactive, targetID); err != nil {
+		t.Fatalf("expected an interactive administrator to impersonate another user, got %v", err)
+	}
+
+	cases := map[string]struct {
+		actor  *security.Claims
+		target uuid.UUID
+	}{
+		"self": {interactive, adminID},
+		"api key": {
+			&security.Claims{UserID: adminID, Role: "admin", AMR: []string{security.AuthMethodAPIKey}},
+			targetID,
+		},
+		"nested impersonation": {
+			&security.Claims{UserID: uuid.New(), Role: "admin", Actor: &security.Actor{UserID: adminID, SessionID: "session"}},
+			targetID,
+		},
+	}
+
+	for name, tc := range cases {
+		if err := checkCanImpersonate(tc.actor, tc.target); err == nil {
+			t.Errorf("%s: expected impersonation to be refused", name)
+		}
+	}
+}
+
+func TestImpersonationToken_CarriesActor(t *testing.T) {
+	actor := &security.Actor{UserID: uuid.New(), Email: "admin@example.com", Role: "admin", SessionID: "admin-session"}
+	token, err := security.GenerateToken(&security.Claims{UserID: uuid.New(), Role: "user", Actor: actor})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	claims, err := security.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if !claims.IsImpersonation() || *claims.Actor != *actor {
+		t.Errorf("expected the act claim to round-trip, got %+v", claims.Actor)
+	}
+}
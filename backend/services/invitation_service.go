@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"angular-n-go-template/backend/mailer"
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// InvitationService handles creating accounts with pre-assigned roles through invitations
+type InvitationService struct {
+	invitationRepo *repositories.InvitationRepository
+	userRepo       *repositories.UserRepository
+	passwordPolicy *PasswordPolicyService
+	rbacConfig     *rbac.RBACConfig
+	mailer         mailer.Mailer
+}
+
+// NewInvitationService creates a new invitation service
+func NewInvitationService(invitationRepo *repositories.InvitationRepository, userRepo *repositories.UserRepository, passwordPolicy *PasswordPolicyService, rbacConfig *rbac.RBACConfig, mail mailer.Mailer) *InvitationService {
+	return &InvitationService{
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		passwordPolicy: passwordPolicy,
+		rbacConfig:     rbacConfig,
+		mailer:         mail,
+	}
+}
+
+// CreateInvitation invites an email address to create an account with the given role.
+// Administrators can only invite with roles whose permissions their own roles grant.
+func (s *InvitationService) CreateInvitation(req *models.CreateInvitationRequest, actor *security.Claims) (*models.Invitation, error) {
+	if _, exists := s.rbacConfig.GetRole(req.Role); !exists {
+		return nil, &ValidationError{Message: "Unknown role: " + req.Role}
+	}
+	if !s.rbacConfig.CanActOnRoles(actor.AllRoles(), []string{req.Role}) {
+		return nil, &ForbiddenError{Message: "Cannot invite with more privileges than your own"}
+	}
+
+	emailExists, err := s.userRepo.EmailExists(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if emailExists {
+		return nil, &ValidationError{Message: "Email already exists"}
+	}
+
+	token, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := security.DurationFromEnv("INVITATION_EXPIRY", 7*24*time.Hour)
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	now := time.Now()
+	invitation := &models.Invitation{
+		ID:        uuid.New(),
+		Email:     req.Email,
+		Role:      req.Role,
+		Message:   req.Message,
+		TokenHash: security.HashToken(token),
+		InvitedBy: &actor.UserID,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, err
+	}
+	invitation.Status = invitation.CurrentStatus()
+
+	s.sendInvitation(invitation, token)
+	return invitation, nil
+}
+
+// sendInvitation emails the invitation link in the background
+func (s *InvitationService) sendInvitation(invitation *models.Invitation, token string) {
+	body := "Hello,\n\nYou have been invited to create an account."
+	if invitation.Message != "" {
+		body += "\n\n" + invitation.Message
+	}
+	body += fmt.Sprintf("\n\nUse the link below to choose your username and password. It expires on %s.\n\n%s",
+		invitation.ExpiresAt.Format(time.RFC1123), appURL("/accept-invitation", url.Values{"token": {token}}))
+
+	message := &mailer.Message{
+		To:      invitation.Email,
+		Subject: "You have been invited",
+		Body:    body,
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := s.mailer.Send(ctx, message); err != nil {
+			log.Printf("Failed to send invitation email: %v", err)
+		}
+	}()
+}
+
+// GetInvitations lists invitations with pagination, optionally only the pending ones
+func (s *InvitationService) GetInvitations(pendingOnly bool, limit, offset int) ([]*models.Invitation, error) {
+	return s.invitationRepo.GetAll(pendingOnly, limit, offset)
+}
+
+// RevokeInvitation cancels a pending invitation so its link can no longer be used
+func (s *InvitationService) RevokeInvitation(id uuid.UUID) error {
+	if _, err := s.invitationRepo.GetByID(id); err != nil {
+		return err
+	}
+
+	revoked, err := s.invitationRepo.Revoke(id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return &ValidationError{Message: "Invitation has already been accepted or revoked"}
+	}
+	return nil
+}
+
+// AcceptInvitation creates the invited account with the role chosen by the administrator
+func (s *InvitationService) AcceptInvitation(req *models.AcceptInvitationRequest) (*models.UserResponse, error) {
+	invitation, err := s.invitationRepo.GetByTokenHash(security.HashToken(req.Token))
+	if err != nil || !invitation.IsPending() {
+		return nil, &ValidationError{Message: "Invalid or expired invitation"}
+	}
+
+	emailExists, err := s.userRepo.EmailExists(invitation.Email)
+	if err != nil {
+		return nil, err
+	}
+	if emailExists {
+		return nil, &ValidationError{Message: "Email already exists"}
+	}
+
+	usernameExists, err := s.userRepo.UsernameExists(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if usernameExists {
+		return nil, &ValidationError{Message: "Username already exists"}
+	}
+
+	if err := s.passwordPolicy.Validate(req.Password, req.Username, invitation.Email); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := security.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// The invitation link was delivered to the address, which proves ownership of it
+	now := time.Now()
+	user := &models.User{
+		ID:              uuid.New(),
+		Email:           invitation.Email,
+		Username:        req.Username,
+		Password:        hashedPassword,
+		FirstName:       req.FirstName,
+		LastName:        req.LastName,
+		Role:            invitation.Role,
+		IsActive:        true,
+		AuthSource:      models.AuthSourceLocal,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	accepted, err := s.invitationRepo.Accept(invitation.ID, user, now)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, &ValidationError{Message: "Invalid or expired invitation"}
+	}
+	s.passwordPolicy.Remember(user.ID, user.Password)
+
+	response := user.ToResponse()
+	return &response, nil
+}
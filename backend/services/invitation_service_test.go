@@ -0,0 +1,21 @@
+package services
+
+import (
+	"testing"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateInvitation_RefusesMorePrivilegedRoles(t *testing.T) {
+	service := NewInvitationService(nil, nil, nil, rbac.DefaultRBACConfig(), nil)
+	moderator := &security.Claims{UserID: uuid.New(), Role: "moderator"}
+
+	_, err := service.CreateInvitation(&models.CreateInvitationRequest{Email: "new@example.com", Role: "admin"}, moderator)
+	if _, ok := err.(*ForbiddenError); !ok {
+		t.Errorf("expected a moderator to be refused inviting an admin, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"angular-n-go-template/backend/directory"
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// ldapNoDefaultRole is the LDAP_DEFAULT_ROLE value refusing directory users outside every mapped group
+const ldapNoDefaultRole = "none"
+
+// GroupRole gives a role to the members of a directory group
+type GroupRole struct {
+	// Group is a group DN or the value of the first component of its DN (e.g. its cn)
+	Group string
+	Role  string
+}
+
+// LDAPSettings configures the LDAP authenticator
+type LDAPSettings struct {
+	Directory directory.Config
+	// GroupRoles are checked in order: the first group the user belongs to gives its role
+	GroupRoles []GroupRole
+	// DefaultRole is given to users outside every mapped group; empty refuses them
+	DefaultRole string
+}
+
+// LDAPSettingsFromEnv reads the LDAP configuration, or returns nil when LDAP_URL is not set.
+// LDAP_GROUP_ROLES maps groups to roles as "group:role" pairs separated by semicolons,
+// e.g. "cn=admins,ou=groups,dc=example,dc=com:admin;Developers:user".
+func LDAPSettingsFromEnv() *LDAPSettings {
+	if os.Getenv("LDAP_URL") == "" {
+		return nil
+	}
+
+	startTLS, _ := strconv.ParseBool(os.Getenv("LDAP_START_TLS"))
+	insecureSkipVerify, _ := strconv.ParseBool(os.Getenv("LDAP_INSECURE_SKIP_VERIFY"))
+
+	defaultRole := os.Getenv("LDAP_DEFAULT_ROLE")
+	switch defaultRole {
+	case "":
+		defaultRole = models.DefaultRole
+	case ldapNoDefaultRole:
+		defaultRole = ""
+	}
+
+	return &LDAPSettings{
+		Directory: directory.Config{
+			URL:                os.Getenv("LDAP_URL"),
+			StartTLS:           startTLS,
+			InsecureSkipVerify: insecureSkipVerify,
+			Timeout:            security.DurationFromEnv("LDAP_TIMEOUT", 10*time.Second),
+			BindDN:             os.Getenv("LDAP_BIND_DN"),
+			BindPassword:       os.Getenv("LDAP_BIND_PASSWORD"),
+			BaseDN:             os.Getenv("LDAP_BASE_DN"),
+			UserFilter:         os.Getenv("LDAP_USER_FILTER"),
+			GroupBaseDN:        os.Getenv("LDAP_GROUP_BASE_DN"),
+			GroupFilter:        os.Getenv("LDAP_GROUP_FILTER"),
+			EmailAttribute:     os.Getenv("LDAP_EMAIL_ATTRIBUTE"),
+			UsernameAttribute:  os.Getenv("LDAP_USERNAME_ATTRIBUTE"),
+			FirstNameAttribute: os.Getenv("LDAP_FIRST_NAME_ATTRIBUTE"),
+			LastNameAttribute:  os.Getenv("LDAP_LAST_NAME_ATTRIBUTE"),
+			GroupAttribute:     os.Getenv("LDAP_GROUP_ATTRIBUTE"),
+		},
+		GroupRoles:  parseGroupRoles(os.Getenv("LDAP_GROUP_ROLES")),
+		DefaultRole: defaultRole,
+	}
+}
+
+// parseGroupRoles parses "group:role" pairs separated by semicolons; the role follows the last colon
+func parseGroupRoles(value string) []GroupRole {
+	var groupRoles []GroupRole
+	for _, pair := range strings.Split(value, ";") {
+		separator := strings.LastIndex(pair, ":")
+		if separator < 0 {
+			if strings.TrimSpace(pair) != "" {
+				log.Printf("Warning: Invalid LDAP_GROUP_ROLES entry %q, expected group:role", pair)
+			}
+			continue
+		}
+
+		group, role := strings.TrimSpace(pair[:separator]), strings.TrimSpace(pair[separator+1:])
+		if group == "" || role == "" {
+			log.Printf("Warning: Invalid LDAP_GROUP_ROLES entry %q, expected group:role", pair)
+			continue
+		}
+		groupRoles = append(groupRoles, GroupRole{Group: group, Role: role})
+	}
+	return groupRoles
+}
+
+// LDAPAuthenticator binds to an LDAP directory or Active Directory with the user's credentials.
+// Users are provisioned on their first login and their profile and role are synchronised on every login.
+type LDAPAuthenticator struct {
+	client           *directory.Client
+	userRepo         *repositories.UserRepository
+	principalService *PrincipalService
+	settings         LDAPSettings
+}
+
+// NewLDAPAuthenticator creates a new LDAP authenticator; group mappings to unknown roles are dropped
+func NewLDAPAuthenticator(client *directory.Client, settings *LDAPSettings, userRepo *repositories.UserRepository, principalService *PrincipalService, rbacConfig *rbac.RBACConfig) *LDAPAuthenticator {
+	validated := *settings
+	validated.GroupRoles = nil
+	for _, groupRole := range settings.GroupRoles {
+		if _, exists := rbacConfig.GetRole(groupRole.Role); !exists {
+			log.Printf("Warning: LDAP group %q is mapped to unknown role %q, ignoring it", groupRole.Group, groupRole.Role)
+			continue
+		}
+		validated.GroupRoles = append(validated.GroupRoles, groupRole)
+	}
+	if _, exists := rbacConfig.GetRole(validated.DefaultRole); validated.DefaultRole != "" && !exists {
+		log.Printf("Warning: LDAP default role %q is unknown, directory users outside mapped groups will be refused", validated.DefaultRole)
+		validated.DefaultRole = ""
+	}
+
+	return &LDAPAuthenticator{
+		client:           client,
+		userRepo:         userRepo,
+		principalService: principalService,
+		settings:         validated,
+	}
+}
+
+// Source returns models.AuthSourceLDAP
+func (a *LDAPAuthenticator) Source() string {
+	return models.AuthSourceLDAP
+}
+
+// Authenticate binds as the directory user, then provisions or synchronises the local account
+func (a *LDAPAuthenticator) Authenticate(login, password string, user *models.User) (*models.User, error) {
+	entry, err := a.client.Authenticate(login, password)
+	if errors.Is(err, directory.ErrInvalidCredentials) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role, allowed := roleForEntry(entry, a.settings.GroupRoles, a.settings.DefaultRole)
+	if !allowed {
+		return nil, &ValidationError{Message: "Your directory account is not allowed to use this application"}
+	}
+
+	if user == nil {
+		return a.provisionUser(entry, login, role)
+	}
+	return a.syncUser(user, entry, role)
+}
+
+// roleForEntry returns the role of the first mapped group the user belongs to, or the default role.
+// allowed is false when no group matches and there is no default role.
+func roleForEntry(entry *directory.Entry, groupRoles []GroupRole, defaultRole string) (role string, allowed bool) {
+	for _, groupRole := range groupRoles {
+		if entry.InGroup(groupRole.Group) {
+			return groupRole.Role, true
+		}
+	}
+	return defaultRole, defaultRole != ""
+}
+
+// provisionUser creates the local account of a directory user on their first login.
+// The stored password is random and never used: the directory checks every login.
+func (a *LDAPAuthenticator) provisionUser(entry *directory.Entry, login, role string) (*models.User, error) {
+	email := entry.Email
+	if email == "" {
+		email = login
+	}
+
+	username, err := availableUsername(a.userRepo, entry.Username, email)
+	if err != nil {
+		return nil, err
+	}
+
+	randomPassword, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := security.HashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// The directory vouches for the email address of its users
+	now := time.Now()
+	user := &models.User{
+		ID:              uuid.New(),
+		Email:           email,
+		Username:        username,
+		Password:        hashedPassword,
+		FirstName:       entry.FirstName,
+		LastName:        entry.LastName,
+		Role:            role,
+		IsActive:        true,
+		AuthSource:      models.AuthSourceLDAP,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err := a.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// syncUser copies the directory profile and role of the user to the local account when they changed.
+// Without group mappings the role is left to administrators.
+func (a *LDAPAuthenticator) syncUser(user *models.User, entry *directory.Entry, role string) (*models.User, error) {
+	changed := false
+	if entry.FirstName != "" && entry.FirstName != user.FirstName {
+		user.FirstName = entry.FirstName
+		changed = true
+	}
+	if entry.LastName != "" && entry.LastName != user.LastName {
+		user.LastName = entry.LastName
+		changed = true
+	}
+	if len(a.settings.GroupRoles) > 0 && role != user.Role {
+		user.Role = role
+		changed = true
+	}
+	if !user.IsEmailVerified() {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		changed = true
+	}
+
+	if changed {
+		user.UpdatedAt = time.Now()
+		if err := a.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+		// A role taken away in the directory applies to the user's other sessions too
+		if err := a.principalService.Invalidate(context.Background(), user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
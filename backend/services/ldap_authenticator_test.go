@@ -0,0 +1,160 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"angular-n-go-template/backend/directory"
+	"angular-n-go-template/backend/directory/directorytest"
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+)
+
+func TestParseGroupRoles(t *testing.T) {
+	got := parseGroupRoles(" cn=admins,ou=groups,dc=example,dc=com:admin ; Developers:user;invalid;:user;")
+	want := []GroupRole{
+		{Group: "cn=admins,ou=groups,dc=example,dc=com", Role: "admin"},
+		{Group: "Developers", Role: "user"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGroupRoles() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLDAPSettingsFromEnv_DefaultRole(t *testing.T) {
+	t.Setenv("LDAP_URL", "")
+	t.Setenv("LDAP_DEFAULT_ROLE", "")
+	if LDAPSettingsFromEnv() != nil {
+		t.Fatal("expected LDAP to be disabled without LDAP_URL")
+	}
+
+	t.Setenv("LDAP_URL", "ldap://localhost")
+	if settings := LDAPSettingsFromEnv(); settings.DefaultRole != models.DefaultRole {
+		t.Errorf("expected default role %q, got %q", models.DefaultRole, settings.DefaultRole)
+	}
+
+	t.Setenv("LDAP_DEFAULT_ROLE", ldapNoDefaultRole)
+	if settings := LDAPSettingsFromEnv(); settings.DefaultRole != "" {
+		t.Errorf("expected no default role, got %q", settings.DefaultRole)
+	}
+}
+
+func TestRoleForEntry(t *testing.T) {
+	groupRoles := []GroupRole{
+		{Group: "cn=admins,ou=groups,dc=example,dc=com", Role: "admin"},
+		{Group: "developers", Role: "user"},
+	}
+	admin := &directory.Entry{Groups: []string{"cn=developers,ou=groups,dc=example,dc=com", "CN=Admins,OU=Groups,DC=example,DC=com"}}
+	developer := &directory.Entry{Groups: []string{"cn=developers,ou=groups,dc=example,dc=com"}}
+	outsider := &directory.Entry{Groups: []string{"cn=sales,ou=groups,dc=example,dc=com"}}
+
+	// The first matching mapping wins, whatever the order of the user's groups
+	if role, allowed := roleForEntry(admin, groupRoles, ""); role != "admin" || !allowed {
+		t.Errorf("expected admin, got %q (allowed %v)", role, allowed)
+	}
+	if role, allowed := roleForEntry(developer, groupRoles, ""); role != "user" || !allowed {
+		t.Errorf("expected user, got %q (allowed %v)", role, allowed)
+	}
+	if role, allowed := roleForEntry(outsider, groupRoles, "user"); role != "user" || !allowed {
+		t.Errorf("expected the default role, got %q (allowed %v)", role, allowed)
+	}
+	if _, allowed := roleForEntry(outsider, groupRoles, ""); allowed {
+		t.Error("expected users outside mapped groups to be refused without a default role")
+	}
+}
+
+func TestNewLDAPAuthenticator_DropsUnknownRoles(t *testing.T) {
+	settings := &LDAPSettings{
+		GroupRoles:  []GroupRole{{Group: "admins", Role: "admin"}, {Group: "wizards", Role: "wizard"}},
+		DefaultRole: "apprentice",
+	}
+
+	authenticator := NewLDAPAuthenticator(nil, settings, nil, nil, rbac.DefaultRBACConfig())
+	if !reflect.DeepEqual(authenticator.settings.GroupRoles, []GroupRole{{Group: "admins", Role: "admin"}}) {
+		t.Errorf("expected the mapping to an unknown role to be dropped, got %+v", authenticator.settings.GroupRoles)
+	}
+	if authenticator.settings.DefaultRole != "" {
+		t.Errorf("expected an unknown default role to be dropped, got %q", authenticator.settings.DefaultRole)
+	}
+}
+
+func TestLDAPAuthenticator_Authenticate(t *testing.T) {
+	server := directorytest.NewServer(
+		directorytest.Entry{
+			DN:         "uid=jane,ou=people,dc=example,dc=com",
+			Password:   "jane-password",
+			Attributes: map[string][]string{"mail": {"jane@example.com"}, "memberOf": {"cn=sales,ou=groups,dc=example,dc=com"}},
+		},
+	)
+	defer server.Close()
+
+	settings := &LDAPSettings{
+		Directory:  directory.Config{URL: server.URL(), BaseDN: "dc=example,dc=com"},
+		GroupRoles: []GroupRole{{Group: "admins", Role: "admin"}},
+	}
+	authenticator := NewLDAPAuthenticator(directory.NewClient(settings.Directory), settings, nil, nil, rbac.DefaultRBACConfig())
+
+	// Wrong passwords are reported as invalid credentials, not errors
+	user, err := authenticator.Authenticate("jane@example.com", "wrong-password", nil)
+	if user != nil || err != nil {
+		t.Errorf("expected invalid credentials, got %v, %v", user, err)
+	}
+
+	// Directory users outside the mapped groups are refused before any account is provisioned
+	_, err = authenticator.Authenticate("jane@example.com", "jane-password", nil)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected a validation error for a user outside the mapped groups, got %v", err)
+	}
+}
+
+// stubAuthenticator accepts one password for its source and records the calls it gets
+type stubAuthenticator struct {
+	source   string
+	password string
+	calls    int
+}
+
+func (a *stubAuthenticator) Source() string {
+	return a.source
+}
+
+func (a *stubAuthenticator) Authenticate(login, password string, user *models.User) (*models.User, error) {
+	a.calls++
+	if password != a.password {
+		return nil, nil
+	}
+	if user == nil {
+		user = &models.User{Email: login, AuthSource: a.source}
+	}
+	return user, nil
+}
+
+func TestAuthService_AuthenticateUsesOwningAuthenticator(t *testing.T) {
+	local := &stubAuthenticator{source: models.AuthSourceLocal, password: "local-password"}
+	ldap := &stubAuthenticator{source: models.AuthSourceLDAP, password: "ldap-password"}
+	service := &AuthService{authenticators: []Authenticator{local, ldap}}
+
+	// A local account cannot be entered with its directory password, and the directory is not asked
+	localUser := &models.User{Email: "local@example.com", AuthSource: models.AuthSourceLocal}
+	if user, _ := service.authenticate(localUser.Email, "ldap-password", localUser); user != nil {
+		t.Error("expected the directory password to be refused for a local account")
+	}
+	if ldap.calls != 0 {
+		t.Error("expected the directory not to be asked about a local account")
+	}
+
+	// A directory account is only checked by the directory
+	ldapUser := &models.User{Email: "ldap@example.com", AuthSource: models.AuthSourceLDAP}
+	if user, _ := service.authenticate(ldapUser.Email, "local-password", ldapUser); user != nil {
+		t.Error("expected the local password to be refused for a directory account")
+	}
+	if user, _ := service.authenticate(ldapUser.Email, "ldap-password", ldapUser); user != ldapUser {
+		t.Error("expected the directory password to be accepted for a directory account")
+	}
+
+	// Unknown logins are offered to every authenticator so the directory can provision them
+	user, _ := service.authenticate("new@example.com", "ldap-password", nil)
+	if user == nil || user.AuthSource != models.AuthSourceLDAP {
+		t.Errorf("expected the directory to provision an unknown login, got %+v", user)
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// Scopes of the login failure counters
+const (
+	loginScopeAccount = "account"
+	loginScopeIP      = "ip"
+)
+
+// TooManyAttemptsError is returned when logins are temporarily refused after repeated failures
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("Too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// loginProtectionSettings holds the brute-force protection limits
+type loginProtectionSettings struct {
+	// backoffThreshold is the number of failures after which each new failure delays the next attempt
+	backoffThreshold int64
+	// backoffBase is the delay after the first failure past the threshold; it doubles with each failure
+	backoffBase time.Duration
+	// maxAccountFailures and maxIPFailures trigger a lockout of lockoutDuration
+	maxAccountFailures int64
+	maxIPFailures      int64
+	lockoutDuration    time.Duration
+	// failureWindow is how long failures are remembered after the last one
+	failureWindow time.Duration
+}
+
+// loginProtectionSettingsFromEnv reads the brute-force protection limits from the environment
+func loginProtectionSettingsFromEnv() loginProtectionSettings {
+	return loginProtectionSettings{
+		backoffThreshold:   intFromEnv("LOGIN_BACKOFF_THRESHOLD", 3),
+		backoffBase:        security.DurationFromEnv("LOGIN_BACKOFF_BASE", time.Second),
+		maxAccountFailures: intFromEnv("LOGIN_MAX_FAILURES", 10),
+		maxIPFailures:      intFromEnv("LOGIN_MAX_IP_FAILURES", 100),
+		lockoutDuration:    security.DurationFromEnv("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+		failureWindow:      security.DurationFromEnv("LOGIN_FAILURE_WINDOW", time.Hour),
+	}
+}
+
+// blockDuration returns how long to refuse logins after the given number of failures
+func (s loginProtectionSettings) blockDuration(failures, maxFailures int64) time.Duration {
+	if failures >= maxFailures {
+		return s.lockoutDuration
+	}
+	if failures < s.backoffThreshold {
+		return 0
+	}
+
+	exponent := float64(failures - s.backoffThreshold)
+	delay := time.Duration(float64(s.backoffBase) * math.Pow(2, exponent))
+	if delay <= 0 || delay > s.lockoutDuration {
+		return s.lockoutDuration
+	}
+	return delay
+}
+
+// LoginProtectionService limits password guessing with per-account and per-IP backoff and lockouts
+type LoginProtectionService struct {
+	loginAttemptRepo *repositories.LoginAttemptRepository
+}
+
+// NewLoginProtectionService creates a new login protection service
+func NewLoginProtectionService(loginAttemptRepo *repositories.LoginAttemptRepository) *LoginProtectionService {
+	return &LoginProtectionService{
+		loginAttemptRepo: loginAttemptRepo,
+	}
+}
+
+// normalizeEmail makes counters and logs independent of the case used to type an address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check refuses a login attempt while the account or the client IP is blocked
+func (s *LoginProtectionService) Check(ctx context.Context, email, ipAddress string) error {
+	scopes := map[string]string{loginScopeAccount: normalizeEmail(email), loginScopeIP: ipAddress}
+
+	var retryAfter time.Duration
+	for scope, key := range scopes {
+		blockedFor, err := s.loginAttemptRepo.BlockedFor(ctx, scope, key)
+		if err != nil {
+			return err
+		}
+		if blockedFor > retryAfter {
+			retryAfter = blockedFor
+		}
+	}
+
+	if retryAfter > 0 {
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login for the account and the client IP, blocking them as limits are reached
+func (s *LoginProtectionService) RecordFailure(ctx context.Context, email, ipAddress string) error {
+	settings := loginProtectionSettingsFromEnv()
+	window := settings.failureWindow
+	if settings.lockoutDuration > window {
+		window = settings.lockoutDuration
+	}
+
+	limits := []struct {
+		scope       string
+		key         string
+		maxFailures int64
+	}{
+		{loginScopeAccount, normalizeEmail(email), settings.maxAccountFailures},
+		{loginScopeIP, ipAddress, settings.maxIPFailures},
+	}
+
+	for _, limit := range limits {
+		failures, err := s.loginAttemptRepo.IncrementFailures(ctx, limit.scope, limit.key, window)
+		if err != nil {
+			return err
+		}
+
+		if duration := settings.blockDuration(failures, limit.maxFailures); duration > 0 {
+			if err := s.loginAttemptRepo.Block(ctx, limit.scope, limit.key, duration); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the failures of an account after a successful login
+func (s *LoginProtectionService) RecordSuccess(ctx context.Context, email string) error {
+	return s.loginAttemptRepo.Reset(ctx, loginScopeAccount, normalizeEmail(email))
+}
+
+// Unlock lifts the lockout of an account
+func (s *LoginProtectionService) Unlock(ctx context.Context, email string) error {
+	return s.loginAttemptRepo.Reset(ctx, loginScopeAccount, normalizeEmail(email))
+}
+
+// LogAttempt records a login attempt for administrators; failures to record are only logged
+func (s *LoginProtectionService) LogAttempt(ctx context.Context, email string, userID *uuid.UUID, client models.ClientInfo, success bool, reason string) {
+	attempt := &models.LoginAttempt{
+		ID:        uuid.New(),
+		RequestID: client.RequestID,
+		Email:     normalizeEmail(email),
+		UserID:    userID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Success:   success,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	}
+
+	if err := s.loginAttemptRepo.Create(ctx, attempt); err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+}
+
+// GetLoginAttempts retrieves the most recent login attempts, optionally only those targeting one email
+func (s *LoginProtectionService) GetLoginAttempts(email string, limit int) ([]*models.LoginAttempt, error) {
+	return s.loginAttemptRepo.GetRecent(context.Background(), normalizeEmail(email), limit)
+}
+
+// intFromEnv parses an integer environment variable, falling back to the default when unset or invalid
+func intFromEnv(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginProtection_BlockDuration(t *testing.T) {
+	settings := loginProtectionSettings{
+		backoffThreshold:   3,
+		backoffBase:        time.Second,
+		maxAccountFailures: 10,
+		lockoutDuration:    30 * time.Minute,
+	}
+
+	cases := []struct {
+		failures int64
+		expected time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{9, 64 * time.Second},
+		{10, 30 * time.Minute},
+		{50, 30 * time.Minute},
+	}
+
+	for _, c := range cases {
+		if got := settings.blockDuration(c.failures, settings.maxAccountFailures); got != c.expected {
+			t.Errorf("after %d failures expected a block of %s, got %s", c.failures, c.expected, got)
+		}
+	}
+
+	// Backoff never exceeds the lockout even below the maximum number of failures
+	settings.maxAccountFailures = 100
+	if got := settings.blockDuration(40, settings.maxAccountFailures); got != settings.lockoutDuration {
+		t.Errorf("expected backoff to be capped at the lockout duration, got %s", got)
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"angular-n-go-template/backend/mailer"
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+)
+
+// Scopes of the magic link request counters
+const (
+	magicLinkScopeAccount = "magic_link_account"
+	magicLinkScopeIP      = "magic_link_ip"
+)
+
+// RateLimitError is returned when a client makes too many requests of one kind
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("Too many requests, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// MagicLinkEnabled reports whether passwordless login links are enabled with MAGIC_LINK_ENABLED
+func MagicLinkEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("MAGIC_LINK_ENABLED"))
+	return enabled
+}
+
+// magicLinkSettings holds the lifetime, browser binding and rate limits of login links
+type magicLinkSettings struct {
+	ttl time.Duration
+	// bindBrowser requires links to be opened in the browser that requested them
+	bindBrowser bool
+	// maxRequests and maxIPRequests limit the links requested for an address and from a client IP per window
+	maxRequests   int64
+	maxIPRequests int64
+	window        time.Duration
+}
+
+// magicLinkSettingsFromEnv reads the login link settings from the environment
+func magicLinkSettingsFromEnv() magicLinkSettings {
+	bindBrowser, err := strconv.ParseBool(os.Getenv("MAGIC_LINK_BIND_BROWSER"))
+	if err != nil {
+		bindBrowser = true
+	}
+
+	return magicLinkSettings{
+		ttl:           security.DurationFromEnv("MAGIC_LINK_EXPIRY", 10*time.Minute),
+		bindBrowser:   bindBrowser,
+		maxRequests:   intFromEnv("MAGIC_LINK_MAX_REQUESTS", 3),
+		maxIPRequests: intFromEnv("MAGIC_LINK_MAX_IP_REQUESTS", 20),
+		window:        security.DurationFromEnv("MAGIC_LINK_RATE_WINDOW", 15*time.Minute),
+	}
+}
+
+// MagicLinkService handles passwordless login with single-use links sent by email
+type MagicLinkService struct {
+	userRepo         *repositories.UserRepository
+	rateLimitRepo    *repositories.RateLimitRepository
+	tokenService     *TokenService
+	mfaService       *MFAService
+	principalService *PrincipalService
+	mailer           mailer.Mailer
+}
+
+// NewMagicLinkService creates a new magic link service
+func NewMagicLinkService(userRepo *repositories.UserRepository, rateLimitRepo *repositories.RateLimitRepository, tokenService *TokenService, mfaService *MFAService, principalService *PrincipalService, mail mailer.Mailer) *MagicLinkService {
+	return &MagicLinkService{
+		userRepo:         userRepo,
+		rateLimitRepo:    rateLimitRepo,
+		tokenService:     tokenService,
+		mfaService:       mfaService,
+		principalService: principalService,
+		mailer:           mail,
+	}
+}
+
+// RequestLink emails a login link if an active local account uses the address and returns the
+// secret the requesting browser must present with the link. As for password resets, the email
+// is sent in the background so neither the response nor its timing reveals whether the address
+// is registered.
+func (s *MagicLinkService) RequestLink(email, ipAddress string) (*models.MagicLinkResponse, error) {
+	ctx := context.Background()
+	settings := magicLinkSettingsFromEnv()
+
+	if err := s.checkRateLimits(ctx, settings, email, ipAddress); err != nil {
+		return nil, err
+	}
+
+	var browserToken, bindingHash string
+	if settings.bindBrowser {
+		var err error
+		browserToken, err = security.GenerateOpaqueToken(32)
+		if err != nil {
+			return nil, err
+		}
+		bindingHash = security.HashToken(browserToken)
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := s.sendLink(ctx, email, bindingHash, settings.ttl); err != nil {
+			log.Printf("Failed to send login link email: %v", err)
+		}
+	}()
+
+	return &models.MagicLinkResponse{
+		Message:      "If an account exists for this email, a login link has been sent",
+		BrowserToken: browserToken,
+		ExpiresIn:    int64(settings.ttl.Seconds()),
+	}, nil
+}
+
+// checkRateLimits counts a link request for the address and the client IP, refusing it past the limits
+func (s *MagicLinkService) checkRateLimits(ctx context.Context, settings magicLinkSettings, email, ipAddress string) error {
+	limits := []struct {
+		scope       string
+		key         string
+		maxRequests int64
+	}{
+		{magicLinkScopeAccount, normalizeEmail(email), settings.maxRequests},
+		{magicLinkScopeIP, ipAddress, settings.maxIPRequests},
+	}
+
+	var retryAfter time.Duration
+	for _, limit := range limits {
+		count, remaining, err := s.rateLimitRepo.Hit(ctx, limit.scope, limit.key, settings.window)
+		if err != nil {
+			return err
+		}
+		if count > limit.maxRequests && remaining > retryAfter {
+			retryAfter = remaining
+		}
+	}
+
+	if retryAfter > 0 {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// sendLink issues a login link token and emails it to the user.
+// Directory users keep logging in with their directory password.
+func (s *MagicLinkService) sendLink(ctx context.Context, email, bindingHash string, ttl time.Duration) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil || !user.IsActive || user.AuthSource != models.AuthSourceLocal {
+		return nil
+	}
+
+	token, err := s.tokenService.IssueMagicLinkToken(ctx, user, bindingHash, ttl)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hello %s,\n\nUse the link below to log in. It expires in %s, can only be used once "+
+			"and must be opened in the browser where you asked for it.\n\n%s\n\n"+
+			"If you did not request this, you can ignore this email.",
+			user.FirstName, ttl, appURL("/magic-link", url.Values{"token": {token}})),
+	})
+}
+
+// CompleteLogin exchanges a login link, together with the secret of the browser that requested it,
+// for a token pair. Users with two-factor authentication still need their second factor.
+func (s *MagicLinkService) CompleteLogin(req *models.ConsumeMagicLinkRequest, client models.ClientInfo) (*LoginResponse, error) {
+	ctx := context.Background()
+
+	claims, err := s.tokenService.ValidateMagicLinkToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the browser before consuming the link so it can still be opened in the right one
+	if !browserMatches(claims.Binding, req.BrowserToken) {
+		return nil, &ValidationError{Message: "Open the login link in the browser where you requested it"}
+	}
+
+	if err := s.tokenService.ConsumeMagicLinkToken(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	// The link was sent to the address the account had at the time
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil || user.Email != claims.Email || user.AuthSource != models.AuthSourceLocal {
+		return nil, &ValidationError{Message: "Invalid or expired login link"}
+	}
+
+	if !user.IsActive {
+		return nil, &ValidationError{Message: "Account is deactivated"}
+	}
+
+	// Opening the link proves the user owns the mailbox, not that they registered the account
+	if !user.IsEmailVerified() {
+		if err := claimUnverifiedAccount(ctx, s.userRepo, s.tokenService, s.principalService, user); err != nil {
+			return nil, err
+		}
+	}
+
+	amr := []string{security.AuthMethodMagicLink}
+
+	if challenge, err := requireSecondFactor(s.mfaService, s.tokenService, user, amr); challenge != nil || err != nil {
+		return challenge, err
+	}
+
+	tokens, err := s.tokenService.IssueTokens(ctx, user, amr, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoginResponse(tokens, user), nil
+}
+
+// browserMatches reports whether the browser token is the one a link was bound to; unbound links match any browser
+func browserMatches(bindingHash, browserToken string) bool {
+	if bindingHash == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(bindingHash), []byte(security.HashToken(browserToken))) == 1
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+func TestBrowserMatches(t *testing.T) {
+	bindingHash := security.HashToken("browser-secret")
+
+	if !browserMatches(bindingHash, "browser-secret") {
+		t.Error("expected the requesting browser to match")
+	}
+	if browserMatches(bindingHash, "other-secret") || browserMatches(bindingHash, "") {
+		t.Error("expected another browser to be refused")
+	}
+	if !browserMatches("", "") {
+		t.Error("expected unbound links to match any browser")
+	}
+}
+
+func TestMagicLinkSettingsFromEnv(t *testing.T) {
+	t.Setenv("MAGIC_LINK_BIND_BROWSER", "")
+	t.Setenv("MAGIC_LINK_EXPIRY", "")
+	if settings := magicLinkSettingsFromEnv(); !settings.bindBrowser || settings.ttl != 10*time.Minute {
+		t.Errorf("expected bound links expiring in 10 minutes by default, got %+v", settings)
+	}
+
+	t.Setenv("MAGIC_LINK_BIND_BROWSER", "false")
+	if magicLinkSettingsFromEnv().bindBrowser {
+		t.Error("expected browser binding to be disabled")
+	}
+}
+
+func TestMagicLinkToken_IsNotAnAccessToken(t *testing.T) {
+	service := &TokenService{}
+	ctx := context.Background()
+
+	linkToken, err := security.GenerateTokenWithTTL(&security.Claims{UserID: uuid.New(), Purpose: security.MagicLinkPurpose}, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := service.ValidateAccessToken(ctx, linkToken); err == nil {
+		t.Error("expected a login link token to be refused as an access token")
+	}
+
+	accessToken, err := security.GenerateToken(&security.Claims{UserID: uuid.New()})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := service.ValidateMagicLinkToken(ctx, accessToken); err == nil {
+		t.Error("expected an access token to be refused as a login link")
+	}
+}
+
+func TestMagicLinkClaim_RemovesSquatterSecondFactor(t *testing.T) {
+	store, revoker := newMemoryAccountStore(), &recordingRevoker{}
+	user := newSquattedAccount(t, "squatter-password")
+
+	// An authenticator app enrolled by whoever registered the account would otherwise stand
+	// between the owner and their account, and an API key would keep them signed in
+	store.totp[user.ID] = true
+	store.apiKeys[user.ID] = []string{"squatter-key"}
+	store.passkeys[user.ID] = []string{"squatter-passkey"}
+
+	// CompleteLogin claims the account once the owner opens the link sent to their mailbox
+	if err := claimUnverifiedAccount(context.Background(), store, revoker, revoker, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if store.totp[user.ID] || len(store.apiKeys[user.ID]) != 0 || len(store.passkeys[user.ID]) != 0 {
+		t.Error("expected the authenticator app, API keys and passkeys of the account to be removed")
+	}
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != user.ID {
+		t.Errorf("expected the sessions of the account to be revoked, got %v", revoker.revoked)
+	}
+}
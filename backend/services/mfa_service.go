@@ -0,0 +1,269 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// recoveryCodeCount is the number of recovery codes generated at a time
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+// MFAService handles TOTP two-factor authentication enrollment and verification
+type MFAService struct {
+	mfaRepo                *repositories.MFARepository
+	userRepo               *repositories.UserRepository
+	webAuthnCredentialRepo *repositories.WebAuthnCredentialRepository
+}
+
+// NewMFAService creates a new MFA service
+func NewMFAService(mfaRepo *repositories.MFARepository, userRepo *repositories.UserRepository, webAuthnCredentialRepo *repositories.WebAuthnCredentialRepository) *MFAService {
+	return &MFAService{
+		mfaRepo:                mfaRepo,
+		userRepo:               userRepo,
+		webAuthnCredentialRepo: webAuthnCredentialRepo,
+	}
+}
+
+// mfaIssuer returns the issuer shown in authenticator apps from MFA_ISSUER
+func mfaIssuer() string {
+	issuer := os.Getenv("MFA_ISSUER")
+	if issuer == "" {
+		issuer = "angular-n-go-template"
+	}
+	return issuer
+}
+
+// IsEnabled reports whether a user has confirmed a TOTP enrollment
+func (s *MFAService) IsEnabled(userID uuid.UUID) (bool, error) {
+	status, err := s.GetStatus(userID)
+	if err != nil {
+		return false, err
+	}
+	return status.Enabled, nil
+}
+
+// Methods lists the second factors protecting the logins of a user: an authenticator app
+// and registered security keys or passkeys. Logins without any complete after the first factor.
+func (s *MFAService) Methods(userID uuid.UUID) ([]string, error) {
+	var methods []string
+
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		methods = append(methods, models.MFAMethodTOTP)
+	}
+
+	hasCredentials, err := s.webAuthnCredentialRepo.Exists(userID)
+	if err != nil {
+		return nil, err
+	}
+	if hasCredentials {
+		methods = append(methods, models.MFAMethodWebAuthn)
+	}
+
+	return methods, nil
+}
+
+// GetStatus returns the two-factor authentication status of a user
+func (s *MFAService) GetStatus(userID uuid.UUID) (*models.MFAStatusResponse, error) {
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if errors.Is(err, repositories.ErrMFANotEnrolled) {
+		return &models.MFAStatusResponse{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.IsEnabled() {
+		return &models.MFAStatusResponse{}, nil
+	}
+
+	remaining, err := s.mfaRepo.CountUnusedRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAStatusResponse{
+		Enabled:                true,
+		EnabledAt:              mfa.EnabledAt,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// Enroll generates a new TOTP secret for a user. The enrollment stays pending until confirmed with a code.
+func (s *MFAService) Enroll(userID uuid.UUID) (*models.MFAEnrollmentResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, &ValidationError{Message: "Two-factor authentication is already enabled"}
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := security.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mfaRepo.Save(&models.UserMFA{
+		UserID:    userID,
+		Secret:    encryptedSecret,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(mfaIssuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication once the user proves their app
+// produces valid codes, and returns the first set of recovery codes
+func (s *MFAService) ConfirmEnrollment(userID uuid.UUID, code string) (*models.MFARecoveryCodesResponse, error) {
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, &ValidationError{Message: "No pending two-factor enrollment"}
+	}
+	if mfa.IsEnabled() {
+		return nil, &ValidationError{Message: "Two-factor authentication is already enabled"}
+	}
+
+	valid, err := s.verifyTOTP(mfa, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, &ValidationError{Message: "Invalid authentication code"}
+	}
+
+	if err := s.mfaRepo.Enable(userID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s.generateRecoveryCodes(userID)
+}
+
+// Disable turns off two-factor authentication after checking a TOTP or recovery code
+func (s *MFAService) Disable(userID uuid.UUID, code string) error {
+	if err := s.requireCode(userID, code); err != nil {
+		return err
+	}
+	return s.mfaRepo.Delete(userID)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of a user after checking a TOTP or recovery code
+func (s *MFAService) RegenerateRecoveryCodes(userID uuid.UUID, code string) (*models.MFARecoveryCodesResponse, error) {
+	if err := s.requireCode(userID, code); err != nil {
+		return nil, err
+	}
+	return s.generateRecoveryCodes(userID)
+}
+
+// VerifyCode checks a TOTP code, or consumes a recovery code, for a user with two-factor authentication enabled
+func (s *MFAService) VerifyCode(userID uuid.UUID, code string) (bool, error) {
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if errors.Is(err, repositories.ErrMFANotEnrolled) {
+		return false, nil
+	}
+	if err != nil || !mfa.IsEnabled() {
+		return false, err
+	}
+
+	valid, err := s.verifyTOTP(mfa, code)
+	if err != nil || valid {
+		return valid, err
+	}
+
+	return s.mfaRepo.UseRecoveryCode(userID, security.HashToken(normalizeRecoveryCode(code)))
+}
+
+// requireCode returns a validation error unless the code is valid for the user
+func (s *MFAService) requireCode(userID uuid.UUID, code string) error {
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return &ValidationError{Message: "Two-factor authentication is not enabled"}
+	}
+
+	valid, err := s.VerifyCode(userID, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return &ValidationError{Message: "Invalid authentication code"}
+	}
+	return nil
+}
+
+// verifyTOTP checks a TOTP code against the enrollment, refusing codes already used
+func (s *MFAService) verifyTOTP(mfa *models.UserMFA, code string) (bool, error) {
+	secret, err := security.DecryptSecret(mfa.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	step, valid := security.ValidateTOTP(secret, code, time.Now())
+	if !valid {
+		return false, nil
+	}
+
+	return s.mfaRepo.UseStep(mfa.UserID, step)
+}
+
+// generateRecoveryCodes creates and stores a new set of recovery codes, returning them in clear text
+func (s *MFAService) generateRecoveryCodes(userID uuid.UUID) (*models.MFARecoveryCodesResponse, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+
+		encoded := recoveryCodeEncoding.EncodeToString(raw)
+		codes[i] = encoded[:4] + "-" + encoded[4:]
+		hashes[i] = security.HashToken(encoded)
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &models.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// normalizeRecoveryCode strips the separators and case users may type in a recovery code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
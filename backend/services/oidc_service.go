@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/oidc"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// OIDCProvidersFromEnv reads the providers listed in OIDC_PROVIDERS (e.g. "google,keycloak").
+// Each provider NAME is configured with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET,
+// and optionally OIDC_<NAME>_DISPLAY_NAME, OIDC_<NAME>_SCOPES and OIDC_<NAME>_REDIRECT_URL.
+func OIDCProvidersFromEnv() []oidc.Config {
+	var configs []oidc.Config
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := oidc.Config{
+			Name:         name,
+			DisplayName:  os.Getenv(prefix + "DISPLAY_NAME"),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if config.Issuer == "" || config.ClientID == "" {
+			log.Printf("Warning: OIDC provider %q is missing %sISSUER or %sCLIENT_ID, skipping it", name, prefix, prefix)
+			continue
+		}
+		if config.RedirectURL == "" {
+			config.RedirectURL = appURL("/oidc/callback/"+name, nil)
+		}
+
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+// OIDCService handles single sign-on with OpenID Connect providers and the identities linked to users
+type OIDCService struct {
+	providers        []*oidc.Provider
+	byName           map[string]*oidc.Provider
+	stateRepo        *repositories.OIDCStateRepository
+	identityRepo     *repositories.UserIdentityRepository
+	userRepo         *repositories.UserRepository
+	tokenService     *TokenService
+	mfaService       *MFAService
+	principalService *PrincipalService
+}
+
+// NewOIDCService creates a new OIDC service for the given providers
+func NewOIDCService(providers []*oidc.Provider, stateRepo *repositories.OIDCStateRepository, identityRepo *repositories.UserIdentityRepository, userRepo *repositories.UserRepository, tokenService *TokenService, mfaService *MFAService, principalService *PrincipalService) *OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &OIDCService{
+		providers:        providers,
+		byName:           byName,
+		stateRepo:        stateRepo,
+		identityRepo:     identityRepo,
+		userRepo:         userRepo,
+		tokenService:     tokenService,
+		mfaService:       mfaService,
+		principalService: principalService,
+	}
+}
+
+// GetProviders lists the configured providers, in configuration order
+func (s *OIDCService) GetProviders() []models.OIDCProvider {
+	providers := make([]models.OIDCProvider, 0, len(s.providers))
+	for _, provider := range s.providers {
+		providers = append(providers, models.OIDCProvider{Name: provider.Name(), DisplayName: provider.DisplayName()})
+	}
+	return providers
+}
+
+// provider returns a configured provider by name
+func (s *OIDCService) provider(name string) (*oidc.Provider, error) {
+	provider, ok := s.byName[name]
+	if !ok {
+		return nil, &ValidationError{Message: "Unknown login provider"}
+	}
+	return provider, nil
+}
+
+// StartLogin begins an authorization code flow with PKCE and returns the provider URL to redirect to.
+// linkUserID is set when a signed-in user links a provider account to their own.
+func (s *OIDCService) StartLogin(providerName string, linkUserID *uuid.UUID) (*models.OIDCAuthorizationResponse, error) {
+	ctx := context.Background()
+
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := security.DurationFromEnv("OIDC_STATE_EXPIRY", 10*time.Minute)
+	err = s.stateRepo.Create(ctx, security.HashToken(state), &models.OIDCState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		LinkUserID:   linkUserID,
+	}, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCAuthorizationResponse{AuthorizationURL: authURL, State: state}, nil
+}
+
+// authenticate consumes the state of a callback, redeems the code and verifies the ID token
+func (s *OIDCService) authenticate(ctx context.Context, providerName string, req *models.OIDCCallbackRequest) (*oidc.IDTokenClaims, *models.OIDCState, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state, err := s.stateRepo.Consume(ctx, security.HashToken(req.State))
+	if err != nil || state.Provider != providerName {
+		return nil, nil, &ValidationError{Message: "Invalid or expired login request"}
+	}
+
+	tokens, err := provider.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC code exchange with %s failed: %v", providerName, err)
+		return nil, nil, &ValidationError{Message: "Login with provider failed"}
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, state.Nonce)
+	if err != nil {
+		log.Printf("OIDC ID token from %s rejected: %v", providerName, err)
+		return nil, nil, &ValidationError{Message: "Login with provider failed"}
+	}
+
+	return claims, state, nil
+}
+
+// CompleteLogin finishes a login started with StartLogin. The provider account is matched to a linked
+// identity, then to a user with the same verified email, and otherwise a new user is created.
+func (s *OIDCService) CompleteLogin(providerName string, req *models.OIDCCallbackRequest, client models.ClientInfo) (*LoginResponse, error) {
+	ctx := context.Background()
+
+	claims, state, err := s.authenticate(ctx, providerName, req)
+	if err != nil {
+		return nil, err
+	}
+	if state.LinkUserID != nil {
+		return nil, &ValidationError{Message: "Invalid or expired login request"}
+	}
+
+	user, err := s.resolveUser(providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, &ValidationError{Message: "Account is deactivated"}
+	}
+
+	amr := []string{security.AuthMethodOIDC}
+
+	// Users with two-factor authentication still need their second factor
+	if challenge, err := requireSecondFactor(s.mfaService, s.tokenService, user, amr); challenge != nil || err != nil {
+		return challenge, err
+	}
+
+	tokens, err := s.tokenService.IssueTokens(ctx, user, amr, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoginResponse(tokens, user), nil
+}
+
+// resolveUser finds or creates the user of a provider account
+func (s *OIDCService) resolveUser(providerName string, claims *oidc.IDTokenClaims) (*models.User, error) {
+	now := time.Now()
+
+	identity, err := s.identityRepo.GetByProviderSubject(providerName, claims.Subject)
+	if err == nil {
+		if err := s.identityRepo.TouchLastLogin(identity.ID, claims.Email, now); err != nil {
+			return nil, err
+		}
+		return s.userRepo.GetByID(identity.UserID)
+	}
+
+	// Matching by email is only safe when the provider has verified the address
+	if !claims.IsEmailVerified() {
+		return nil, &ValidationError{Message: "The provider did not confirm your email address; sign in with your password and link this account from your profile"}
+	}
+
+	user, err := s.userRepo.GetByEmail(claims.Email)
+	if err != nil {
+		if RegistrationMode() == RegistrationInviteOnly {
+			return nil, &ValidationError{Message: "Registration is by invitation only"}
+		}
+		if user, err = s.createUser(claims); err != nil {
+			return nil, err
+		}
+	} else if !user.IsEmailVerified() {
+		// The provider proved ownership of the address, not that its owner registered the account
+		if err := claimUnverifiedAccount(context.Background(), s.userRepo, s.tokenService, s.principalService, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.linkIdentity(user.ID, providerName, claims, &now); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createUser creates a user with the default role from a provider profile.
+// The password is random: the user can set one later with a password reset.
+func (s *OIDCService) createUser(claims *oidc.IDTokenClaims) (*models.User, error) {
+	username, err := availableUsername(s.userRepo, claims.PreferredUsername, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	randomPassword, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := security.HashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+
+	now := time.Now()
+	user := &models.User{
+		ID:              uuid.New(),
+		Email:           claims.Email,
+		Username:        username,
+		Password:        hashedPassword,
+		FirstName:       firstName,
+		LastName:        lastName,
+		Role:            models.DefaultRole,
+		IsActive:        true,
+		AuthSource:      models.AuthSourceLocal,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// linkIdentity records that a provider account belongs to a user
+func (s *OIDCService) linkIdentity(userID uuid.UUID, providerName string, claims *oidc.IDTokenClaims, lastLoginAt *time.Time) error {
+	return s.identityRepo.Create(&models.UserIdentity{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Provider:    providerName,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		CreatedAt:   time.Now(),
+		LastLoginAt: lastLoginAt,
+	})
+}
+
+// CompleteLink finishes linking a provider account started with StartLogin by a signed-in user
+func (s *OIDCService) CompleteLink(providerName string, req *models.OIDCCallbackRequest, userID uuid.UUID) ([]*models.UserIdentity, error) {
+	ctx := context.Background()
+
+	claims, state, err := s.authenticate(ctx, providerName, req)
+	if err != nil {
+		return nil, err
+	}
+	if state.LinkUserID == nil || *state.LinkUserID != userID {
+		return nil, &ValidationError{Message: "Invalid or expired login request"}
+	}
+
+	if identity, err := s.identityRepo.GetByProviderSubject(providerName, claims.Subject); err == nil {
+		if identity.UserID != userID {
+			return nil, &ValidationError{Message: "This provider account is already linked to another user"}
+		}
+		return s.identityRepo.GetByUserID(userID)
+	}
+
+	if err := s.linkIdentity(userID, providerName, claims, nil); err != nil {
+		return nil, err
+	}
+
+	return s.identityRepo.GetByUserID(userID)
+}
+
+// GetIdentities lists the provider accounts linked to a user
+func (s *OIDCService) GetIdentities(userID uuid.UUID) ([]*models.UserIdentity, error) {
+	return s.identityRepo.GetByUserID(userID)
+}
+
+// UnlinkIdentity removes a provider account from a user
+func (s *OIDCService) UnlinkIdentity(userID, identityID uuid.UUID) error {
+	deleted, err := s.identityRepo.Delete(identityID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &ValidationError{Message: "Identity not found"}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestOIDCProvidersFromEnv(t *testing.T) {
+	t.Setenv("APP_BASE_URL", "https://app.example.com/")
+	t.Setenv("OIDC_PROVIDERS", "Google, my-idp ,incomplete")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "google-client")
+	t.Setenv("OIDC_GOOGLE_CLIENT_SECRET", "google-secret")
+	t.Setenv("OIDC_MY_IDP_ISSUER", "https://idp.example.com/realms/main")
+	t.Setenv("OIDC_MY_IDP_CLIENT_ID", "idp-client")
+	t.Setenv("OIDC_MY_IDP_SCOPES", "openid email")
+	t.Setenv("OIDC_MY_IDP_REDIRECT_URL", "https://app.example.com/sso")
+	t.Setenv("OIDC_INCOMPLETE_ISSUER", "https://incomplete.example.com")
+
+	configs := OIDCProvidersFromEnv()
+	if len(configs) != 2 {
+		t.Fatalf("expected 2 complete providers, got %d: %+v", len(configs), configs)
+	}
+
+	google := configs[0]
+	if google.Name != "google" || google.ClientSecret != "google-secret" {
+		t.Errorf("unexpected google provider: %+v", google)
+	}
+	if google.RedirectURL != "https://app.example.com/oidc/callback/google" {
+		t.Errorf("expected default redirect URL, got %q", google.RedirectURL)
+	}
+
+	idp := configs[1]
+	if idp.Name != "my-idp" || idp.RedirectURL != "https://app.example.com/sso" || len(idp.Scopes) != 2 {
+		t.Errorf("unexpected my-idp provider: %+v", idp)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"log"
+	"strings"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// PasswordPolicyService applies the password policy and the password history to new passwords
+type PasswordPolicyService struct {
+	historyRepo *repositories.PasswordHistoryRepository
+}
+
+// NewPasswordPolicyService creates a new password policy service
+func NewPasswordPolicyService(historyRepo *repositories.PasswordHistoryRepository) *PasswordPolicyService {
+	return &PasswordPolicyService{
+		historyRepo: historyRepo,
+	}
+}
+
+// Validate checks a password for a new account against the password policy
+func (s *PasswordPolicyService) Validate(password, username, email string) error {
+	violations := security.CurrentPasswordPolicy().Validate(password, username, email)
+	if len(violations) > 0 {
+		return &ValidationError{Message: "Password " + strings.Join(violations, ", ")}
+	}
+	return nil
+}
+
+// ValidateChange checks a new password for an existing user against the policy and their recent passwords
+func (s *PasswordPolicyService) ValidateChange(user *models.User, password string) error {
+	if err := s.Validate(password, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	historySize := security.CurrentPasswordPolicy().HistorySize
+	if historySize == 0 {
+		return nil
+	}
+
+	previous, err := s.historyRepo.GetRecent(user.ID, historySize)
+	if err != nil {
+		return err
+	}
+	// The current password counts even if it predates the history
+	previous = append(previous, user.Password)
+
+	for _, hash := range previous {
+		reused, _, err := security.VerifyPassword(password, hash)
+		if err == nil && reused {
+			return &ValidationError{Message: "Password was used recently, choose a different one"}
+		}
+	}
+
+	return nil
+}
+
+// Remember adds a newly set password hash to the history of a user; failures to record are only logged
+func (s *PasswordPolicyService) Remember(userID uuid.UUID, passwordHash string) {
+	historySize := security.CurrentPasswordPolicy().HistorySize
+	if historySize == 0 {
+		return
+	}
+	if err := s.historyRepo.Add(userID, passwordHash, historySize); err != nil {
+		log.Printf("Failed to record password history: %v", err)
+	}
+}
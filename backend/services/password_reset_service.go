@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"angular-n-go-template/backend/mailer"
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// passwordResetPurpose scopes password reset tokens in the one-time token store
+const passwordResetPurpose = "password_reset"
+
+// PasswordResetService handles the forgot/reset password flow
+type PasswordResetService struct {
+	userRepo         *repositories.UserRepository
+	oneTimeTokenRepo *repositories.OneTimeTokenRepository
+	tokenService     *TokenService
+	passwordPolicy   *PasswordPolicyService
+	mailer           mailer.Mailer
+}
+
+// NewPasswordResetService creates a new password reset service
+func NewPasswordResetService(userRepo *repositories.UserRepository, oneTimeTokenRepo *repositories.OneTimeTokenRepository, tokenService *TokenService, passwordPolicy *PasswordPolicyService, mail mailer.Mailer) *PasswordResetService {
+	return &PasswordResetService{
+		userRepo:         userRepo,
+		oneTimeTokenRepo: oneTimeTokenRepo,
+		tokenService:     tokenService,
+		passwordPolicy:   passwordPolicy,
+		mailer:           mail,
+	}
+}
+
+// RequestReset emails a password reset link if an active account uses the address.
+// The work happens in the background so neither the result nor the response time
+// reveals whether the email is registered.
+func (s *PasswordResetService) RequestReset(email string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := s.sendResetEmail(ctx, email); err != nil {
+			log.Printf("Failed to send password reset email: %v", err)
+		}
+	}()
+}
+
+// sendResetEmail issues a reset token and emails it to the user
+func (s *PasswordResetService) sendResetEmail(ctx context.Context, email string) error {
+	// Directory users change their password in the directory
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil || !user.IsActive || user.AuthSource != models.AuthSourceLocal {
+		return nil
+	}
+
+	token, err := security.GenerateOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+
+	ttl := security.DurationFromEnv("PASSWORD_RESET_TOKEN_EXPIRY", time.Hour)
+	if err := s.oneTimeTokenRepo.Create(ctx, passwordResetPurpose, security.HashToken(token), user.ID, "", ttl); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nA password reset was requested for your account. "+
+			"Use the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\n"+
+			"If you did not request this, you can ignore this email.",
+			user.FirstName, ttl, appURL("/reset-password", url.Values{"token": {token}})),
+	})
+}
+
+// ResetPassword sets a new password using a reset token and revokes every existing session
+func (s *PasswordResetService) ResetPassword(token, newPassword string) error {
+	ctx := context.Background()
+
+	tokenHash := security.HashToken(token)
+
+	// Check the new password before consuming the token so a rejected password does not waste the link
+	userID, _, err := s.oneTimeTokenRepo.Peek(ctx, passwordResetPurpose, tokenHash)
+	if err != nil {
+		return &ValidationError{Message: "Invalid or expired reset token"}
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || user.AuthSource != models.AuthSourceLocal {
+		return &ValidationError{Message: "Invalid or expired reset token"}
+	}
+
+	if err := s.passwordPolicy.ValidateChange(user, newPassword); err != nil {
+		return err
+	}
+
+	consumedBy, _, err := s.oneTimeTokenRepo.Consume(ctx, passwordResetPurpose, tokenHash)
+	if err != nil || consumedBy != user.ID {
+		return &ValidationError{Message: "Invalid or expired reset token"}
+	}
+
+	hashedPassword, err := security.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	user.Password = hashedPassword
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	s.passwordPolicy.Remember(user.ID, user.Password)
+
+	// A link requested while this one was in use must not change the password again
+	if err := s.RevokeResets(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return s.tokenService.RevokeAllForUser(ctx, user.ID)
+}
+
+// RevokeResets invalidates the pending password reset link of a user, e.g. when their email changes
+func (s *PasswordResetService) RevokeResets(ctx context.Context, userID uuid.UUID) error {
+	return s.oneTimeTokenRepo.Revoke(ctx, passwordResetPurpose, userID)
+}
+
+// appURL builds a link to the frontend application from APP_BASE_URL
+func appURL(path string, query url.Values) string {
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:4200"
+	}
+
+	link := strings.TrimSuffix(baseURL, "/") + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// principalCache stores resolved principals, in Redis outside of tests
+type principalCache interface {
+	Get(ctx context.Context, userID uuid.UUID) (*models.UserPrincipal, error)
+	Set(ctx context.Context, userID uuid.UUID, principal *models.UserPrincipal, ttl time.Duration) error
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
+
+// principalSource loads the account and the role grants a principal is resolved from
+type principalSource interface {
+	GetByID(id uuid.UUID) (*models.User, error)
+	GetActiveByUserID(userID uuid.UUID) ([]*models.UserRole, error)
+}
+
+// principalRepositories loads principals from the user and user role repositories
+type principalRepositories struct {
+	*repositories.UserRepository
+	*repositories.UserRoleRepository
+}
+
+// PrincipalService resolves the current roles and state of users at each request, so that
+// role changes and deactivation apply within seconds instead of when access tokens expire.
+// Principals are cached in Redis for PRINCIPAL_CACHE_TTL and cleared when a user changes.
+type PrincipalService struct {
+	source principalSource
+	cache  principalCache
+	ttl    time.Duration
+}
+
+// NewPrincipalService creates a new principal service
+func NewPrincipalService(userRepo *repositories.UserRepository, userRoleRepo *repositories.UserRoleRepository, cacheRepo *repositories.PrincipalCacheRepository) *PrincipalService {
+	return &PrincipalService{
+		source: principalRepositories{userRepo, userRoleRepo},
+		cache:  cacheRepo,
+		ttl:    security.DurationFromEnv("PRINCIPAL_CACHE_TTL", 30*time.Second),
+	}
+}
+
+// Resolve returns the current principal of a user
+func (s *PrincipalService) Resolve(ctx context.Context, userID uuid.UUID) (*models.UserPrincipal, error) {
+	cached, err := s.cache.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		return cached, nil
+	}
+
+	user, err := s.source.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, err := s.source.GetActiveByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &models.UserPrincipal{
+		IsActive:      user.IsActive,
+		EmailVerified: user.IsEmailVerified(),
+		Role:          user.Role,
+	}
+	ttl := s.ttl
+	for _, grant := range grants {
+		if grant.IsExpired() {
+			continue
+		}
+		principal.Roles = append(principal.Roles, grant.Role)
+
+		// Grants stop applying when they expire, not when the cache does
+		if grant.ExpiresAt != nil && time.Until(*grant.ExpiresAt) < ttl {
+			ttl = time.Until(*grant.ExpiresAt)
+		}
+	}
+
+	if ttl > 0 {
+		if err := s.cache.Set(ctx, user.ID, principal, ttl); err != nil {
+			return nil, err
+		}
+	}
+	return principal, nil
+}
+
+// Apply replaces the roles and email verification state carried by token claims with the current
+// ones of the user. Deactivated and deleted accounts are refused.
+func (s *PrincipalService) Apply(ctx context.Context, claims *security.Claims) (*security.Claims, error) {
+	principal, err := s.Resolve(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, &ValidationError{Message: "Invalid token"}
+		}
+		return nil, err
+	}
+	if !principal.IsActive {
+		return nil, &ValidationError{Message: "Account is deactivated"}
+	}
+
+	current := *claims
+	current.Role = principal.Role
+	current.Roles = principal.Roles
+	current.EmailUnverified = !principal.EmailVerified
+	return &current, nil
+}
+
+// Invalidate clears the cached principal of a user whose account or roles changed
+func (s *PrincipalService) Invalidate(ctx context.Context, userID uuid.UUID) error {
+	return s.cache.Delete(ctx, userID)
+}
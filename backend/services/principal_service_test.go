@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// memoryPrincipalCache is a principal cache recording the lifetime of each entry
+type memoryPrincipalCache struct {
+	principals map[uuid.UUID]*models.UserPrincipal
+	ttls       map[uuid.UUID]time.Duration
+}
+
+func newMemoryPrincipalCache() *memoryPrincipalCache {
+	return &memoryPrincipalCache{principals: map[uuid.UUID]*models.UserPrincipal{}, ttls: map[uuid.UUID]time.Duration{}}
+}
+
+func (c *memoryPrincipalCache) Get(_ context.Context, userID uuid.UUID) (*models.UserPrincipal, error) {
+	return c.principals[userID], nil
+}
+
+func (c *memoryPrincipalCache) Set(_ context.Context, userID uuid.UUID, principal *models.UserPrincipal, ttl time.Duration) error {
+	c.principals[userID], c.ttls[userID] = principal, ttl
+	return nil
+}
+
+func (c *memoryPrincipalCache) Delete(_ context.Context, userID uuid.UUID) error {
+	delete(c.principals, userID)
+	return nil
+}
+
+// memoryPrincipalSource serves users and their grants from memory, counting the users loaded
+type memoryPrincipalSource struct {
+	users  map[uuid.UUID]*models.User
+	grants map[uuid.UUID][]*models.UserRole
+	loads  int
+}
+
+func (s *memoryPrincipalSource) GetByID(id uuid.UUID) (*models.User, error) {
+	s.loads++
+	user, ok := s.users[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (s *memoryPrincipalSource) GetActiveByUserID(userID uuid.UUID) ([]*models.UserRole, error) {
+	return s.grants[userID], nil
+}
+
+func newTestPrincipalService(users ...*models.User) (*PrincipalService, *memoryPrincipalSource, *memoryPrincipalCache) {
+	source := &memoryPrincipalSource{users: map[uuid.UUID]*models.User{}, grants: map[uuid.UUID][]*models.UserRole{}}
+	for _, user := range users {
+		source.users[user.ID] = user
+	}
+	cache := newMemoryPrincipalCache()
+	return &PrincipalService{source: source, cache: cache, ttl: 30 * time.Second}, source, cache
+}
+
+func TestPrincipalService_CachesUntilInvalidated(t *testing.T) {
+	now := time.Now()
+	user := &models.User{ID: uuid.New(), Role: "admin", IsActive: true, EmailVerifiedAt: &now}
+	service, source, _ := newTestPrincipalService(user)
+	ctx := context.Background()
+
+	if _, err := service.Resolve(ctx, user.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Demoting the user is not seen while the principal is cached
+	user.Role = "user"
+	principal, _ := service.Resolve(ctx, user.ID)
+	if source.loads != 1 || principal.Role != "admin" {
+		t.Errorf("expected the cached principal, got role %q after %d loads", principal.Role, source.loads)
+	}
+
+	if err := service.Invalidate(ctx, user.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	principal, _ = service.Resolve(ctx, user.ID)
+	if source.loads != 2 || principal.Role != "user" {
+		t.Errorf("expected the demoted role after invalidation, got %q after %d loads", principal.Role, source.loads)
+	}
+}
+
+func TestPrincipalService_Grants(t *testing.T) {
+	now := time.Now()
+	user := &models.User{ID: uuid.New(), Role: "user", IsActive: true, EmailVerifiedAt: &now}
+	service, source, cache := newTestPrincipalService(user)
+
+	soon, past := now.Add(10*time.Second), now.Add(-time.Minute)
+	source.grants[user.ID] = []*models.UserRole{
+		{UserID: user.ID, Role: "moderator", ExpiresAt: &soon},
+		{UserID: user.ID, Role: "admin", ExpiresAt: &past},
+	}
+
+	principal, err := service.Resolve(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if roles := principal.AllRoles(); len(roles) != 2 || roles[1] != "moderator" {
+		t.Errorf("expected the expired grant to be left out, got %v", roles)
+	}
+
+	// The cache does not outlive the grant that expires first
+	if ttl := cache.ttls[user.ID]; ttl <= 0 || ttl > 10*time.Second {
+		t.Errorf("expected the cache lifetime to end with the grant, got %s", ttl)
+	}
+}
+
+func TestPrincipalService_Apply(t *testing.T) {
+	now := time.Now()
+	active := &models.User{ID: uuid.New(), Role: "user", IsActive: true}
+	inactive := &models.User{ID: uuid.New(), Role: "admin", IsActive: false, EmailVerifiedAt: &now}
+	service, _, _ := newTestPrincipalService(active, inactive)
+	ctx := context.Background()
+
+	// Tokens issued before a demotion or email change carry stale roles and verification state
+	claims, err := service.Apply(ctx, &security.Claims{UserID: active.ID, Role: "admin", Roles: []string{"moderator"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.Role != "user" || len(claims.Roles) != 0 || !claims.EmailUnverified {
+		t.Errorf("expected the current roles and verification state, got %+v", claims)
+	}
+
+	if _, err := service.Apply(ctx, &security.Claims{UserID: inactive.ID, Role: "admin"}); err == nil {
+		t.Error("expected a deactivated user to be rejected")
+	}
+	if _, err := service.Apply(ctx, &security.Claims{UserID: uuid.New(), Role: "admin"}); err == nil {
+		t.Error("expected a deleted user to be rejected")
+	}
+}
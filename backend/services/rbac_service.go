@@ -0,0 +1,391 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/repositories"
+
+	"github.com/lib/pq"
+)
+
+// rbacResyncInterval is how often instances reload stored roles in case a change announcement was missed
+const rbacResyncInterval = time.Minute
+
+var (
+	roleNamePattern       = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	permissionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)*$`)
+)
+
+// RBACService manages the roles and permissions stored in the database. The configuration used
+// for permission checks combines the built-in roles and permissions of the RBAC configuration
+// file with the stored ones; built-in ones take precedence and cannot be changed through the API.
+type RBACService struct {
+	rbacRepo  *repositories.RBACRepository
+	eventRepo *repositories.RBACEventRepository
+	userRepo  *repositories.UserRepository
+	// config is the combined configuration shared with the permission middleware
+	config *rbac.RBACConfig
+
+	// mu serializes rebuilds of the combined configuration
+	mu   sync.Mutex
+	base *rbac.RBACConfig
+	// requiredPermissions must stay defined, as routes depend on them
+	requiredPermissions []string
+}
+
+// NewRBACService creates a new RBAC service on top of the configuration loaded from the RBAC configuration file
+func NewRBACService(base *rbac.RBACConfig, rbacRepo *repositories.RBACRepository, eventRepo *repositories.RBACEventRepository, userRepo *repositories.UserRepository) *RBACService {
+	return &RBACService{
+		rbacRepo:  rbacRepo,
+		eventRepo: eventRepo,
+		userRepo:  userRepo,
+		config:    base.Merge(&rbac.RBACConfig{}),
+		base:      base,
+	}
+}
+
+// Config returns the combined configuration used for permission checks. It is updated in
+// place, so it can be shared once and always reflects the latest roles and permissions.
+func (s *RBACService) Config() *rbac.RBACConfig {
+	return s.config
+}
+
+// Refresh reloads the stored roles and permissions
+func (s *RBACService) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rebuild(s.base, s.requiredPermissions)
+}
+
+// SetBaseConfig replaces the built-in roles and permissions, for example after the RBAC
+// configuration file was edited. The change is refused if it leaves the combined configuration invalid.
+func (s *RBACService) SetBaseConfig(base *rbac.RBACConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rebuild(base, s.requiredPermissions)
+}
+
+// RequirePermissions makes sure the permissions protecting routes are defined, now and after every change
+func (s *RBACService) RequirePermissions(permissions []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rebuild(s.base, permissions)
+}
+
+// rebuild combines the built-in configuration with the stored roles and permissions and
+// puts the result in use if it is valid. The caller must hold s.mu.
+func (s *RBACService) rebuild(base *rbac.RBACConfig, requiredPermissions []string) error {
+	stored, err := s.rbacRepo.Load()
+	if err != nil {
+		return err
+	}
+
+	for name := range stored.Roles {
+		if _, exists := base.GetRole(name); exists {
+			log.Printf("Warning: Stored role %q is ignored, the RBAC configuration file defines it", name)
+		}
+	}
+	for name := range stored.Permissions {
+		if _, exists := base.GetPermission(name); exists {
+			log.Printf("Warning: Stored permission %q is ignored, the RBAC configuration file defines it", name)
+		}
+	}
+
+	merged := base.Merge(stored)
+	if err := merged.Validate(requiredPermissions); err != nil {
+		return err
+	}
+
+	s.config.Replace(merged)
+	s.base = base
+	s.requiredPermissions = requiredPermissions
+	return nil
+}
+
+// WatchChanges reloads the stored roles and permissions whenever an instance announces a
+// change, and periodically in case an announcement was missed, until ctx is done
+func (s *RBACService) WatchChanges(ctx context.Context) {
+	changes := s.eventRepo.SubscribeChanges(ctx)
+	ticker := time.NewTicker(rbacResyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+		}
+
+		if err := s.Refresh(); err != nil {
+			log.Printf("Failed to reload roles and permissions: %v", err)
+		}
+	}
+}
+
+// builtIn returns the roles and permissions of the RBAC configuration file
+func (s *RBACService) builtIn() *rbac.RBACConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.base
+}
+
+// changed applies a change made to the stored roles and permissions and announces it to the other instances
+func (s *RBACService) changed() error {
+	if err := s.Refresh(); err != nil {
+		return err
+	}
+
+	// The other instances catch up at their next periodic reload if the announcement fails
+	if err := s.eventRepo.PublishChange(context.Background()); err != nil {
+		log.Printf("Failed to announce an RBAC change: %v", err)
+	}
+	return nil
+}
+
+// GetRoles lists every role, built-in ones first
+func (s *RBACService) GetRoles() []*models.RoleResponse {
+	base := s.builtIn()
+
+	var roles []*models.RoleResponse
+	for _, role := range s.config.GetAllRoles() {
+		_, builtIn := base.GetRole(role.Name)
+		roles = append(roles, &models.RoleResponse{
+			Role:                 role,
+			BuiltIn:              builtIn,
+			EffectivePermissions: s.config.EffectivePermissions(role.Name),
+		})
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].BuiltIn != roles[j].BuiltIn {
+			return roles[i].BuiltIn
+		}
+		return roles[i].Name < roles[j].Name
+	})
+	return roles
+}
+
+// GetPermissions lists every permission, built-in ones first
+func (s *RBACService) GetPermissions() []*models.PermissionResponse {
+	base := s.builtIn()
+
+	var permissions []*models.PermissionResponse
+	for _, permission := range s.config.GetAllPermissions() {
+		_, builtIn := base.GetPermission(permission.Name)
+		permissions = append(permissions, &models.PermissionResponse{Permission: permission, BuiltIn: builtIn})
+	}
+
+	sort.Slice(permissions, func(i, j int) bool {
+		if permissions[i].BuiltIn != permissions[j].BuiltIn {
+			return permissions[i].BuiltIn
+		}
+		return permissions[i].Name < permissions[j].Name
+	})
+	return permissions
+}
+
+// CreateRole stores a new role
+func (s *RBACService) CreateRole(req *models.CreateRoleRequest) (*rbac.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, &ValidationError{Message: "Role names may only contain lowercase letters, digits, '-' and '_'"}
+	}
+	if _, exists := s.config.GetRole(req.Name); exists {
+		return nil, &ValidationError{Message: "Role already exists"}
+	}
+
+	role, err := s.newRole(req.Name, &req.UpdateRoleRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.rbacRepo.CreateRole(role); err != nil {
+		if isUniqueViolation(err) {
+			return nil, &ValidationError{Message: "Role already exists"}
+		}
+		return nil, err
+	}
+
+	return role, s.changed()
+}
+
+// UpdateRole replaces the description, MFA requirement, parent roles and permissions of a stored role
+func (s *RBACService) UpdateRole(name string, req *models.UpdateRoleRequest) (*rbac.Role, error) {
+	if _, builtIn := s.builtIn().GetRole(name); builtIn {
+		return nil, &ForbiddenError{Message: "Built-in roles can only be changed in the RBAC configuration file"}
+	}
+
+	role, err := s.newRole(name, req)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.rbacRepo.UpdateRole(role)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("role not found")
+	}
+
+	return role, s.changed()
+}
+
+// newRole builds a role from a request and checks that the configuration stays valid with it:
+// permissions must be defined or well-formed wildcards, and parent roles must exist without
+// forming an inheritance cycle
+func (s *RBACService) newRole(name string, req *models.UpdateRoleRequest) (*rbac.Role, error) {
+	role := &rbac.Role{
+		Name:        name,
+		Description: req.Description,
+		Inherits:    uniqueSorted(req.Inherits),
+		Permissions: uniqueSorted(req.Permissions),
+		RequireMFA:  req.RequireMFA,
+	}
+
+	candidate := (&rbac.RBACConfig{Roles: map[string]*rbac.Role{name: role}}).Merge(s.config)
+	if err := candidate.Validate(nil); err != nil {
+		return nil, &ValidationError{Message: err.Error()}
+	}
+	return role, nil
+}
+
+// uniqueSorted returns the distinct values of a list, sorted
+func uniqueSorted(values []string) []string {
+	unique := []string{}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// DeleteRole deletes a stored role that no user has
+func (s *RBACService) DeleteRole(name string) error {
+	if _, builtIn := s.builtIn().GetRole(name); builtIn {
+		return &ForbiddenError{Message: "Built-in roles can only be changed in the RBAC configuration file"}
+	}
+
+	for _, role := range s.config.GetAllRoles() {
+		for _, parent := range role.Inherits {
+			if parent == name {
+				return &ValidationError{Message: fmt.Sprintf("Role is inherited by %s", role.Name)}
+			}
+		}
+	}
+
+	inUse, err := s.userRepo.RoleInUse(name)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return &ValidationError{Message: "Role is assigned to users"}
+	}
+
+	deleted, err := s.rbacRepo.DeleteRole(name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("role not found")
+	}
+
+	return s.changed()
+}
+
+// CreatePermission stores a new permission
+func (s *RBACService) CreatePermission(req *models.CreatePermissionRequest) (*rbac.Permission, error) {
+	if !permissionNamePattern.MatchString(req.Name) {
+		return nil, &ValidationError{Message: "Permission names are dot-separated lowercase words, e.g. reports.read"}
+	}
+	if _, exists := s.config.GetPermission(req.Name); exists {
+		return nil, &ValidationError{Message: "Permission already exists"}
+	}
+
+	permission := newPermission(req.Name, &req.UpdatePermissionRequest)
+	if err := s.rbacRepo.CreatePermission(permission); err != nil {
+		if isUniqueViolation(err) {
+			return nil, &ValidationError{Message: "Permission already exists"}
+		}
+		return nil, err
+	}
+
+	return permission, s.changed()
+}
+
+// UpdatePermission updates the description, resource and action of a stored permission
+func (s *RBACService) UpdatePermission(name string, req *models.UpdatePermissionRequest) (*rbac.Permission, error) {
+	if _, builtIn := s.builtIn().GetPermission(name); builtIn {
+		return nil, &ForbiddenError{Message: "Built-in permissions can only be changed in the RBAC configuration file"}
+	}
+
+	permission := newPermission(name, req)
+	updated, err := s.rbacRepo.UpdatePermission(permission)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("permission not found")
+	}
+
+	return permission, s.changed()
+}
+
+// newPermission builds a permission from a request
+func newPermission(name string, req *models.UpdatePermissionRequest) *rbac.Permission {
+	return &rbac.Permission{
+		Name:        name,
+		Description: req.Description,
+		Resource:    req.Resource,
+		Action:      req.Action,
+	}
+}
+
+// DeletePermission deletes a stored permission and removes it from the roles granting it
+func (s *RBACService) DeletePermission(name string) error {
+	if _, builtIn := s.builtIn().GetPermission(name); builtIn {
+		return &ForbiddenError{Message: "Built-in permissions can only be changed in the RBAC configuration file"}
+	}
+
+	s.mu.Lock()
+	required := s.requiredPermissions
+	s.mu.Unlock()
+	for _, permission := range required {
+		if permission == name {
+			return &ValidationError{Message: "Permission protects routes and cannot be deleted"}
+		}
+	}
+
+	deleted, err := s.rbacRepo.DeletePermission(name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("permission not found")
+	}
+
+	return s.changed()
+}
+
+// isUniqueViolation reports whether a database error is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestRBACNamePatterns(t *testing.T) {
+	for _, name := range []string{"auditor", "support-agent", "level_2"} {
+		if !roleNamePattern.MatchString(name) {
+			t.Errorf("expected role name %q to be accepted", name)
+		}
+	}
+	for _, name := range []string{"", "Auditor", "2fa", "support agent", "admin.*"} {
+		if roleNamePattern.MatchString(name) {
+			t.Errorf("expected role name %q to be refused", name)
+		}
+	}
+
+	for _, name := range []string{"reports.read", "admin.reports.export", "billing"} {
+		if !permissionNamePattern.MatchString(name) {
+			t.Errorf("expected permission name %q to be accepted", name)
+		}
+	}
+	for _, name := range []string{"", "reports.", ".read", "reports..read", "reports.*", "Reports.Read"} {
+		if permissionNamePattern.MatchString(name) {
+			t.Errorf("expected permission name %q to be refused", name)
+		}
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	if !isUniqueViolation(&pq.Error{Code: "23505"}) {
+		t.Error("expected a unique violation to be detected")
+	}
+	if isUniqueViolation(&pq.Error{Code: "23503"}) || isUniqueViolation(errors.New("role not found")) {
+		t.Error("expected other errors not to be unique violations")
+	}
+}
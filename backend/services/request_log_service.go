@@ -28,6 +28,7 @@ func (s *RequestLogService) LogRequest(ctx context.Context, req *models.CreateRe
 		Method:       req.Method,
 		Path:         req.Path,
 		UserID:       req.UserID,
+		ActorID:      req.ActorID,
 		IPAddress:    req.IPAddress,
 		UserAgent:    req.UserAgent,
 		StatusCode:   req.StatusCode,
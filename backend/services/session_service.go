@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// SessionService lets users and administrators review and revoke login sessions
+type SessionService struct {
+	sessionRepo *repositories.SessionRepository
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(sessionRepo *repositories.SessionRepository) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+	}
+}
+
+// GetSessions lists the active sessions of a user, most recently used first.
+// currentSessionID marks the session making the request, if any.
+func (s *SessionService) GetSessions(userID uuid.UUID, currentSessionID string) ([]*models.Session, error) {
+	sessions, err := s.sessionRepo.GetByUserID(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession signs a user out of one session
+func (s *SessionService) RevokeSession(userID uuid.UUID, sessionID string) error {
+	deleted, err := s.sessionRepo.Delete(context.Background(), sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &ValidationError{Message: "Session not found"}
+	}
+	return nil
+}
+
+// RevokeOtherSessions signs a user out of every session but the current one and returns how many were revoked
+func (s *SessionService) RevokeOtherSessions(userID uuid.UUID, currentSessionID string) (int, error) {
+	ctx := context.Background()
+
+	sessions, err := s.sessionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+		deleted, err := s.sessionRepo.Delete(ctx, session.ID, userID)
+		if err != nil {
+			return revoked, err
+		}
+		if deleted {
+			revoked++
+		}
+	}
+
+	return revoked, nil
+}
+
+// RevokeAllSessions signs a user out of every session
+func (s *SessionService) RevokeAllSessions(userID uuid.UUID) error {
+	return s.sessionRepo.DeleteAllForUser(context.Background(), userID)
+}
+
+// deviceName returns the label of a new session: the name chosen by the client, or one guessed from its user agent
+func deviceName(client models.ClientInfo) string {
+	if name := strings.TrimSpace(client.DeviceName); name != "" {
+		return name
+	}
+	return describeUserAgent(client.UserAgent)
+}
+
+// describeUserAgent turns a user agent into a short "Browser on Platform" label
+func describeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	// Order matters: Edge and Opera announce themselves as Chrome, and Chrome as Safari
+	browsers := []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	platforms := []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+
+	browser := ""
+	for _, candidate := range browsers {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+	platform := ""
+	for _, candidate := range platforms {
+		if strings.Contains(userAgent, candidate.token) {
+			platform = candidate.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	default:
+		return "Unknown device"
+	}
+}
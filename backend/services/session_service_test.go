@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+
+	"angular-n-go-template/backend/models"
+)
+
+func TestDeviceName(t *testing.T) {
+	cases := []struct {
+		client   models.ClientInfo
+		expected string
+	}{
+		{models.ClientInfo{DeviceName: "  Work laptop "}, "Work laptop"},
+		{models.ClientInfo{UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0"}, "Edge on Windows"},
+		{models.ClientInfo{UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15"}, "Safari on macOS"},
+		{models.ClientInfo{UserAgent: "Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"}, "Chrome on Android"},
+		{models.ClientInfo{UserAgent: "curl/8.4.0"}, "curl"},
+		{models.ClientInfo{}, "Unknown device"},
+	}
+
+	for _, c := range cases {
+		if got := deviceName(c.client); got != c.expected {
+			t.Errorf("expected %q for %+v, got %q", c.expected, c.client, got)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// refreshTokenBytes is the amount of entropy in an opaque refresh token
+const refreshTokenBytes = 32
+
+// sessionTouchInterval limits how often the last activity of a session is written
+const sessionTouchInterval = time.Minute
+
+// maxMFAAttempts is the number of wrong codes accepted for one MFA pending token before it is revoked
+const maxMFAAttempts = 5
+
+// TokenService handles issuing, rotating and revoking access and refresh tokens
+type TokenService struct {
+	refreshTokenRepo    *repositories.RefreshTokenRepository
+	tokenRevocationRepo *repositories.TokenRevocationRepository
+	sessionRepo         *repositories.SessionRepository
+	userRoleRepo        *repositories.UserRoleRepository
+}
+
+// NewTokenService creates a new token service
+func NewTokenService(refreshTokenRepo *repositories.RefreshTokenRepository, tokenRevocationRepo *repositories.TokenRevocationRepository, sessionRepo *repositories.SessionRepository, userRoleRepo *repositories.UserRoleRepository) *TokenService {
+	return &TokenService{
+		refreshTokenRepo:    refreshTokenRepo,
+		tokenRevocationRepo: tokenRevocationRepo,
+		sessionRepo:         sessionRepo,
+		userRoleRepo:        userRoleRepo,
+	}
+}
+
+// TokenPair represents an access token together with its refresh token
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+}
+
+// IssueTokens starts a new session for the user on the client's device and returns its first token pair.
+// amr lists the authentication methods the user completed to log in.
+func (s *TokenService) IssueTokens(ctx context.Context, user *models.User, amr []string, client models.ClientInfo) (*TokenPair, error) {
+	now := time.Now()
+	session := &models.Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		DeviceName: deviceName(client),
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := s.sessionRepo.Create(ctx, session, security.RefreshTokenTTL()); err != nil {
+		return nil, err
+	}
+
+	return s.issuePair(ctx, user, session.ID, amr)
+}
+
+// RotateRefreshToken consumes a refresh token and returns the record it belonged to.
+// Presenting a token that was already used revokes its whole family, since it means
+// the token has been replayed by someone other than the legitimate client.
+func (s *TokenService) RotateRefreshToken(ctx context.Context, refreshToken string) (*models.RefreshToken, error) {
+	tokenHash := security.HashToken(refreshToken)
+
+	token, err := s.refreshTokenRepo.GetByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid refresh token"}
+	}
+
+	active, err := s.sessionRepo.Exists(ctx, token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, &ValidationError{Message: "Invalid refresh token"}
+	}
+
+	firstUse, err := s.refreshTokenRepo.MarkUsed(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, repositories.ErrRefreshTokenNotFound) {
+			return nil, &ValidationError{Message: "Invalid refresh token"}
+		}
+		return nil, err
+	}
+	if !firstUse {
+		if _, err := s.sessionRepo.Delete(ctx, token.FamilyID, token.UserID); err != nil {
+			return nil, err
+		}
+		return nil, &ValidationError{Message: "Refresh token reuse detected"}
+	}
+
+	return token, nil
+}
+
+// ContinueFamily issues the next token pair of the session of a successfully rotated refresh token
+func (s *TokenService) ContinueFamily(ctx context.Context, user *models.User, previous *models.RefreshToken, client models.ClientInfo) (*TokenPair, error) {
+	active, err := s.sessionRepo.Refresh(ctx, previous.FamilyID, client.IPAddress, client.UserAgent, time.Now(), security.RefreshTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, &ValidationError{Message: "Invalid refresh token"}
+	}
+
+	return s.issuePair(ctx, user, previous.FamilyID, previous.AMR)
+}
+
+// issuePair creates a new refresh token in the family and a matching access token
+func (s *TokenService) issuePair(ctx context.Context, user *models.User, familyID string, amr []string) (*TokenPair, error) {
+	refreshToken, err := security.GenerateOpaqueToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &models.RefreshToken{
+		TokenHash: security.HashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		AMR:       amr,
+		CreatedAt: now,
+		ExpiresAt: now.Add(security.RefreshTokenTTL()),
+	}
+	if err := s.refreshTokenRepo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+
+	generation, err := s.tokenRevocationRepo.GetGeneration(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, ttl, err := s.grantedRoles(user.ID, security.AccessTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := security.GenerateTokenWithTTL(&security.Claims{
+		UserID:     user.ID,
+		Email:      user.Email,
+		Username:   user.Username,
+		Role:       user.Role,
+		Roles:      roles,
+		SessionID:  familyID,
+		Generation: generation,
+
+		EmailUnverified: !user.IsEmailVerified(),
+		AMR:             amr,
+	}, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(ttl.Seconds()),
+	}, nil
+}
+
+// grantedRoles returns the active roles granted to a user on top of the role of their account.
+// ttl is shortened so that a token carrying them expires no later than the first of the grants.
+func (s *TokenService) grantedRoles(userID uuid.UUID, ttl time.Duration) ([]string, time.Duration, error) {
+	grants, err := s.userRoleRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var roles []string
+	for _, grant := range grants {
+		roles = append(roles, grant.Role)
+		if grant.ExpiresAt != nil {
+			if remaining := time.Until(*grant.ExpiresAt); remaining < ttl {
+				ttl = remaining
+			}
+		}
+	}
+	return roles, ttl, nil
+}
+
+// ValidateAccessToken verifies an access token and checks that it has not been revoked
+func (s *TokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*security.Claims, error) {
+	claims, err := security.ValidateToken(tokenString)
+	if err != nil || claims.Purpose != "" {
+		return nil, &ValidationError{Message: "Invalid token"}
+	}
+
+	revoked, err := s.tokenRevocationRepo.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, &ValidationError{Message: "Token has been revoked"}
+	}
+
+	generation, err := s.tokenRevocationRepo.GetGeneration(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Generation < generation {
+		return nil, &ValidationError{Message: "Token has been revoked"}
+	}
+
+	// Access tokens die with the session they were issued for
+	if claims.SessionID != "" {
+		active, err := s.sessionRepo.Touch(ctx, claims.SessionID, time.Now(), sessionTouchInterval)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, &ValidationError{Message: "Session has been revoked"}
+		}
+	}
+
+	// Impersonation tokens die with the session of the administrator who started them
+	if claims.Actor != nil {
+		active, err := s.sessionRepo.Exists(ctx, claims.Actor.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, &ValidationError{Message: "Session has been revoked"}
+		}
+	}
+
+	return claims, nil
+}
+
+// IssueImpersonationToken signs an access token for the target user carrying the actor's identity,
+// returning it with its lifetime, which is shorter than ttl when a role granted to the target expires sooner.
+// It has no refresh token and is not bound to a session of the target, so it cannot be extended.
+func (s *TokenService) IssueImpersonationToken(ctx context.Context, actor *security.Claims, target *models.User, ttl time.Duration) (string, time.Duration, error) {
+	generation, err := s.tokenRevocationRepo.GetGeneration(ctx, target.ID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	roles, ttl, err := s.grantedRoles(target.ID, ttl)
+	if err != nil {
+		return "", 0, err
+	}
+
+	token, err := security.GenerateTokenWithTTL(&security.Claims{
+		UserID:     target.ID,
+		Email:      target.Email,
+		Username:   target.Username,
+		Role:       target.Role,
+		Roles:      roles,
+		Generation: generation,
+
+		EmailUnverified: !target.IsEmailVerified(),
+		// The administrator's own authentication methods apply, e.g. for roles requiring MFA
+		AMR: append([]string{}, actor.AMR...),
+		Actor: &security.Actor{
+			UserID:    actor.UserID,
+			Email:     actor.Email,
+			Username:  actor.Username,
+			Role:      actor.Role,
+			SessionID: actor.SessionID,
+		},
+	}, ttl)
+	return token, ttl, err
+}
+
+// RevokeSession revokes an access token and the session it belongs to
+func (s *TokenService) RevokeSession(ctx context.Context, claims *security.Claims) error {
+	if err := s.tokenRevocationRepo.RevokeToken(ctx, claims.ID, claims.RemainingLifetime()); err != nil {
+		return err
+	}
+
+	if claims.SessionID == "" {
+		return nil
+	}
+	_, err := s.sessionRepo.Delete(ctx, claims.SessionID, claims.UserID)
+	return err
+}
+
+// RevokeAllForUser invalidates every outstanding access and refresh token of a user
+func (s *TokenService) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.tokenRevocationRepo.IncrementGeneration(ctx, userID); err != nil {
+		return err
+	}
+	return s.sessionRepo.DeleteAllForUser(ctx, userID)
+}
+
+// IssueMFAToken signs the short-lived token a user exchanges, together with a second factor, for a token pair.
+// amr lists the authentication methods of the first factor.
+func (s *TokenService) IssueMFAToken(user *models.User, amr []string) (string, error) {
+	return security.GenerateTokenWithTTL(&security.Claims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		Role:     user.Role,
+		AMR:      amr,
+		Purpose:  security.MFAPendingPurpose,
+	}, security.DurationFromEnv("MFA_TOKEN_EXPIRY", 5*time.Minute))
+}
+
+// ValidateMFAToken verifies an MFA pending token that has not been used or revoked yet
+func (s *TokenService) ValidateMFAToken(ctx context.Context, tokenString string) (*security.Claims, error) {
+	claims, err := security.ValidateToken(tokenString)
+	if err != nil || claims.Purpose != security.MFAPendingPurpose {
+		return nil, &ValidationError{Message: "Invalid or expired MFA token"}
+	}
+
+	revoked, err := s.tokenRevocationRepo.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, &ValidationError{Message: "Invalid or expired MFA token"}
+	}
+
+	return claims, nil
+}
+
+// RecordFailedMFAAttempt counts a wrong code submitted with an MFA pending token,
+// revoking the token once too many attempts have been made
+func (s *TokenService) RecordFailedMFAAttempt(ctx context.Context, claims *security.Claims) error {
+	attempts, err := s.tokenRevocationRepo.IncrementAttempts(ctx, claims.ID, claims.RemainingLifetime())
+	if err != nil {
+		return err
+	}
+	if attempts >= maxMFAAttempts {
+		return s.tokenRevocationRepo.RevokeToken(ctx, claims.ID, claims.RemainingLifetime())
+	}
+	return nil
+}
+
+// ConsumeMFAToken marks an MFA pending token as used once the login has been completed,
+// failing if it already was so that concurrent completions cannot both issue tokens
+func (s *TokenService) ConsumeMFAToken(ctx context.Context, claims *security.Claims) error {
+	consumed, err := s.tokenRevocationRepo.ConsumeToken(ctx, claims.ID, claims.RemainingLifetime())
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return &ValidationError{Message: "Invalid or expired MFA token"}
+	}
+	return nil
+}
+
+// IssueMagicLinkToken signs the single-use token emailed to a user to log in without a password.
+// bindingHash ties the token to the browser that requested it; an empty hash leaves it unbound.
+func (s *TokenService) IssueMagicLinkToken(ctx context.Context, user *models.User, bindingHash string, ttl time.Duration) (string, error) {
+	generation, err := s.tokenRevocationRepo.GetGeneration(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return security.GenerateTokenWithTTL(&security.Claims{
+		UserID:     user.ID,
+		Email:      user.Email,
+		Username:   user.Username,
+		Role:       user.Role,
+		Generation: generation,
+		Purpose:    security.MagicLinkPurpose,
+		Binding:    bindingHash,
+	}, ttl)
+}
+
+// ValidateMagicLinkToken verifies a magic link token that has not been used yet and was issued
+// after the user's last "log out everywhere" or password change
+func (s *TokenService) ValidateMagicLinkToken(ctx context.Context, tokenString string) (*security.Claims, error) {
+	claims, err := security.ValidateToken(tokenString)
+	if err != nil || claims.Purpose != security.MagicLinkPurpose {
+		return nil, &ValidationError{Message: "Invalid or expired login link"}
+	}
+
+	revoked, err := s.tokenRevocationRepo.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, &ValidationError{Message: "Invalid or expired login link"}
+	}
+
+	generation, err := s.tokenRevocationRepo.GetGeneration(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Generation < generation {
+		return nil, &ValidationError{Message: "Invalid or expired login link"}
+	}
+
+	return claims, nil
+}
+
+// ConsumeMagicLinkToken marks a magic link token as used, failing if it already was
+func (s *TokenService) ConsumeMagicLinkToken(ctx context.Context, claims *security.Claims) error {
+	consumed, err := s.tokenRevocationRepo.ConsumeToken(ctx, claims.ID, claims.RemainingLifetime())
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return &ValidationError{Message: "Invalid or expired login link"}
+	}
+	return nil
+}
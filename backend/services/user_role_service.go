@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+// roleGrantCleanupInterval is how often expired role grants are deleted
+const roleGrantCleanupInterval = time.Hour
+
+// UserRoleService lets administrators grant roles to users on top of the role of their account,
+// permanently or until an expiry date
+type UserRoleService struct {
+	userRoleRepo     *repositories.UserRoleRepository
+	userRepo         *repositories.UserRepository
+	principalService *PrincipalService
+	rbacConfig       *rbac.RBACConfig
+}
+
+// NewUserRoleService creates a new user role service
+func NewUserRoleService(userRoleRepo *repositories.UserRoleRepository, userRepo *repositories.UserRepository, principalService *PrincipalService, rbacConfig *rbac.RBACConfig) *UserRoleService {
+	return &UserRoleService{
+		userRoleRepo:     userRoleRepo,
+		userRepo:         userRepo,
+		principalService: principalService,
+		rbacConfig:       rbacConfig,
+	}
+}
+
+// grantedRoleNames returns the names of the active roles granted to a user on top of the role of their account
+func grantedRoleNames(userRoleRepo *repositories.UserRoleRepository, userID uuid.UUID) ([]string, error) {
+	grants, err := userRoleRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []string
+	for _, grant := range grants {
+		roles = append(roles, grant.Role)
+	}
+	return roles, nil
+}
+
+// ActiveRoles returns every active role of a user, the role of their account first.
+// It is used by policy conditions, so unknown users simply have no role.
+func (s *UserRoleService) ActiveRoles(userID string) ([]string, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, nil
+	}
+
+	principal, err := s.principalService.Resolve(context.Background(), id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return principal.AllRoles(), nil
+}
+
+// GetRoles returns the role of a user's account and the active roles granted on top of it
+func (s *UserRoleService) GetRoles(userID uuid.UUID) (*models.UserRolesResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, err := s.userRoleRepo.GetActiveByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserRolesResponse{Role: user.Role, Grants: grants}, nil
+}
+
+// GrantRole gives a role to a user, until req.ExpiresAt when set. Granting a role the user already
+// has through a grant replaces its expiry. Administrators can only grant roles whose permissions
+// their own roles grant.
+func (s *UserRoleService) GrantRole(actor *security.Claims, userID uuid.UUID, req *models.GrantRoleRequest) (*models.UserRole, error) {
+	if _, exists := s.rbacConfig.GetRole(req.Role); !exists {
+		return nil, &ValidationError{Message: "Unknown role: " + req.Role}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, &ValidationError{Message: "Expiry must be in the future"}
+	}
+	if !s.rbacConfig.CanActOnRoles(actor.AllRoles(), []string{req.Role}) {
+		return nil, &ForbiddenError{Message: "Cannot grant a role with more privileges than your own"}
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == req.Role {
+		return nil, &ValidationError{Message: "The account already has this role"}
+	}
+
+	grant := &models.UserRole{
+		UserID:    user.ID,
+		Role:      req.Role,
+		GrantedBy: &actor.UserID,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.userRoleRepo.Grant(grant); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Role grant: %s (%s) granted %s to %s (%s)", actor.Email, actor.UserID, req.Role, user.Email, user.ID)
+
+	// The role applies to the user's next request
+	if err := s.principalService.Invalidate(context.Background(), user.ID); err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+// RevokeRole removes a role granted to a user. The role stops applying from the user's next request.
+func (s *UserRoleService) RevokeRole(actor *security.Claims, userID uuid.UUID, role string) error {
+	// Grants of roles that no longer exist can always be cleaned up
+	if _, exists := s.rbacConfig.GetRole(role); exists && !s.rbacConfig.CanActOnRoles(actor.AllRoles(), []string{role}) {
+		return &ForbiddenError{Message: "Cannot revoke a role with more privileges than your own"}
+	}
+
+	revoked, err := s.userRoleRepo.Revoke(userID, role)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("role grant not found")
+	}
+
+	log.Printf("Role grant: %s (%s) revoked %s from %s", actor.Email, actor.UserID, role, userID)
+
+	return s.principalService.Invalidate(context.Background(), userID)
+}
+
+// WatchExpiredGrants periodically deletes the role grants that have expired, until ctx is done.
+// Expired grants already give no role, as they are left out when resolving the roles of users.
+func (s *UserRoleService) WatchExpiredGrants(ctx context.Context) {
+	ticker := time.NewTicker(roleGrantCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.userRoleRepo.DeleteExpired(time.Now())
+		if err != nil {
+			log.Printf("Failed to delete expired role grants: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Deleted %d expired role grants", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
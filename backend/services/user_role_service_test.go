@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+func TestGrantRole_RefusesInvalidGrants(t *testing.T) {
+	service := NewUserRoleService(nil, nil, nil, rbac.DefaultRBACConfig())
+	past := time.Now().Add(-time.Hour)
+
+	moderator := &security.Claims{UserID: uuid.New(), Role: "user", Roles: []string{"moderator"}}
+	admin := &security.Claims{UserID: uuid.New(), Role: "admin"}
+
+	cases := map[string]struct {
+		actor     *security.Claims
+		req       *models.GrantRoleRequest
+		forbidden bool
+	}{
+		"unknown role":   {admin, &models.GrantRoleRequest{Role: "auditor"}, false},
+		"expired":        {admin, &models.GrantRoleRequest{Role: "moderator", ExpiresAt: &past}, false},
+		"more privilege": {moderator, &models.GrantRoleRequest{Role: "admin"}, true},
+	}
+
+	for name, tc := range cases {
+		_, err := service.GrantRole(tc.actor, uuid.New(), tc.req)
+		_, forbidden := err.(*ForbiddenError)
+		_, invalid := err.(*ValidationError)
+		if forbidden != tc.forbidden || invalid == tc.forbidden {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestRevokeRole_RefusesMorePrivilegedRoles(t *testing.T) {
+	service := NewUserRoleService(nil, nil, nil, rbac.DefaultRBACConfig())
+	moderator := &security.Claims{UserID: uuid.New(), Role: "moderator"}
+
+	if _, ok := service.RevokeRole(moderator, uuid.New(), "admin").(*ForbiddenError); !ok {
+		t.Error("expected a moderator to be refused revoking the admin role")
+	}
+}
@@ -1,27 +1,61 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
 	"angular-n-go-template/backend/repositories"
 	"angular-n-go-template/backend/security"
 
 	"github.com/google/uuid"
 )
 
+// usernameInvalidCharacters matches the characters removed when deriving a username from an external profile
+var usernameInvalidCharacters = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
 // UserService handles user business logic
 type UserService struct {
-	userRepo *repositories.UserRepository
+	userRepo                 *repositories.UserRepository
+	emailVerificationService *EmailVerificationService
+	passwordResetService     *PasswordResetService
+	passwordPolicy           *PasswordPolicyService
+	tokenService             *TokenService
+	principalService         *PrincipalService
+	rbacConfig               *rbac.RBACConfig
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo *repositories.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo *repositories.UserRepository, emailVerificationService *EmailVerificationService, passwordResetService *PasswordResetService, passwordPolicy *PasswordPolicyService, tokenService *TokenService, principalService *PrincipalService, rbacConfig *rbac.RBACConfig) *UserService {
+	return &UserService{
+		userRepo:                 userRepo,
+		emailVerificationService: emailVerificationService,
+		passwordResetService:     passwordResetService,
+		passwordPolicy:           passwordPolicy,
+		tokenService:             tokenService,
+		principalService:         principalService,
+		rbacConfig:               rbacConfig,
+	}
 }
 
-// CreateUser creates a new user
-func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.UserResponse, error) {
+// CreateUser creates a new user on behalf of an administrator, with the requested role.
+// Administrators can only give roles whose permissions their own roles grant.
+func (s *UserService) CreateUser(actor *security.Claims, req *models.CreateUserRequest) (*models.UserResponse, error) {
+	role := req.Role
+	if role == "" {
+		role = models.DefaultRole
+	}
+	if _, exists := s.rbacConfig.GetRole(role); !exists {
+		return nil, &ValidationError{Message: "Unknown role: " + role}
+	}
+	if !s.rbacConfig.CanActOnRoles(actor.AllRoles(), []string{role}) {
+		return nil, &ForbiddenError{Message: "Cannot create a user with more privileges than your own"}
+	}
+
 	// Check if email already exists
 	emailExists, err := s.userRepo.EmailExists(req.Email)
 	if err != nil {
@@ -40,6 +74,10 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.UserRes
 		return nil, &ValidationError{Message: "Username already exists"}
 	}
 
+	if err := s.passwordPolicy.Validate(req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := security.HashPassword(req.Password)
 	if err != nil {
@@ -48,21 +86,27 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.UserRes
 
 	// Create user
 	user := &models.User{
-		ID:        uuid.New(),
-		Email:     req.Email,
-		Username:  req.Username,
-		Password:  hashedPassword,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		IsActive:  true,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:         uuid.New(),
+		Email:      req.Email,
+		Username:   req.Username,
+		Password:   hashedPassword,
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		Role:       role,
+		IsActive:   true,
+		AuthSource: models.AuthSourceLocal,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
 
 	err = s.userRepo.Create(user)
 	if err != nil {
 		return nil, err
 	}
+	s.passwordPolicy.Remember(user.ID, user.Password)
+
+	// The administrator chose the address, so its owner still has to confirm it
+	s.emailVerificationService.SendVerification(user)
 
 	response := user.ToResponse()
 	return &response, nil
@@ -97,12 +141,18 @@ func (s *UserService) GetUsers(limit, offset int) ([]*models.UserResponse, error
 
 // UpdateUser updates a user
 func (s *UserService) UpdateUser(id uuid.UUID, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+	// Roles are granted and revoked by administrators through their own routes
+	if req.Role != nil {
+		return nil, &ValidationError{Message: "Roles cannot be changed here, use /api/v1/admin/users/:id/roles"}
+	}
+
 	user, err := s.userRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update fields if provided
+	emailChanged := false
 	if req.Email != nil {
 		// Check if new email already exists
 		emailExists, err := s.userRepo.EmailExists(*req.Email)
@@ -112,6 +162,11 @@ func (s *UserService) UpdateUser(id uuid.UUID, req *models.UpdateUserRequest) (*
 		if emailExists && *req.Email != user.Email {
 			return nil, &ValidationError{Message: "Email already exists"}
 		}
+		if *req.Email != user.Email {
+			// A new address has to be verified again
+			emailChanged = true
+			user.EmailVerifiedAt = nil
+		}
 		user.Email = *req.Email
 	}
 
@@ -146,13 +201,120 @@ func (s *UserService) UpdateUser(id uuid.UUID, req *models.UpdateUserRequest) (*
 		return nil, err
 	}
 
+	// Deactivation and email changes apply from the user's next request
+	if err := s.principalService.Invalidate(context.Background(), user.ID); err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		// Links sent to the previous address must not verify the new one nor reset the password
+		if err := s.emailVerificationService.RevokeVerification(context.Background(), user.ID); err != nil {
+			return nil, err
+		}
+		if err := s.passwordResetService.RevokeResets(context.Background(), user.ID); err != nil {
+			return nil, err
+		}
+		s.emailVerificationService.SendVerification(user)
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// UpdateProfile updates the fields users may change on their own account
+func (s *UserService) UpdateProfile(id uuid.UUID, req *models.UpdateProfileRequest) (*models.UserResponse, error) {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		// Check if new username already exists
+		usernameExists, err := s.userRepo.UsernameExists(*req.Username)
+		if err != nil {
+			return nil, err
+		}
+		if usernameExists {
+			return nil, &ValidationError{Message: "Username already exists"}
+		}
+		user.Username = *req.Username
+	}
+
+	if req.FirstName != nil {
+		user.FirstName = *req.FirstName
+	}
+
+	if req.LastName != nil {
+		user.LastName = *req.LastName
+	}
+
+	user.UpdatedAt = time.Now()
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
 	response := user.ToResponse()
 	return &response, nil
 }
 
+// ChangePassword replaces the password of a user who knows the current one.
+// Every existing session is revoked and a fresh token pair is returned for the caller's device.
+func (s *UserService) ChangePassword(id uuid.UUID, req *models.ChangePasswordRequest, amr []string, client models.ClientInfo) (*LoginResponse, error) {
+	ctx := context.Background()
+
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.AuthSource != models.AuthSourceLocal {
+		return nil, &ValidationError{Message: "Your password is managed by your organization's directory"}
+	}
+
+	valid, _, err := security.VerifyPassword(req.CurrentPassword, user.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, &ValidationError{Message: "Current password is incorrect"}
+	}
+
+	if err := s.passwordPolicy.ValidateChange(user, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := security.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = hashedPassword
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	s.passwordPolicy.Remember(user.ID, user.Password)
+
+	// Sign out other devices, which may be in the hands of whoever knew the old password
+	if err := s.tokenService.RevokeAllForUser(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokenService.IssueTokens(ctx, user, amr, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoginResponse(tokens, user), nil
+}
+
 // DeleteUser deletes a user
 func (s *UserService) DeleteUser(id uuid.UUID) error {
-	return s.userRepo.Delete(id)
+	if err := s.userRepo.Delete(id); err != nil {
+		return err
+	}
+	return s.principalService.Invalidate(context.Background(), id)
 }
 
 // ValidationError represents a validation error
@@ -164,8 +326,46 @@ func (e *ValidationError) Error() string {
 	return e.Message
 }
 
+// ForbiddenError is returned when the caller is not allowed to act on the requested resource
+type ForbiddenError struct {
+	Message string
+}
 
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
 
+// availableUsername derives an unused username for an account provisioned from an external
+// profile, from its preferred username or the local part of its email
+func availableUsername(userRepo *repositories.UserRepository, preferred, email string) (string, error) {
+	base := preferred
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = usernameInvalidCharacters.ReplaceAllString(base, "")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
 
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		exists, err := userRepo.UsernameExists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
 
+		suffix, err := security.GenerateOpaqueToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + usernameInvalidCharacters.ReplaceAllString(suffix, "")
+	}
 
+	return "", fmt.Errorf("could not find an available username for %q", base)
+}
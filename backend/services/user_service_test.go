@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/rbac"
+	"angular-n-go-template/backend/security"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateUser_RefusesMorePrivilegedRoles(t *testing.T) {
+	service := NewUserService(nil, nil, nil, nil, nil, nil, rbac.DefaultRBACConfig())
+	moderator := &security.Claims{UserID: uuid.New(), Role: "moderator"}
+
+	req := &models.CreateUserRequest{Email: "new@example.com", Username: "newuser", Password: "a-long-password", Role: "admin"}
+	_, err := service.CreateUser(moderator, req)
+	if _, ok := err.(*ForbiddenError); !ok {
+		t.Errorf("expected a moderator to be refused creating an admin, got %v", err)
+	}
+}
+
+func TestUpdateUser_RefusesRoleChanges(t *testing.T) {
+	service := NewUserService(nil, nil, nil, nil, nil, nil, rbac.DefaultRBACConfig())
+	role := "admin"
+
+	// The role used to be silently ignored, answering 200 without granting anything
+	_, err := service.UpdateUser(uuid.New(), &models.UpdateUserRequest{Role: &role})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected a role change to be refused, got %v", err)
+	}
+}
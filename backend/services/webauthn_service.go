@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"angular-n-go-template/backend/models"
+	"angular-n-go-template/backend/repositories"
+	"angular-n-go-template/backend/security"
+	"angular-n-go-template/backend/webauthn"
+
+	"github.com/google/uuid"
+)
+
+// webAuthnChallengeBytes is the amount of entropy in a ceremony challenge
+const webAuthnChallengeBytes = 32
+
+// WebAuthnConfigFromEnv reads the relying party configuration.
+// WEBAUTHN_RP_ID and WEBAUTHN_ORIGINS default to the host and origin of APP_BASE_URL.
+func WebAuthnConfigFromEnv() webauthn.Config {
+	var appHost, appOrigin string
+	if parsed, err := url.Parse(appURL("", nil)); err == nil {
+		appHost = parsed.Hostname()
+		appOrigin = parsed.Scheme + "://" + parsed.Host
+	}
+
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		rpID = appHost
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{appOrigin}
+	}
+
+	rpName := os.Getenv("WEBAUTHN_RP_NAME")
+	if rpName == "" {
+		rpName = mfaIssuer()
+	}
+
+	return webauthn.Config{
+		ID:      rpID,
+		Name:    rpName,
+		Origins: origins,
+		Timeout: security.DurationFromEnv("WEBAUTHN_CHALLENGE_EXPIRY", 5*time.Minute),
+	}
+}
+
+// WebAuthnService handles security key and passkey registration, passwordless login and second factor ceremonies
+type WebAuthnService struct {
+	relyingParty    *webauthn.RelyingParty
+	timeout         time.Duration
+	credentialRepo  *repositories.WebAuthnCredentialRepository
+	challengeRepo   *repositories.WebAuthnChallengeRepository
+	userRepo        *repositories.UserRepository
+	tokenService    *TokenService
+	loginProtection *LoginProtectionService
+}
+
+// NewWebAuthnService creates a new WebAuthn service
+func NewWebAuthnService(config webauthn.Config, credentialRepo *repositories.WebAuthnCredentialRepository, challengeRepo *repositories.WebAuthnChallengeRepository, userRepo *repositories.UserRepository, tokenService *TokenService, loginProtection *LoginProtectionService) *WebAuthnService {
+	return &WebAuthnService{
+		relyingParty:    webauthn.NewRelyingParty(config),
+		timeout:         config.Timeout,
+		credentialRepo:  credentialRepo,
+		challengeRepo:   challengeRepo,
+		userRepo:        userRepo,
+		tokenService:    tokenService,
+		loginProtection: loginProtection,
+	}
+}
+
+// newChallenge stores the state of a ceremony under a fresh random challenge and returns the challenge
+func (s *WebAuthnService) newChallenge(ctx context.Context, state *models.WebAuthnChallenge) ([]byte, error) {
+	challenge := make([]byte, webAuthnChallengeBytes)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	state.Challenge = challenge
+	if err := s.challengeRepo.Create(ctx, security.HashToken(webauthn.EncodeBase64(challenge)), state, s.timeout); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// consumeChallenge returns the state of the ceremony a response answers; each challenge can only be answered once
+func (s *WebAuthnService) consumeChallenge(ctx context.Context, clientDataJSON, ceremony string) (*models.WebAuthnChallenge, error) {
+	clientData, _, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid security key response"}
+	}
+
+	state, err := s.challengeRepo.Consume(ctx, security.HashToken(clientData.Challenge))
+	if err != nil || state.Ceremony != ceremony {
+		return nil, &ValidationError{Message: "Invalid or expired security key challenge"}
+	}
+	return state, nil
+}
+
+// BeginRegistration starts registering a new security key or passkey for a user
+func (s *WebAuthnService) BeginRegistration(userID uuid.UUID) (*webauthn.CreationOptions, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.credentialRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([]webauthn.CredentialDescriptor, len(credentials))
+	for i, credential := range credentials {
+		exclude[i] = credential.Descriptor()
+	}
+
+	challenge, err := s.newChallenge(context.Background(), &models.WebAuthnChallenge{
+		Ceremony: models.WebAuthnRegistration,
+		UserID:   &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The user handle is the user ID; it comes back with passkey logins to identify the account
+	return s.relyingParty.CreationOptions(challenge, webauthn.UserEntity{
+		ID:          webauthn.EncodeBase64(userID[:]),
+		Name:        user.Email,
+		DisplayName: displayName(user),
+	}, exclude), nil
+}
+
+// FinishRegistration verifies the authenticator's response and stores the new credential
+func (s *WebAuthnService) FinishRegistration(userID uuid.UUID, req *models.WebAuthnRegistrationRequest) (*models.WebAuthnCredential, error) {
+	state, err := s.consumeChallenge(context.Background(), req.Credential.Response.ClientDataJSON, models.WebAuthnRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if state.UserID == nil || *state.UserID != userID {
+		return nil, &ValidationError{Message: "Invalid or expired security key challenge"}
+	}
+
+	verified, err := s.relyingParty.VerifyRegistration(&req.Credential, state.Challenge, false)
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid security key response"}
+	}
+
+	if _, err := s.credentialRepo.GetByCredentialID(verified.ID); err == nil {
+		return nil, &ValidationError{Message: "This security key is already registered"}
+	}
+
+	transports := verified.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	credential := &models.WebAuthnCredential{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Name:           req.Name,
+		CredentialID:   verified.ID,
+		PublicKey:      verified.PublicKey,
+		SignCount:      verified.SignCount,
+		AAGUID:         verified.AAGUID,
+		Transports:     transports,
+		BackupEligible: verified.BackupEligible,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := s.credentialRepo.Create(credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// GetCredentials lists the security keys and passkeys of a user
+func (s *WebAuthnService) GetCredentials(userID uuid.UUID) ([]*models.WebAuthnCredential, error) {
+	return s.credentialRepo.GetByUserID(userID)
+}
+
+// DeleteCredential removes a security key or passkey of a user
+func (s *WebAuthnService) DeleteCredential(userID, id uuid.UUID) error {
+	deleted, err := s.credentialRepo.Delete(id, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &ValidationError{Message: "Security key not found"}
+	}
+	return nil
+}
+
+// BeginLogin starts a passwordless login; the user picks one of the passkeys stored for this site
+func (s *WebAuthnService) BeginLogin() (*webauthn.RequestOptions, error) {
+	challenge, err := s.newChallenge(context.Background(), &models.WebAuthnChallenge{Ceremony: models.WebAuthnLogin})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.relyingParty.RequestOptions(challenge, nil, webauthn.UserVerificationRequired), nil
+}
+
+// CompleteLogin logs a user in with a passkey. The authenticator must verify the user with a PIN or
+// biometric, so the login counts as multi-factor and no other second factor is asked.
+func (s *WebAuthnService) CompleteLogin(req *models.WebAuthnLoginRequest, client models.ClientInfo) (*LoginResponse, error) {
+	ctx := context.Background()
+
+	state, err := s.consumeChallenge(ctx, req.Credential.Response.ClientDataJSON, models.WebAuthnLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.verifyAssertion(&req.Credential, state.Challenge, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Directory users keep logging in with their directory password
+	user, err := s.userRepo.GetByID(credential.UserID)
+	if err != nil || user.AuthSource != models.AuthSourceLocal {
+		return nil, &ValidationError{Message: "Passkey login is not available for this account"}
+	}
+
+	if !user.IsActive {
+		return nil, &ValidationError{Message: "Account is deactivated"}
+	}
+
+	if err := checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokenService.IssueTokens(ctx, user, []string{security.AuthMethodWebAuthn, security.AuthMethodMFA}, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoginResponse(tokens, user), nil
+}
+
+// BeginMFA starts a second factor ceremony with one of the security keys of the user behind an MFA pending token
+func (s *WebAuthnService) BeginMFA(req *models.WebAuthnMFAStartRequest) (*webauthn.RequestOptions, error) {
+	ctx := context.Background()
+
+	claims, err := s.tokenService.ValidateMFAToken(ctx, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.credentialRepo.GetByUserID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) == 0 {
+		return nil, &ValidationError{Message: "No security key is registered"}
+	}
+	allow := make([]webauthn.CredentialDescriptor, len(credentials))
+	for i, credential := range credentials {
+		allow[i] = credential.Descriptor()
+	}
+
+	challenge, err := s.newChallenge(ctx, &models.WebAuthnChallenge{
+		Ceremony:   models.WebAuthnMFA,
+		UserID:     &claims.UserID,
+		MFATokenID: claims.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.relyingParty.RequestOptions(challenge, allow, webauthn.UserVerificationDiscouraged), nil
+}
+
+// CompleteMFALogin finishes a two-step login with the MFA token from the first step and a security key
+func (s *WebAuthnService) CompleteMFALogin(req *models.WebAuthnMFALoginRequest, client models.ClientInfo) (*LoginResponse, error) {
+	ctx := context.Background()
+
+	claims, err := s.tokenService.ValidateMFAToken(ctx, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.consumeChallenge(ctx, req.Credential.Response.ClientDataJSON, models.WebAuthnMFA)
+	if err != nil {
+		return nil, err
+	}
+	if state.MFATokenID != claims.ID || state.UserID == nil || *state.UserID != claims.UserID {
+		return nil, &ValidationError{Message: "Invalid or expired security key challenge"}
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid or expired MFA token"}
+	}
+
+	if !user.IsActive {
+		return nil, &ValidationError{Message: "Account is deactivated"}
+	}
+
+	if _, err := s.verifyAssertion(&req.Credential, state.Challenge, &user.ID, false); err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			if err := s.tokenService.RecordFailedMFAAttempt(ctx, claims); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.tokenService.ConsumeMFAToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	if err := s.loginProtection.RecordSuccess(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	// Keep the first factor used before the MFA step (password, single sign-on or login link)
+	amr := append(append([]string{}, claims.AMR...), security.AuthMethodWebAuthn, security.AuthMethodMFA)
+	tokens, err := s.tokenService.IssueTokens(ctx, user, amr, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoginResponse(tokens, user), nil
+}
+
+// verifyAssertion checks an assertion against the stored credential it was made with and records the new
+// signature counter. userID restricts the credentials accepted to those of one user when set.
+func (s *WebAuthnService) verifyAssertion(response *webauthn.AuthenticationResponse, challenge []byte, userID *uuid.UUID, requireUserVerification bool) (*models.WebAuthnCredential, error) {
+	credentialID, err := webauthn.DecodeBase64(response.RawID)
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid security key response"}
+	}
+
+	credential, err := s.credentialRepo.GetByCredentialID(credentialID)
+	if err != nil || (userID != nil && credential.UserID != *userID) {
+		return nil, &ValidationError{Message: "Unknown security key"}
+	}
+
+	assertion, err := s.relyingParty.VerifyAssertion(response, challenge, credential.Credential(), requireUserVerification)
+	if errors.Is(err, webauthn.ErrSignCountRegression) {
+		return nil, s.refuseClonedCredential(credential)
+	}
+	if err != nil {
+		return nil, &ValidationError{Message: "Invalid security key response"}
+	}
+
+	// Discoverable credentials return the user ID they were registered for
+	if assertion.UserHandle != nil && string(assertion.UserHandle) != string(credential.UserID[:]) {
+		return nil, &ValidationError{Message: "Invalid security key response"}
+	}
+
+	updated, err := s.credentialRepo.UpdateSignCount(credential.ID, assertion.SignCount, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, s.refuseClonedCredential(credential)
+	}
+	return credential, nil
+}
+
+// refuseClonedCredential logs and reports an assertion whose signature counter did not increase
+func (s *WebAuthnService) refuseClonedCredential(credential *models.WebAuthnCredential) error {
+	log.Printf("Warning: Signature counter of WebAuthn credential %s did not increase, the authenticator may have been cloned", credential.ID)
+	return &ValidationError{Message: "This security key may have been cloned and was refused"}
+}
+
+// displayName returns the name shown for a user by authenticators
+func displayName(user *models.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Username
+}
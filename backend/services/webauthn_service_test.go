@@ -0,0 +1,41 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"angular-n-go-template/backend/models"
+)
+
+func TestWebAuthnConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_BASE_URL", "https://app.example.com:8443/portal/")
+	t.Setenv("WEBAUTHN_RP_ID", "")
+	t.Setenv("WEBAUTHN_ORIGINS", "")
+
+	config := WebAuthnConfigFromEnv()
+	if config.ID != "app.example.com" {
+		t.Errorf("expected the relying party ID to default to the app host, got %q", config.ID)
+	}
+	if !reflect.DeepEqual(config.Origins, []string{"https://app.example.com:8443"}) {
+		t.Errorf("expected the origins to default to the app origin, got %v", config.Origins)
+	}
+
+	t.Setenv("WEBAUTHN_RP_ID", "example.com")
+	t.Setenv("WEBAUTHN_ORIGINS", "https://app.example.com, https://admin.example.com,")
+	config = WebAuthnConfigFromEnv()
+	if config.ID != "example.com" {
+		t.Errorf("expected the configured relying party ID, got %q", config.ID)
+	}
+	if !reflect.DeepEqual(config.Origins, []string{"https://app.example.com", "https://admin.example.com"}) {
+		t.Errorf("expected the configured origins, got %v", config.Origins)
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	if name := displayName(&models.User{Username: "jdoe", FirstName: "Jane", LastName: "Doe"}); name != "Jane Doe" {
+		t.Errorf("expected the full name, got %q", name)
+	}
+	if name := displayName(&models.User{Username: "jdoe"}); name != "jdoe" {
+		t.Errorf("expected the username without a name, got %q", name)
+	}
+}
@@ -0,0 +1,86 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent       = 0x01
+	flagUserVerified      = 0x04
+	flagBackupEligible    = 0x08
+	flagBackedUp          = 0x10
+	flagAttestedData      = 0x40
+	flagExtensionDataSent = 0x80
+)
+
+// maxCredentialIDLength is the longest credential ID allowed by the specification
+const maxCredentialIDLength = 1023
+
+// authenticatorData is the data signed by the authenticator during both ceremonies
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// Attested credential data, only present after a registration
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthenticatorData decodes authenticator data; the extensions, if any, are checked to be valid CBOR and ignored
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+
+	parsed := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if parsed.has(flagAttestedData) {
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		parsed.aaguid = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > maxCredentialIDLength || len(rest) < idLength {
+			return nil, errors.New("invalid credential ID length")
+		}
+		parsed.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		parsed.publicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if parsed.has(flagExtensionDataSent) {
+		extensions, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := extensions.(map[interface{}]interface{}); !ok {
+			return nil, errors.New("invalid extension data")
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) > 0 {
+		return nil, errors.New("unexpected data after authenticator data")
+	}
+	return parsed, nil
+}
+
+// has reports whether a flag is set
+func (d *authenticatorData) has(flag byte) bool {
+	return d.flags&flag != 0
+}
@@ -0,0 +1,47 @@
+package webauthn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// {1: 2, 3: -7, "a": h'0102', "b": [true, null]}
+	data := []byte{0xa4, 0x01, 0x02, 0x03, 0x26, 0x61, 'a', 0x42, 0x01, 0x02, 0x61, 'b', 0x82, 0xf5, 0xf6}
+
+	decoded, n, err := decodeCBOR(data)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	want := map[interface{}]interface{}{
+		int64(1): int64(2),
+		int64(3): int64(-7),
+		"a":      []byte{1, 2},
+		"b":      []interface{}{true, nil},
+	}
+	if n != len(data) || !reflect.DeepEqual(decoded, want) {
+		t.Errorf("decodeCBOR() = %#v (%d bytes), want %#v", decoded, n, want)
+	}
+}
+
+func TestDecodeCBOR_RejectsMalformedData(t *testing.T) {
+	cases := map[string][]byte{
+		"truncated string":  {0x45, 0x01},
+		"huge array":        {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"indefinite length": {0x5f},
+		"float":             {0xfa, 0x00, 0x00, 0x00, 0x00},
+		"duplicate map key": {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"array map key":     {0xa1, 0x80, 0x01},
+		"integer overflow":  {0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"empty input":       {},
+		"nested beyond limit": {
+			0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x00,
+		},
+	}
+
+	for name, data := range cases {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
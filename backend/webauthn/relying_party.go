@@ -0,0 +1,395 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Client data types of the two ceremonies
+const (
+	clientDataCreate = "webauthn.create"
+	clientDataGet    = "webauthn.get"
+)
+
+// User verification requirements
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+// ErrSignCountRegression is returned when an assertion's signature counter did not increase,
+// which suggests the authenticator has been cloned
+var ErrSignCountRegression = errors.New("signature counter did not increase")
+
+// Config describes the relying party
+type Config struct {
+	// ID is the relying party ID, the registrable domain credentials are scoped to (e.g. "example.com")
+	ID   string
+	Name string
+	// Origins lists the origins allowed to run the ceremonies (e.g. "https://app.example.com")
+	Origins []string
+	// Timeout is the time given to the user to complete a ceremony
+	Timeout time.Duration
+}
+
+// RelyingParty runs the server side of WebAuthn registration and authentication ceremonies
+type RelyingParty struct {
+	config   Config
+	rpIDHash [32]byte
+}
+
+// NewRelyingParty creates a relying party; the timeout defaults to 5 minutes
+func NewRelyingParty(config Config) *RelyingParty {
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Minute
+	}
+	return &RelyingParty{config: config, rpIDHash: sha256.Sum256([]byte(config.ID))}
+}
+
+// EncodeBase64 encodes binary values the way WebAuthn JSON serializations do (base64url without padding)
+func EncodeBase64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeBase64 decodes a base64url value, with or without padding
+func DecodeBase64(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// RPEntity identifies the relying party to the authenticator
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity identifies the user account a credential is created for
+type UserEntity struct {
+	// ID is the user handle, an opaque identifier of at most 64 bytes (base64url)
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameter is an accepted credential type and algorithm
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor identifies an existing credential
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection states the requirements on the authenticator creating a credential
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are the options passed to navigator.credentials.create (PublicKeyCredentialCreationOptionsJSON)
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RPEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the options passed to navigator.credentials.get (PublicKeyCredentialRequestOptionsJSON)
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the response of the authenticator to a registration
+type AttestationResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+	AttestationObject string   `json:"attestationObject" binding:"required"`
+	Transports        []string `json:"transports,omitempty"`
+}
+
+// RegistrationResponse is the credential returned by navigator.credentials.create, serialized with toJSON()
+type RegistrationResponse struct {
+	ID       string              `json:"id"`
+	RawID    string              `json:"rawId" binding:"required"`
+	Type     string              `json:"type"`
+	Response AttestationResponse `json:"response"`
+}
+
+// AssertionResponse is the response of the authenticator to an authentication
+type AssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData string `json:"authenticatorData" binding:"required"`
+	Signature         string `json:"signature" binding:"required"`
+	UserHandle        string `json:"userHandle,omitempty"`
+}
+
+// AuthenticationResponse is the credential returned by navigator.credentials.get, serialized with toJSON()
+type AuthenticationResponse struct {
+	ID       string            `json:"id"`
+	RawID    string            `json:"rawId" binding:"required"`
+	Type     string            `json:"type"`
+	Response AssertionResponse `json:"response"`
+}
+
+// ClientData is the client data collected by the browser for a ceremony
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+// ParseClientData decodes the base64url clientDataJSON of a response, e.g. to find the challenge it answers
+func ParseClientData(clientDataJSON string) (*ClientData, []byte, error) {
+	raw, err := DecodeBase64(clientDataJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid client data: %w", err)
+	}
+
+	clientData := &ClientData{}
+	if err := json.Unmarshal(raw, clientData); err != nil {
+		return nil, nil, fmt.Errorf("invalid client data: %w", err)
+	}
+	return clientData, raw, nil
+}
+
+// Credential is a verified credential to store after a registration
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE_Key of the credential
+	PublicKey      []byte
+	SignCount      uint32
+	AAGUID         []byte
+	Transports     []string
+	UserVerified   bool
+	BackupEligible bool
+}
+
+// Assertion is the result of a verified authentication
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+	// UserHandle is the user ID stored with a discoverable credential, if the authenticator returned it
+	UserHandle []byte
+}
+
+// CreationOptions returns the options of a registration ceremony for a user.
+// Credentials the user already registered are excluded so an authenticator is not registered twice.
+func (rp *RelyingParty) CreationOptions(challenge []byte, user UserEntity, exclude []CredentialDescriptor) *CreationOptions {
+	params := make([]CredentialParameter, len(SupportedAlgorithms))
+	for i, alg := range SupportedAlgorithms {
+		params[i] = CredentialParameter{Type: "public-key", Alg: alg}
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		Challenge:          EncodeBase64(challenge),
+		RP:                 RPEntity{ID: rp.config.ID, Name: rp.config.Name},
+		User:               user,
+		PubKeyCredParams:   params,
+		Timeout:            rp.config.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		// Discoverable credentials allow logging in without typing an email (passkeys)
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: UserVerificationPreferred,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options of an authentication ceremony.
+// An empty allow list lets the user pick any discoverable credential for this relying party.
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow []CredentialDescriptor, userVerification string) *RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+
+	return &RequestOptions{
+		Challenge:        EncodeBase64(challenge),
+		Timeout:          rp.config.Timeout.Milliseconds(),
+		RPID:             rp.config.ID,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// VerifyRegistration checks the response to a registration ceremony started with challenge and returns the new credential.
+// Attestation statements are not evaluated: credentials are trusted on first use, as with "none" attestation.
+func (rp *RelyingParty) VerifyRegistration(response *RegistrationResponse, challenge []byte, requireUserVerification bool) (*Credential, error) {
+	if _, _, err := rp.verifyClientData(response.Response.ClientDataJSON, clientDataCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	attestationObject, err := DecodeBase64(response.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	decoded, n, err := decodeCBOR(attestationObject)
+	if err != nil || n != len(attestationObject) {
+		return nil, errors.New("invalid attestation object")
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid attestation object")
+	}
+
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if format == "" || statement == nil || rawAuthData == nil {
+		return nil, errors.New("invalid attestation object")
+	}
+	if format == "none" && len(statement) != 0 {
+		return nil, errors.New("unexpected attestation statement")
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+	if !authData.has(flagAttestedData) {
+		return nil, errors.New("missing attested credential data")
+	}
+
+	rawID, err := DecodeBase64(response.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.credentialID) {
+		return nil, errors.New("credential ID mismatch")
+	}
+
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             append([]byte{}, authData.credentialID...),
+		PublicKey:      append([]byte{}, authData.publicKey...),
+		SignCount:      authData.signCount,
+		AAGUID:         append([]byte{}, authData.aaguid...),
+		Transports:     response.Response.Transports,
+		UserVerified:   authData.has(flagUserVerified),
+		BackupEligible: authData.has(flagBackupEligible),
+	}, nil
+}
+
+// VerifyAssertion checks the response to an authentication ceremony started with challenge against a stored credential.
+// The signature counter must increase unless the authenticator does not implement one (always zero).
+func (rp *RelyingParty) VerifyAssertion(response *AuthenticationResponse, challenge []byte, credential *Credential, requireUserVerification bool) (*Assertion, error) {
+	rawID, err := DecodeBase64(response.RawID)
+	if err != nil || !bytes.Equal(rawID, credential.ID) {
+		return nil, errors.New("credential ID mismatch")
+	}
+
+	_, clientDataJSON, err := rp.verifyClientData(response.Response.ClientDataJSON, clientDataGet, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	rawAuthData, err := DecodeBase64(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authenticator data: %w", err)
+	}
+	authData, err := rp.verifyAuthenticatorData(rawAuthData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := DecodeBase64(response.Response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if !key.verify(append(append([]byte{}, rawAuthData...), clientDataHash[:]...), signature) {
+		return nil, errors.New("invalid signature")
+	}
+
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return nil, ErrSignCountRegression
+	}
+
+	var userHandle []byte
+	if response.Response.UserHandle != "" {
+		userHandle, err = DecodeBase64(response.Response.UserHandle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user handle: %w", err)
+		}
+	}
+
+	return &Assertion{
+		SignCount:    authData.signCount,
+		UserVerified: authData.has(flagUserVerified),
+		UserHandle:   userHandle,
+	}, nil
+}
+
+// verifyClientData checks the type, challenge and origin of the client data and returns it with its raw JSON
+func (rp *RelyingParty) verifyClientData(encoded, ceremony string, challenge []byte) (*ClientData, []byte, error) {
+	clientData, raw, err := ParseClientData(encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if clientData.Type != ceremony {
+		return nil, nil, fmt.Errorf("unexpected client data type %q", clientData.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(EncodeBase64(challenge))) != 1 {
+		return nil, nil, errors.New("challenge mismatch")
+	}
+	if clientData.CrossOrigin || !rp.allowedOrigin(clientData.Origin) {
+		return nil, nil, fmt.Errorf("origin %q is not allowed", clientData.Origin)
+	}
+	return clientData, raw, nil
+}
+
+// verifyAuthenticatorData checks that the data is scoped to this relying party and that the user was present
+func (rp *RelyingParty) verifyAuthenticatorData(raw []byte, requireUserVerification bool) (*authenticatorData, error) {
+	authData, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rp.rpIDHash[:]) != 1 {
+		return nil, errors.New("relying party ID mismatch")
+	}
+	if !authData.has(flagUserPresent) {
+		return nil, errors.New("user was not present")
+	}
+	if requireUserVerification && !authData.has(flagUserVerified) {
+		return nil, errors.New("user was not verified")
+	}
+	return authData, nil
+}
+
+// allowedOrigin reports whether a ceremony may run on an origin
+func (rp *RelyingParty) allowedOrigin(origin string) bool {
+	for _, allowed := range rp.config.Origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
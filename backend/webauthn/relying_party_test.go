@@ -0,0 +1,146 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+
+	"angular-n-go-template/backend/webauthn"
+	"angular-n-go-template/backend/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:4200"
+)
+
+var testUser = webauthn.UserEntity{ID: webauthn.EncodeBase64([]byte("user-handle")), Name: "jane@example.com", DisplayName: "Jane Doe"}
+
+func newTestRelyingParty() *webauthn.RelyingParty {
+	return webauthn.NewRelyingParty(webauthn.Config{ID: testRPID, Name: "Test", Origins: []string{testOrigin}})
+}
+
+// register runs a registration ceremony with the authenticator and returns the stored credential
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	challenge := []byte("registration-challenge-0123456789")
+	response, err := authenticator.Register(rp.CreationOptions(challenge, testUser, nil))
+	if err != nil {
+		t.Fatalf("authenticator failed to register: %v", err)
+	}
+
+	credential, err := rp.VerifyRegistration(response, challenge, true)
+	if err != nil {
+		t.Fatalf("expected the registration to be accepted, got %v", err)
+	}
+	return credential
+}
+
+func TestRelyingParty_RegistrationAndAssertion(t *testing.T) {
+	rp := newTestRelyingParty()
+	authenticator := webauthntest.NewAuthenticator(testRPID, testOrigin)
+	credential := register(t, rp, authenticator)
+
+	if string(credential.ID) != string(authenticator.CredentialID()) || !credential.UserVerified {
+		t.Errorf("unexpected credential %+v", credential)
+	}
+
+	challenge := []byte("assertion-challenge-0123456789")
+	response, err := authenticator.Login(rp.RequestOptions(challenge, nil, webauthn.UserVerificationRequired))
+	if err != nil {
+		t.Fatalf("authenticator failed to sign: %v", err)
+	}
+
+	assertion, err := rp.VerifyAssertion(response, challenge, credential, true)
+	if err != nil {
+		t.Fatalf("expected the assertion to be accepted, got %v", err)
+	}
+	if assertion.SignCount != 1 || string(assertion.UserHandle) != "user-handle" {
+		t.Errorf("unexpected assertion %+v", assertion)
+	}
+}
+
+func TestRelyingParty_RejectsInvalidRegistrations(t *testing.T) {
+	rp := newTestRelyingParty()
+	challenge := []byte("registration-challenge-0123456789")
+
+	cases := map[string]struct {
+		authenticator *webauthntest.Authenticator
+		challenge     []byte
+	}{
+		"other challenge":     {webauthntest.NewAuthenticator(testRPID, testOrigin), []byte("other-challenge")},
+		"phishing origin":     {webauthntest.NewAuthenticator(testRPID, "https://evil.example.com"), challenge},
+		"other relying party": {webauthntest.NewAuthenticator("evil.example.com", testOrigin), challenge},
+	}
+
+	for name, tc := range cases {
+		response, err := tc.authenticator.Register(rp.CreationOptions(challenge, testUser, nil))
+		if err != nil {
+			t.Fatalf("%s: authenticator failed to register: %v", name, err)
+		}
+		if _, err := rp.VerifyRegistration(response, tc.challenge, false); err == nil {
+			t.Errorf("%s: expected the registration to be refused", name)
+		}
+	}
+
+	// User verification is only enforced when required
+	authenticator := webauthntest.NewAuthenticator(testRPID, testOrigin)
+	authenticator.UserVerified = false
+	response, err := authenticator.Register(rp.CreationOptions(challenge, testUser, nil))
+	if err != nil {
+		t.Fatalf("authenticator failed to register: %v", err)
+	}
+	if _, err := rp.VerifyRegistration(response, challenge, true); err == nil {
+		t.Error("expected an unverified user to be refused when verification is required")
+	}
+	if _, err := rp.VerifyRegistration(response, challenge, false); err != nil {
+		t.Errorf("expected an unverified user to be accepted when verification is not required, got %v", err)
+	}
+}
+
+func TestRelyingParty_RejectsInvalidAssertions(t *testing.T) {
+	rp := newTestRelyingParty()
+	authenticator := webauthntest.NewAuthenticator(testRPID, testOrigin)
+	credential := register(t, rp, authenticator)
+	challenge := []byte("assertion-challenge-0123456789")
+	options := rp.RequestOptions(challenge, nil, webauthn.UserVerificationPreferred)
+
+	response, err := authenticator.Login(options)
+	if err != nil {
+		t.Fatalf("authenticator failed to sign: %v", err)
+	}
+
+	// A signature replayed for another challenge is refused
+	if _, err := rp.VerifyAssertion(response, []byte("other-challenge"), credential, false); err == nil {
+		t.Error("expected an assertion for another challenge to be refused")
+	}
+
+	// A tampered signature is refused
+	tampered := *response
+	tampered.Response.Signature = webauthn.EncodeBase64([]byte("not a signature"))
+	if _, err := rp.VerifyAssertion(&tampered, challenge, credential, false); err == nil {
+		t.Error("expected a tampered signature to be refused")
+	}
+
+	// Another credential's assertion is refused
+	other := webauthntest.NewAuthenticator(testRPID, testOrigin)
+	otherCredential := register(t, rp, other)
+	if _, err := rp.VerifyAssertion(response, challenge, otherCredential, false); err == nil {
+		t.Error("expected an assertion of another credential to be refused")
+	}
+
+	// A counter that does not increase reveals a cloned authenticator
+	assertion, err := rp.VerifyAssertion(response, challenge, credential, false)
+	if err != nil {
+		t.Fatalf("expected the assertion to be accepted, got %v", err)
+	}
+	credential.SignCount = assertion.SignCount
+	authenticator.SignCount = 0
+	replayed, err := authenticator.Login(options)
+	if err != nil {
+		t.Fatalf("authenticator failed to sign: %v", err)
+	}
+	if _, err := rp.VerifyAssertion(replayed, challenge, credential, false); !errors.Is(err, webauthn.ErrSignCountRegression) {
+		t.Errorf("expected ErrSignCountRegression, got %v", err)
+	}
+}
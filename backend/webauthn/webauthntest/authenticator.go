@@ -0,0 +1,206 @@
+// Package webauthntest provides a software WebAuthn authenticator for tests.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"angular-n-go-template/backend/webauthn"
+)
+
+// Authenticator is a software authenticator holding one discoverable ES256 credential.
+// Its fields can be changed between ceremonies to simulate misbehaving clients and authenticators.
+type Authenticator struct {
+	// Origin is reported in the client data
+	Origin string
+	// RPID is the relying party ID the authenticator scopes its credential to
+	RPID string
+	// UserVerified reports whether the user passed a PIN or biometric check
+	UserVerified bool
+	// SignCount is the signature counter, incremented before each assertion
+	SignCount uint32
+
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+}
+
+// NewAuthenticator creates an authenticator for a relying party that verifies its user
+func NewAuthenticator(rpID, origin string) *Authenticator {
+	return &Authenticator{Origin: origin, RPID: rpID, UserVerified: true}
+}
+
+// CredentialID returns the ID of the registered credential
+func (a *Authenticator) CredentialID() []byte {
+	return a.credentialID
+}
+
+// Register creates a new credential for the options of a registration ceremony
+func (a *Authenticator) Register(options *webauthn.CreationOptions) (*webauthn.RegistrationResponse, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	credentialID := make([]byte, 32)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, err
+	}
+	userHandle, err := webauthn.DecodeBase64(options.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	a.key, a.credentialID, a.userHandle = key, credentialID, userHandle
+
+	coseKey := encodeCBOR(map[int64]interface{}{
+		1:  int64(2),
+		3:  int64(webauthn.AlgES256),
+		-1: int64(1),
+		-2: padTo32(key.X.Bytes()),
+		-3: padTo32(key.Y.Bytes()),
+	})
+
+	authData := a.authenticatorData(0x40)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(credentialID)))
+	authData = append(authData, credentialID...)
+	authData = append(authData, coseKey...)
+
+	clientDataJSON, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attestationObject := encodeCBOR(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+
+	return &webauthn.RegistrationResponse{
+		ID:    webauthn.EncodeBase64(credentialID),
+		RawID: webauthn.EncodeBase64(credentialID),
+		Type:  "public-key",
+		Response: webauthn.AttestationResponse{
+			ClientDataJSON:    webauthn.EncodeBase64(clientDataJSON),
+			AttestationObject: webauthn.EncodeBase64(attestationObject),
+			Transports:        []string{"internal"},
+		},
+	}, nil
+}
+
+// Login signs the challenge of an authentication ceremony with the registered credential
+func (a *Authenticator) Login(options *webauthn.RequestOptions) (*webauthn.AuthenticationResponse, error) {
+	if a.key == nil {
+		return nil, errors.New("no credential registered")
+	}
+
+	a.SignCount++
+	authData := a.authenticatorData(0)
+	clientDataJSON, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &webauthn.AuthenticationResponse{
+		ID:    webauthn.EncodeBase64(a.credentialID),
+		RawID: webauthn.EncodeBase64(a.credentialID),
+		Type:  "public-key",
+		Response: webauthn.AssertionResponse{
+			ClientDataJSON:    webauthn.EncodeBase64(clientDataJSON),
+			AuthenticatorData: webauthn.EncodeBase64(authData),
+			Signature:         webauthn.EncodeBase64(signature),
+			UserHandle:        webauthn.EncodeBase64(a.userHandle),
+		},
+	}, nil
+}
+
+// authenticatorData returns the RP ID hash, flags and signature counter with the given extra flags
+func (a *Authenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags |= 0x01
+	if a.UserVerified {
+		flags |= 0x04
+	}
+
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.SignCount)
+}
+
+// clientData returns the client data JSON a browser would collect
+func (a *Authenticator) clientData(ceremony, challenge string) ([]byte, error) {
+	return json.Marshal(webauthn.ClientData{Type: ceremony, Challenge: challenge, Origin: a.Origin})
+}
+
+func padTo32(value []byte) []byte {
+	return append(make([]byte, 32-len(value)), value...)
+}
+
+// encodeCBOR encodes the values the authenticator produces: integers, byte and text strings and maps
+func encodeCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case map[int64]interface{}:
+		keys := make([]int64, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+		encoded := cborHead(5, uint64(len(v)))
+		for _, key := range keys {
+			encoded = append(encoded, encodeCBOR(key)...)
+			encoded = append(encoded, encodeCBOR(v[key])...)
+		}
+		return encoded
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		encoded := cborHead(5, uint64(len(v)))
+		for _, key := range keys {
+			encoded = append(encoded, encodeCBOR(key)...)
+			encoded = append(encoded, encodeCBOR(v[key])...)
+		}
+		return encoded
+	}
+	panic("webauthntest: unsupported CBOR value")
+}
+
+// cborHead encodes the initial byte and argument of an item
+func cborHead(major byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{major<<5 | byte(argument)}
+	case argument <= 0xff:
+		return []byte{major<<5 | 24, byte(argument)}
+	case argument <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(argument))
+	case argument <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(argument))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, argument)
+}
@@ -11,7 +11,11 @@ describe('errorInterceptor', () => {
   let mockHandler: jasmine.Spy;
 
   beforeEach(() => {
-    const authSpy = jasmine.createSpyObj('AuthService', ['logout']);
+    const authSpy = jasmine.createSpyObj('AuthService', [
+      'logout',
+      'getRefreshToken',
+      'refreshSession',
+    ]);
     const routerSpyObj = jasmine.createSpyObj('Router', ['navigate']);
 
     TestBed.configureTestingModule({
@@ -79,6 +83,29 @@ describe('errorInterceptor', () => {
     });
   });
 
+  it('should refresh the session and retry on 401 when a refresh token exists', () => {
+    const request = new HttpRequest('GET', '/api/test');
+    const httpError = new HttpErrorResponse({
+      status: 401,
+      statusText: 'Unauthorized',
+    });
+    const mockEvent: HttpEvent<unknown> = {} as HttpEvent<unknown>;
+
+    authServiceSpy.getRefreshToken.and.returnValue('refresh-token');
+    authServiceSpy.refreshSession.and.returnValue(of('new-access-token'));
+    mockHandler.and.returnValues(throwError(() => httpError), of(mockEvent));
+
+    errorInterceptor(request, mockHandler).subscribe(result => {
+      expect(result).toBe(mockEvent);
+    });
+
+    expect(authServiceSpy.refreshSession).toHaveBeenCalled();
+    expect(authServiceSpy.logout).not.toHaveBeenCalled();
+    expect(mockHandler.calls.mostRecent().args[0].headers.get('Authorization')).toBe(
+      'Bearer new-access-token'
+    );
+  });
+
   it('should handle 403 errors', () => {
     const request = new HttpRequest('GET', '/api/test');
     const httpError = new HttpErrorResponse({
@@ -1,15 +1,35 @@
 import { HttpInterceptorFn, HttpErrorResponse } from '@angular/common/http';
 import { inject } from '@angular/core';
 import { Router } from '@angular/router';
-import { catchError, throwError } from 'rxjs';
+import { catchError, switchMap, throwError } from 'rxjs';
 import { AuthService } from '../services/auth.service';
 
+// Endpoints whose 401 responses must not trigger a token refresh
+const NO_REFRESH_ENDPOINTS = ['/auth/login', '/auth/register', '/auth/refresh'];
+
 export const errorInterceptor: HttpInterceptorFn = (req, next) => {
   const router = inject(Router);
   const authService = inject(AuthService);
 
   return next(req).pipe(
     catchError((error: HttpErrorResponse) => {
+      const canRefresh =
+        error.status === 401 &&
+        !!authService.getRefreshToken() &&
+        !NO_REFRESH_ENDPOINTS.some(endpoint => req.url.includes(endpoint));
+
+      if (canRefresh) {
+        // Retry once with a fresh access token before giving up on the session
+        return authService.refreshSession().pipe(
+          switchMap(token => next(req.clone({ setHeaders: { Authorization: `Bearer ${token}` } }))),
+          catchError(() => {
+            authService.logout();
+            router.navigate(['/login']);
+            return throwError(() => new Error('Authentication failed. Please login again.'));
+          })
+        );
+      }
+
       let errorMessage = 'An unexpected error occurred';
 
       if (error.error instanceof ErrorEvent) {
@@ -26,6 +26,8 @@ describe('LoginComponent', () => {
 
   const mockLoginResponse: LoginResponse = {
     token: 'mock-jwt-token',
+    refresh_token: 'mock-refresh-token',
+    expires_in: 900,
     user: mockUser,
   };
 
@@ -31,6 +31,8 @@ describe('AuthService', () => {
 
   const mockLoginResponse: LoginResponse = {
     token: 'mock-jwt-token',
+    refresh_token: 'mock-refresh-token',
+    expires_in: 900,
     user: mockUser,
   };
 
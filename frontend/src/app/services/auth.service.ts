@@ -1,6 +1,15 @@
 import { Injectable, inject } from '@angular/core';
 import { HttpClient, HttpErrorResponse } from '@angular/common/http';
-import { Observable, BehaviorSubject, tap, catchError, throwError } from 'rxjs';
+import {
+  Observable,
+  BehaviorSubject,
+  tap,
+  catchError,
+  throwError,
+  map,
+  shareReplay,
+  finalize,
+} from 'rxjs';
 import { Router } from '@angular/router';
 import { environment } from '../../environments/environment';
 import { RBACService, DEFAULT_RBAC_CONFIG } from '../config/rbac.config';
@@ -33,6 +42,8 @@ export interface RegisterRequest {
 
 export interface LoginResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   user: User;
 }
 
@@ -72,6 +83,7 @@ export class AuthService {
   private readonly rbacService = new RBACService(DEFAULT_RBAC_CONFIG);
   private currentUserSubject = new BehaviorSubject<User | null>(null);
   private isInitializedSubject = new BehaviorSubject<boolean>(false);
+  private refreshInFlight$: Observable<string> | null = null;
   public currentUser$ = this.currentUserSubject.asObservable();
   public isInitialized$ = this.isInitializedSubject.asObservable();
 
@@ -85,14 +97,35 @@ export class AuthService {
       .pipe(
         tap(response => {
           if (response.status === 'success' && response.data) {
-            localStorage.setItem('token', response.data.token);
-            this.currentUserSubject.next(response.data.user);
+            this.storeSession(response.data);
           }
         }),
         catchError(this.handleError)
       );
   }
 
+  /**
+   * Exchanges the stored refresh token for a new token pair.
+   * Concurrent callers share a single request because refresh tokens are single-use.
+   */
+  refreshSession(): Observable<string> {
+    if (!this.refreshInFlight$) {
+      this.refreshInFlight$ = this.http
+        .post<BackendApiResponse<LoginResponse>>(`${this.API_URL}/auth/refresh`, {
+          refresh_token: this.getRefreshToken(),
+        })
+        .pipe(
+          map(response => {
+            this.storeSession(response.data);
+            return response.data.token;
+          }),
+          finalize(() => (this.refreshInFlight$ = null)),
+          shareReplay(1)
+        );
+    }
+    return this.refreshInFlight$;
+  }
+
   register(userData: RegisterRequest): Observable<BackendApiResponse<User>> {
     return this.http
       .post<BackendApiResponse<User>>(`${this.API_URL}/auth/register`, userData)
@@ -101,6 +134,7 @@ export class AuthService {
 
   logout(): void {
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     this.currentUserSubject.next(null);
     this.router.navigate(['/login']);
   }
@@ -115,6 +149,10 @@ export class AuthService {
     return localStorage.getItem('token');
   }
 
+  getRefreshToken(): string | null {
+    return localStorage.getItem('refresh_token');
+  }
+
   isAuthenticated(): boolean {
     const token = this.getToken();
     return !!token;
@@ -166,6 +204,12 @@ export class AuthService {
     return this.rbacService;
   }
 
+  private storeSession(session: LoginResponse): void {
+    localStorage.setItem('token', session.token);
+    localStorage.setItem('refresh_token', session.refresh_token);
+    this.currentUserSubject.next(session.user);
+  }
+
   private loadUserFromStorage(): void {
     const token = this.getToken();
     if (token) {